@@ -13,7 +13,8 @@ const (
 	addCliNamePromptText   = "Globally unique name (lowercase letters and hyphens only; short yet descriptive)\n> "
 	addDescPromptText      = "Description of the mod (optional)\n> "
 	addDetailURLPromptText = "Mod homepage/wiki URL\n> "
-	addDownloadPromptText  = "Desired package download URL\n> "
+	addDownloadPromptText  = "Desired package download URL or absolute path to a local JAR file\n> "
+	addMirrorsPromptText   = "Mirror URLs serving the same package (optional, comma-separated)\n> "
 	addGroupNamePromptText = "Server group\n> "
 )
 
@@ -32,9 +33,13 @@ var (
 	DetailsURLPrompt input.Prompt
 
 	// DownloadURLPrompt asks the user for a link to the location where the
-	// current version of the mod should be downloaded from
+	// current version of the mod should be downloaded from, or a local file
 	DownloadURLPrompt input.Prompt
 
+	// MirrorURLsPrompt asks the user for alternative locations serving the
+	// same file as the download URL
+	MirrorURLsPrompt input.Prompt
+
 	// GroupPrompt prompts the user which group the mod should go in when
 	// adding a new server mod
 	GroupPrompt input.Prompt
@@ -57,8 +62,9 @@ server-side mod when building new versions of the tool itself by using the
 
 All inputs for the mod information are collected interactively during
 execution.`,
+	Annotations: locksInstall,
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
-		var friendlyName, cliName, desc, detURL, dlURL, groupName string
+		var friendlyName, cliName, desc, detURL, dlURL, mirrors, groupName string
 
 		out := cmd.OutOrStdout()
 		in := cmd.InOrStdin()
@@ -83,6 +89,10 @@ execution.`,
 			return
 		}
 
+		if mirrors, err = MirrorURLsPrompt.GetInput(out, in); err != nil {
+			return
+		}
+
 		mod := &mc.Mod{
 			FriendlyName: friendlyName,
 			CliName:      cliName,
@@ -91,6 +101,10 @@ execution.`,
 			LatestURL:    dlURL,
 		}
 
+		if mirrorURLs := input.SplitList(mirrors); len(mirrorURLs) > 0 {
+			mod.MirrorURLs = mirrorURLs
+		}
+
 		if *serverMod {
 			if groupName, err = GroupPrompt.GetInput(out, in); err != nil {
 				return
@@ -124,7 +138,9 @@ func InitAddPrompts() {
 
 	DetailsURLPrompt = input.NewLinePrompt(addDetailURLPromptText, &input.URLValidator{})
 
-	DownloadURLPrompt = input.NewLinePrompt(addDownloadPromptText, &input.URLValidator{})
+	DownloadURLPrompt = input.NewLinePrompt(addDownloadPromptText, &input.ModSourceValidator{})
+
+	MirrorURLsPrompt = input.NewLinePrompt(addMirrorsPromptText, &input.URLListValidator{})
 
 	GroupPrompt = input.NewLinePrompt(addGroupNamePromptText, &input.GroupNameValidator{})
 }
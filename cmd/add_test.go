@@ -22,6 +22,7 @@ var _ = Describe("Add Cmd", func() {
 	var descNoOp *noOpPrompt
 	var detailURLNoOp *noOpPrompt
 	var latestURLNoOp *noOpPrompt
+	var mirrorsNoOp *noOpPrompt
 	var groupNoOp *noOpPrompt
 	var serverAddSaveFake *serverAddSaveNoOp
 
@@ -52,6 +53,7 @@ var _ = Describe("Add Cmd", func() {
 		descNoOp = &noOpPrompt{}
 		detailURLNoOp = &noOpPrompt{}
 		latestURLNoOp = &noOpPrompt{}
+		mirrorsNoOp = &noOpPrompt{}
 		groupNoOp = &noOpPrompt{ReturnStr: groupName}
 
 		cmd.FriendlyPrompt = friendlyNoOp
@@ -59,6 +61,7 @@ var _ = Describe("Add Cmd", func() {
 		cmd.DescPrompt = descNoOp
 		cmd.DetailsURLPrompt = detailURLNoOp
 		cmd.DownloadURLPrompt = latestURLNoOp
+		cmd.MirrorURLsPrompt = mirrorsNoOp
 		cmd.GroupPrompt = groupNoOp
 
 		cmd.RootCmd.SetArgs([]string{"add"})
@@ -107,6 +110,14 @@ var _ = Describe("Add Cmd", func() {
 			Expect(err).To(Equal(expectedErr))
 		})
 
+		It("returns error from mirror urls prompt", func() {
+			mirrorsNoOp.ReturnErr = expectedErr
+
+			err := cmd.RootCmd.Execute()
+
+			Expect(err).To(Equal(expectedErr))
+		})
+
 		It("returns error from group prompt", func() {
 			groupNoOp.ReturnErr = expectedErr
 			cmd.RootCmd.SetArgs([]string{"add", "--server"})
@@ -136,6 +147,7 @@ var _ = Describe("Add Cmd", func() {
 				Description:  "A super duper fun mod",
 				DetailsURL:   "<pretend this is a url>",
 				LatestURL:    "<also pretend this is a url>",
+				MirrorURLs:   []string{"<mirror 1>", "<mirror 2>"},
 			}
 
 			friendlyNoOp.ReturnStr = expectedModValues.FriendlyName
@@ -143,6 +155,7 @@ var _ = Describe("Add Cmd", func() {
 			descNoOp.ReturnStr = expectedModValues.Description
 			detailURLNoOp.ReturnStr = expectedModValues.DetailsURL
 			latestURLNoOp.ReturnStr = expectedModValues.LatestURL
+			mirrorsNoOp.ReturnStr = strings.Join(expectedModValues.MirrorURLs, ", ")
 		})
 
 		Context("client mod", func() {
@@ -304,6 +317,7 @@ func validateMod(actual *mc.Mod, expected *mc.Mod) {
 	Expect(actual.Description).To(Equal(expected.Description))
 	Expect(actual.DetailsURL).To(Equal(expected.DetailsURL))
 	Expect(actual.LatestURL).To(Equal(expected.LatestURL))
+	Expect(actual.MirrorURLs).To(Equal(expected.MirrorURLs))
 }
 
 type serverAddSaveNoOp struct {
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/mc"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupKeep *int
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup [command]",
+	Short: "Manage the backups of the mods folder",
+	Long: `
+Before install changes any jars, the mods folder of the target is archived into
+a timestamped zip, along with the installation records. For servers, the files
+are downloaded first. Backups are kept in the .mcmods-backups folder in your
+home directory, in a folder for each target:
+ local             the local Minecraft install
+ profile-<name>    the Minecraft instance of a profile
+ remote-<name>     a named remote
+ server-<host>     a server given with --ftp-server
+
+The target is selected like for any other command, but list and prune only
+use its name, so they don't connect to it or ask for its password:
+ $ backup list --remote survival
+
+Automatic backups are on for every target, unless they're turned off with
+backup auto off.`,
+}
+
+// backupListCmd represents the backup list command
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the backups of the target",
+	Long: `
+Prints out the backups of the target, oldest first, with their size in bytes.`,
+	Args:        cobra.NoArgs,
+	Annotations: namesTarget,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openBackupStore()
+		if err != nil {
+			return err
+		}
+
+		backups, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		if len(backups) == 0 {
+			printToUser("No backups.")
+			return nil
+		}
+
+		lines := make([]string, 0, len(backups))
+		for _, b := range backups {
+			lines = append(lines, fmt.Sprintf("%s  %s  %10d", b.ID, b.Created.Local().Format("2006-01-02 15:04"), b.Size))
+		}
+		printToUser(strings.Join(lines, "\n"))
+		return nil
+	},
+}
+
+// backupRestoreCmd represents the backup restore command
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore the mods folder from a backup",
+	Long: `
+Restores the mods folder and installation records of the target from a backup,
+by its ID from backup list. Files in the mods folder which aren't in the backup
+are deleted. The current mods folder is backed up first, unless automatic
+backups are off.
+
+The install history is kept, and the changes to the installed mods are added
+to it like any other install. Backups can be restored when the installation
+records are corrupt, to replace them.`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: repairsConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		store, err := openBackupStore()
+		if err != nil {
+			return err
+		}
+		if err = findBackup(store, args[0]); err != nil {
+			return err
+		}
+		if err = autoBackup(); err != nil {
+			return err
+		}
+
+		res, err := store.Restore(fs, args[0])
+		if err != nil {
+			return err
+		}
+
+		*UserModConfig = *res.Config
+		if err = cfgIo.Save(UserModConfig); err != nil {
+			return err
+		}
+
+		printToUser(fmt.Sprintf("Restored %s: wrote %d file(s), deleted %d.", args[0], res.Written, res.Removed))
+		return nil
+	},
+}
+
+// backupPruneCmd represents the backup prune command
+var backupPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete the oldest backups of the target",
+	Long: `
+Deletes all but the newest backups of the target.
+
+Example:
+ $ backup prune --keep 5`,
+	Args:        cobra.NoArgs,
+	Annotations: namesTarget,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flags().Changed("keep") {
+			return errors.New("--keep is required")
+		}
+		if *backupKeep < 0 {
+			return fmt.Errorf("invalid --keep %d: keep 0 or more backups", *backupKeep)
+		}
+
+		store, err := openBackupStore()
+		if err != nil {
+			return err
+		}
+
+		removed, err := store.Prune(*backupKeep)
+		if err != nil {
+			return err
+		}
+
+		printToUser(fmt.Sprintf("Deleted %d backup(s).", len(removed)))
+		return nil
+	},
+}
+
+// backupAutoCmd represents the backup auto command
+var backupAutoCmd = &cobra.Command{
+	Use:   "auto <on|off>",
+	Short: "Turn automatic backups on or off for the target",
+	Long: fmt.Sprintf(`
+Turns the backups made before each install on or off for the target. The
+setting is stored in the tool's config, under %s, by the target's name.`, mc.AutoBackupKey),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var on bool
+		switch args[0] {
+		case "on":
+			on = true
+		case "off":
+		default:
+			return fmt.Errorf("invalid setting %q: use on or off", args[0])
+		}
+
+		mc.SetAutoBackup(connectedTarget, on)
+		if err := ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		printToUser(fmt.Sprintf("Automatic backups turned %s for %s.", args[0], mc.BackupTargetName(connectedTarget)))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(backupCmd)
+
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	backupCmd.AddCommand(backupPruneCmd)
+	backupCmd.AddCommand(backupAutoCmd)
+
+	backupKeep = backupPruneCmd.Flags().Int("keep", 0, "The number of the newest backups to keep.")
+}
+
+// installTargetName names the target the args connect to, for its backups
+func installTargetName(args *mc.FTPArgs) string {
+	switch {
+	case args == nil && mc.CurrentProfile() != nil:
+		return "profile-" + mc.CurrentProfile().Name
+	case args == nil:
+		return InstallLocal
+	case args.Remote != "":
+		return "remote-" + args.Remote
+	default:
+		return "server-" + args.Server
+	}
+}
+
+func openBackupStore() (*mc.BackupStore, error) {
+	store := mc.DefaultBackupStore(connectedTarget)
+	if store == nil {
+		return nil, errors.New("backups are kept in the home directory, but there isn't one")
+	}
+	return store, nil
+}
+
+func findBackup(store *mc.BackupStore, id string) error {
+	backups, err := store.List()
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		if b.ID == id {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown backup: %s; see backup list", id)
+}
+
+// autoBackup backs up the mods folder of the target before it's changed,
+// unless automatic backups are off for it. A target without a mods folder has
+// nothing to back up.
+func autoBackup() error {
+	if !mc.AutoBackupEnabled(connectedTarget) {
+		return nil
+	}
+
+	store, err := openBackupStore()
+	if err != nil {
+		return err
+	}
+
+	_, err = store.Create(fs, time.Now())
+	if err == mc.ErrNothingToBackUp {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("the mods folder couldn't be backed up, so it wasn't changed: %w", err)
+	}
+	return nil
+}
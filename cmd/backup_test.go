@@ -0,0 +1,155 @@
+package cmd_test
+
+import (
+	"mcmods/cmd"
+	"mcmods/mc"
+	. "mcmods/testdata"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Backup Cmd", func() {
+	var td *rootTestData
+	var store *mc.BackupStore
+
+	jarPath := filepath.Join("/minecraft", mc.ModInstallPath("mod-x"))
+	backupAt := func(hour int) string {
+		b, err := store.Create(mc.LocalFileSystem{Fs: td.fs}, time.Date(2021, 6, 1, hour, 0, 0, 0, time.Local))
+		Expect(err).To(BeNil())
+		return b.ID
+	}
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.InstallPathKey, "/minecraft")
+		cmd.ViperInstance.Set(mc.AutoBackupKey, nil)
+		store = mc.DefaultBackupStore(cmd.InstallLocal)
+
+		Expect(afero.WriteFile(td.fs, jarPath, []byte("x1"), 0644)).To(BeNil())
+	})
+
+	Context("install", func() {
+		BeforeEach(func() {
+			cmd.Filter = emptyFilter{Return: []*mc.Mod{TestingClientMod1}}
+			cmd.Installer = emptyInstaller{}
+			cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+				return fakeDownloader{}, nil
+			}
+		})
+
+		It("backs up the mods folder first", func() {
+			cmd.RootCmd.SetArgs([]string{"install"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			backups, err := store.List()
+			Expect(err).To(BeNil())
+			Expect(backups).To(HaveLen(1))
+		})
+
+		It("doesn't back up when there's nothing to install", func() {
+			cmd.Filter = emptyFilter{Return: []*mc.Mod{}}
+			cmd.RootCmd.SetArgs([]string{"install"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			backups, _ := store.List()
+			Expect(backups).To(BeEmpty())
+		})
+
+		It("doesn't back up targets with automatic backups off", func() {
+			mc.SetAutoBackup(cmd.InstallLocal, false)
+			cmd.RootCmd.SetArgs([]string{"install"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			backups, _ := store.List()
+			Expect(backups).To(BeEmpty())
+		})
+	})
+
+	It("lists the backups", func() {
+		first := backupAt(12)
+		second := backupAt(13)
+		cmd.RootCmd.SetArgs([]string{"backup", "list"})
+
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		Expect(td.outBuffer.String()).To(MatchRegexp(`^` + first + `  .+\n` + second + `  .+$`))
+	})
+
+	It("lists the backups of a server without connecting to it", func() {
+		cmd.ViperInstance.Set(mc.RemotesKey, []interface{}{})
+		Expect(mc.SetRemote(mc.Remote{Name: "survival", Server: "mc.example.com:21", User: "admin"})).To(BeNil())
+		store = mc.DefaultBackupStore("remote-survival")
+		id := backupAt(12)
+		cmd.CreateFsFunc = func(ftpArgs *mc.FTPArgs) (mc.FileSystem, error) {
+			Fail("nothing should be connected to")
+			return nil, nil
+		}
+		cmd.RootCmd.SetArgs([]string{"backup", "list", "--remote", "survival"})
+
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		Expect(td.outBuffer.String()).To(HavePrefix(id + "  "))
+	})
+
+	It("prints when there are no backups", func() {
+		cmd.RootCmd.SetArgs([]string{"backup", "list"})
+
+		executeAndVerifyOutput(td.outBuffer, "No backups.", true)
+	})
+
+	It("restores a backup and saves its records", func() {
+		id := backupAt(12)
+		Expect(afero.WriteFile(td.fs, jarPath, []byte("x2"), 0644)).To(BeNil())
+		cmd.RootCmd.SetArgs([]string{"backup", "restore", id})
+
+		executeAndVerifyOutput(td.outBuffer, "Restored "+id+": wrote 1 file(s), deleted 0.", true)
+
+		b, _ := afero.ReadFile(td.fs, jarPath)
+		Expect(string(b)).To(Equal("x1"))
+		Expect(*td.cfgIoSpy.Saved).To(BeTrue())
+		backups, _ := store.List()
+		Expect(backups).To(HaveLen(2), "the current mods folder is backed up first")
+	})
+
+	It("returns an error for unknown backups", func() {
+		cmd.RootCmd.SetArgs([]string{"backup", "restore", "20210601-120000"})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		Expect(*td.cfgIoSpy.Saved).To(BeFalse())
+	})
+
+	It("prunes the oldest backups", func() {
+		backupAt(12)
+		backupAt(13)
+		newest := backupAt(14)
+		cmd.RootCmd.SetArgs([]string{"backup", "prune", "--keep", "1"})
+
+		executeAndVerifyOutput(td.outBuffer, "Deleted 2 backup(s).", true)
+
+		backups, _ := store.List()
+		Expect(backups).To(HaveLen(1))
+		Expect(backups[0].ID).To(Equal(newest))
+	})
+
+	It("requires --keep to prune", func() {
+		cmd.RootCmd.SetArgs([]string{"backup", "prune"})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+
+	It("turns automatic backups off for the target", func() {
+		cmd.RootCmd.SetArgs([]string{"backup", "auto", "off"})
+
+		executeAndVerifyOutput(td.outBuffer, "Automatic backups turned off for local.", true)
+
+		Expect(mc.AutoBackupEnabled(cmd.InstallLocal)).To(BeFalse())
+	})
+})
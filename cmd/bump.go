@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/mc"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// CreateResolverFunc initializes the ModResolver used to find new versions
+	CreateResolverFunc func() (mc.ModResolver, error) = CreateDefaultResolver
+
+	bumpAll     *bool
+	bumpDryRun  *bool
+	bumpServer  *bool
+	gameVersion *string
+	modLoader   *string
+)
+
+type bumpTarget struct {
+	mod    *mc.Mod
+	server bool
+}
+
+// bumpCmd represents the bump command
+var bumpCmd = &cobra.Command{
+	Use:   "bump [mods...|--all]",
+	Short: "Update mod definitions to the newest upstream files",
+	Long: `
+Bump looks up the newest upstream file of each mod which is compatible with the
+configured Minecraft version and mod loader, then updates the mod's latest
+download URL and file hashes. The mod's mirror URLs host the previous file, so
+they're removed. Mods are looked up on Modrinth, using the mod's project ID or
+its Modrinth download/homepage URL.
+
+The changes are printed as a diff for review. Use --dry-run to only print them
+without saving.
+
+Server mods can only be bumped when building new versions of this tool, using
+the --server option from the root of the tool's code repo.
+
+Examples:
+ $ bump some-mod another-mod
+ $ bump --all --dry-run
+ $ bump --all --server --game-version 1.18.1
+
+The Minecraft version and mod loader are stored, so they're only needed on the
+first command. While a profile is in use, they're stored in the profile.`,
+	Annotations: locksInstall,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if *bumpAll == (len(args) > 0) {
+			return errors.New("Specify either mod names or --all")
+		}
+
+		targets, err := getBumpTargets(args)
+		if err != nil {
+			return err
+		}
+
+		gv, loader, err := getResolveVersions()
+		if err != nil {
+			return err
+		}
+
+		resolver, err := CreateResolverFunc()
+		if err != nil {
+			return err
+		}
+
+		clientChanged, serverChanged := false, false
+
+		for _, t := range targets {
+			f, err := resolver.Resolve(t.mod, gv, loader)
+			if errors.Is(err, mc.ErrNoUpdateSource) && *bumpAll {
+				printLineToUser(fmt.Sprintf("%s\n  skipped: no update source", t.mod.CliName))
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			if !printBumpDiff(t.mod, f) {
+				continue
+			}
+
+			if !*bumpDryRun {
+				t.mod.LatestURL = f.URL
+				t.mod.Hashes = f.Hashes
+				// the mirrors host the previous file
+				t.mod.MirrorURLs = nil
+			}
+
+			if t.server {
+				serverChanged = true
+			} else {
+				clientChanged = true
+			}
+		}
+
+		if !clientChanged && !serverChanged {
+			printToUser("All mods are up-to-date.")
+			return nil
+		}
+
+		if *bumpDryRun {
+			printToUser("Dry run; config not updated.")
+			return nil
+		}
+
+		if clientChanged {
+			if err = cfgIo.Save(UserModConfig); err != nil {
+				return err
+			}
+		}
+
+		if serverChanged {
+			if err = ServerCfgSaver.Save(); err != nil {
+				return err
+			}
+		}
+
+		printToUser("Config updated.")
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(bumpCmd)
+
+	flags := bumpCmd.Flags()
+
+	bumpAll = flags.BoolP("all", "a", false, "Bump all client mods, or all server mods with --server.")
+
+	bumpDryRun = flags.Bool("dry-run", false, "Print the changes without saving them.")
+
+	bumpServer = flags.Bool("server", false, "Allow bumping server mods. Only allowed when building new versions of this tool.")
+
+	gameVersion = flags.String("game-version", "", "The Minecraft version to find compatible files for, e.g. 1.18.1. Stored, only needed on the first command.")
+
+	modLoader = flags.String("loader", "", "The mod loader to find compatible files for. Stored, defaults to fabric.")
+}
+
+func getBumpTargets(names []string) ([]bumpTarget, error) {
+	targets := []bumpTarget{}
+
+	if *bumpAll {
+		for _, mod := range UserModConfig.ClientMods {
+			targets = append(targets, bumpTarget{mod: mod})
+		}
+		if *bumpServer {
+			for _, mod := range getAllServerMods() {
+				targets = append(targets, bumpTarget{mod: mod, server: true})
+			}
+		}
+		return targets, nil
+	}
+
+	clientMods := NameMapper.MapAllMods(UserModConfig.ClientMods)
+	serverMods := map[string]bool{}
+	for _, mod := range getAllServerMods() {
+		serverMods[mod.CliName] = true
+	}
+
+	for _, name := range names {
+		mod, exists := clientMods[name]
+		if !exists {
+			return nil, mc.NewUnknownModError(name)
+		}
+
+		isServer := serverMods[name]
+		if isServer && !*bumpServer {
+			return nil, fmt.Errorf("%s is a server mod; use --server when building new versions of this tool", name)
+		}
+
+		targets = append(targets, bumpTarget{mod: mod, server: isServer})
+	}
+
+	return targets, nil
+}
+
+func getAllServerMods() []*mc.Mod {
+	mods := []*mc.Mod{}
+	for _, name := range getSortedServerModGroupNames() {
+		mods = append(mods, mc.ServerGroups[name].Mods...)
+	}
+	return mods
+}
+
+func getSortedServerModGroupNames() []string {
+	names := getServerModGroupNames(mc.ServerGroups)
+	sort.Strings(names)
+	return names
+}
+
+// getResolveVersions returns the Minecraft version and mod loader from the
+// flags, falling back to the profile in use and then viper. Values given as
+// flags are stored in the profile, or in viper without one, unless it's a dry
+// run.
+func getResolveVersions() (string, string, error) {
+	profile := mc.CurrentProfile()
+
+	gv := ViperInstance.GetString(mc.GameVersionKey)
+	if profile != nil && profile.GameVersion != "" {
+		gv = profile.GameVersion
+	}
+	if *gameVersion != "" {
+		gv = *gameVersion
+	}
+	if gv == "" {
+		return "", "", errors.New("A Minecraft version is required: use --game-version")
+	}
+
+	loader := ViperInstance.GetString(mc.ModLoaderKey)
+	if profile != nil && profile.ModLoader != "" {
+		loader = profile.ModLoader
+	}
+	if *modLoader != "" {
+		loader = *modLoader
+	}
+	if loader == "" {
+		loader = mc.DefaultModLoader
+	}
+
+	if (*gameVersion != "" || *modLoader != "") && !*bumpDryRun {
+		if err := storeResolveVersions(profile); err != nil {
+			return "", "", err
+		}
+	}
+
+	return gv, loader, nil
+}
+
+// storeResolveVersions stores the version flags which are given in the
+// profile, or in viper without one
+func storeResolveVersions(profile *mc.Profile) error {
+	if profile != nil {
+		if *gameVersion != "" {
+			profile.GameVersion = *gameVersion
+		}
+		if *modLoader != "" {
+			profile.ModLoader = *modLoader
+		}
+		if err := mc.SetProfile(*profile); err != nil {
+			return err
+		}
+	} else {
+		if *gameVersion != "" {
+			ViperInstance.Set(mc.GameVersionKey, *gameVersion)
+		}
+		if *modLoader != "" {
+			ViperInstance.Set(mc.ModLoaderKey, *modLoader)
+		}
+	}
+	return ViperInstance.WriteConfig()
+}
+
+// printBumpDiff prints the differences between the mod definition and the
+// resolved file. Returns false if there are no differences.
+func printBumpDiff(mod *mc.Mod, f *mc.ModFile) bool {
+	lines := []string{}
+
+	if mod.LatestURL != f.URL {
+		lines = append(lines, "  - latestUrl: "+mod.LatestURL, "  + latestUrl: "+f.URL)
+	}
+
+	algos := map[string]bool{}
+	for algo := range mod.Hashes {
+		algos[algo] = true
+	}
+	for algo := range f.Hashes {
+		algos[algo] = true
+	}
+
+	sortedAlgos := make([]string, 0, len(algos))
+	for algo := range algos {
+		sortedAlgos = append(sortedAlgos, algo)
+	}
+	sort.Strings(sortedAlgos)
+
+	for _, algo := range sortedAlgos {
+		prev, next := mod.Hashes[algo], f.Hashes[algo]
+		if prev == next {
+			continue
+		}
+		if prev != "" {
+			lines = append(lines, fmt.Sprintf("  - %s: %s", algo, prev))
+		}
+		if next != "" {
+			lines = append(lines, fmt.Sprintf("  + %s: %s", algo, next))
+		}
+	}
+
+	if len(lines) == 0 {
+		return false
+	}
+	if len(mod.MirrorURLs) > 0 {
+		lines = append(lines, "  - mirrorUrls: "+strings.Join(mod.MirrorURLs, ","))
+	}
+
+	printLineToUser(mod.CliName)
+	for _, l := range lines {
+		printLineToUser(l)
+	}
+	return true
+}
+
+// CreateDefaultResolver initializes a new mod resolver with a real HTTP Client,
+// configured by the HTTP options
+func CreateDefaultResolver() (mc.ModResolver, error) {
+	opts, err := getHTTPOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	hc, err := mc.NewConfiguredHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return mc.NewModrinthResolver(hc), nil
+}
@@ -0,0 +1,194 @@
+package cmd_test
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/cmd"
+	"mcmods/mc"
+	. "mcmods/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bump Cmd", func() {
+	var td *rootTestData
+	var resolver *fakeResolver
+	var serverSave *serverAddSaveNoOp
+	var serverSaved bool
+
+	newURL := "https://cdn.modrinth.com/data/abc/versions/2/mod.jar"
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.NameMapper = fakeNameMapper{Map: TestingCliModMap}
+		cmd.ViperInstance.Set(mc.GameVersionKey, "1.18.1")
+
+		resolver = &fakeResolver{Files: map[string]*mc.ModFile{}}
+		cmd.CreateResolverFunc = func() (mc.ModResolver, error) {
+			return resolver, nil
+		}
+
+		serverSaved = false
+		serverSave = &serverAddSaveNoOp{}
+		cmd.ServerCfgSaver = serverSaverSpy{serverAddSaveNoOp: serverSave, Saved: &serverSaved}
+	})
+
+	It("requires mod names or --all", func() {
+		cmd.RootCmd.SetArgs([]string{"bump"})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+
+	It("rejects mod names with --all", func() {
+		cmd.RootCmd.SetArgs([]string{"bump", "--all", TestingClientMod1.CliName})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+
+	It("returns an error for unknown mods", func() {
+		cmd.RootCmd.SetArgs([]string{"bump", "unknown"})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+
+	It("requires --server to bump server mods", func() {
+		cmd.RootCmd.SetArgs([]string{"bump", TestingServerRequired1.CliName})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		Expect(serverSaved).To(BeFalse())
+	})
+
+	It("requires a game version", func() {
+		cmd.ViperInstance.Set(mc.GameVersionKey, "")
+		cmd.RootCmd.SetArgs([]string{"bump", TestingClientMod1.CliName})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+
+	It("updates the client mod, prints the diff, and saves", func() {
+		oldURL := TestingClientMod1.LatestURL
+		resolver.Files[TestingClientMod1.CliName] = &mc.ModFile{URL: newURL, Hashes: map[string]string{"sha512": "abc"}}
+		cmd.RootCmd.SetArgs([]string{"bump", TestingClientMod1.CliName})
+		expectedOutput := fmt.Sprintf("%s\n  - latestUrl: %s\n  + latestUrl: %s\n  + sha512: abc\nConfig updated.",
+			TestingClientMod1.CliName, oldURL, newURL)
+
+		executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
+
+		Expect(TestingClientMod1.LatestURL).To(Equal(newURL))
+		Expect(TestingClientMod1.Hashes).To(HaveKeyWithValue("sha512", "abc"))
+		Expect(*td.cfgIoSpy.Saved).To(BeTrue())
+		Expect(serverSaved).To(BeFalse())
+	})
+
+	It("saves the server config for server mods", func() {
+		resolver.Files[TestingServerRequired1.CliName] = &mc.ModFile{URL: newURL}
+		cmd.RootCmd.SetArgs([]string{"bump", "--server", TestingServerRequired1.CliName})
+
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		Expect(TestingServerRequired1.LatestURL).To(Equal(newURL))
+		Expect(serverSaved).To(BeTrue())
+		Expect(*td.cfgIoSpy.Saved).To(BeFalse())
+	})
+
+	It("doesn't update or save on a dry run", func() {
+		oldURL := TestingClientMod1.LatestURL
+		resolver.Files[TestingClientMod1.CliName] = &mc.ModFile{URL: newURL}
+		cmd.RootCmd.SetArgs([]string{"bump", "--dry-run", TestingClientMod1.CliName})
+		expectedOutput := fmt.Sprintf("%s\n  - latestUrl: %s\n  + latestUrl: %s\nDry run; config not updated.",
+			TestingClientMod1.CliName, oldURL, newURL)
+
+		executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
+
+		Expect(TestingClientMod1.LatestURL).To(Equal(oldURL))
+		Expect(*td.cfgIoSpy.Saved).To(BeFalse())
+	})
+
+	It("doesn't store the version flags on a dry run", func() {
+		cmd.ViperInstance.Set(mc.GameVersionKey, "")
+		resolver.Files[TestingClientMod1.CliName] = &mc.ModFile{URL: newURL}
+		cmd.RootCmd.SetArgs([]string{"bump", "--dry-run", "--game-version", "1.18.1", TestingClientMod1.CliName})
+
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		Expect(cmd.ViperInstance.GetString(mc.GameVersionKey)).To(BeEmpty())
+	})
+
+	It("removes the mirrors of the previous file", func() {
+		oldURL := TestingClientMod1.LatestURL
+		mirror := "https://mirror.example.com/mod1.jar"
+		TestingClientMod1.MirrorURLs = []string{mirror}
+		resolver.Files[TestingClientMod1.CliName] = &mc.ModFile{URL: newURL}
+		cmd.RootCmd.SetArgs([]string{"bump", TestingClientMod1.CliName})
+		expectedOutput := fmt.Sprintf("%s\n  - latestUrl: %s\n  + latestUrl: %s\n  - mirrorUrls: %s\nConfig updated.",
+			TestingClientMod1.CliName, oldURL, newURL, mirror)
+
+		executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
+
+		Expect(TestingClientMod1.MirrorURLs).To(BeEmpty())
+	})
+
+	It("doesn't save when nothing changed", func() {
+		resolver.Files[TestingClientMod1.CliName] = &mc.ModFile{URL: TestingClientMod1.LatestURL}
+		cmd.RootCmd.SetArgs([]string{"bump", TestingClientMod1.CliName})
+
+		executeAndVerifyOutput(td.outBuffer, "All mods are up-to-date.", true)
+
+		Expect(*td.cfgIoSpy.Saved).To(BeFalse())
+	})
+
+	It("skips mods without an update source with --all", func() {
+		resolver.Files[TestingClientMod2.CliName] = &mc.ModFile{URL: newURL}
+		cmd.RootCmd.SetArgs([]string{"bump", "--all"})
+
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		Expect(TestingClientMod2.LatestURL).To(Equal(newURL))
+		Expect(*td.cfgIoSpy.Saved).To(BeTrue())
+	})
+
+	It("returns resolver errors for named mods", func() {
+		cmd.RootCmd.SetArgs([]string{"bump", TestingClientMod1.CliName})
+
+		err := cmd.RootCmd.Execute()
+
+		Expect(errors.Is(err, mc.ErrNoUpdateSource)).To(BeTrue())
+	})
+
+	It("returns errors from saving", func() {
+		td.cfgIoSpy.SaveErr = errors.New("save err")
+		resolver.Files[TestingClientMod1.CliName] = &mc.ModFile{URL: newURL}
+		cmd.RootCmd.SetArgs([]string{"bump", TestingClientMod1.CliName})
+
+		Expect(cmd.RootCmd.Execute()).To(Equal(td.cfgIoSpy.SaveErr))
+	})
+})
+
+// ----
+// Resolver
+// ----
+
+type fakeResolver struct {
+	Files map[string]*mc.ModFile
+}
+
+func (r fakeResolver) Resolve(mod *mc.Mod, gameVersion string, loader string) (*mc.ModFile, error) {
+	Expect(gameVersion).To(Equal("1.18.1"))
+	Expect(loader).To(Equal(mc.DefaultModLoader))
+	if f, ok := r.Files[mod.CliName]; ok {
+		return f, nil
+	}
+	return nil, mc.ErrNoUpdateSource
+}
+
+type serverSaverSpy struct {
+	*serverAddSaveNoOp
+	Saved *bool
+}
+
+func (s serverSaverSpy) Save() error {
+	*(s.Saved) = true
+	return s.serverAddSaveNoOp.Save()
+}
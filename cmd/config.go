@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"mcmods/mc"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	configRepairScan *bool
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config [command]",
+	Short: "Maintain the installation records of the target",
+	Long: fmt.Sprintf(`
+The installation records of the target are kept in %s, in
+the mods folder. Every save replaces the file in one step, and keeps the
+previous file as %s%s.`, mc.ModConfigFileName, mc.ModConfigFileName, mc.ModConfigBackupSuffix),
+}
+
+// configRepairCmd represents the config repair command
+var configRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Rebuild corrupt installation records",
+	Long: fmt.Sprintf(`
+Rebuilds the installation records of the target when they can't be read, e.g.
+after an interrupted save. While the records are corrupt, commands which change
+them stop with an error, and commands which only read them show a warning.
+
+The last good copy of the records is restored if there is one. Otherwise, or
+with --scan, the installations are rebuilt from the jars in the mods folder:
+jars which are the last file the install history has for their mod get that
+record back, and other jars of known mods are replaced on the next install.
+The client mods can only be recovered from the last good copy.
+
+The corrupt file is kept as %s%s.`, mc.ModConfigFileName, mc.ModConfigCorruptSuffix),
+	Args:        cobra.NoArgs,
+	Annotations: repairsConfig,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		res, err := mc.RepairConfig(fs, *configRepairScan)
+		if err != nil {
+			return err
+		}
+
+		*UserModConfig = *res.Config
+		if err = cfgIo.Save(UserModConfig); err != nil {
+			return err
+		}
+
+		kept := filepath.Join(mc.ModFolderName, mc.ModConfigFileName+mc.ModConfigCorruptSuffix)
+		if res.FromBackup {
+			printToUser(fmt.Sprintf("Restored the last good copy of the installation records. The corrupt file was kept as %s.", kept))
+			return nil
+		}
+
+		printLineToUser(fmt.Sprintf("Rebuilt the installation records from the mods folder: %d mod(s), %d with their records from the history.", len(res.Recovered), len(res.Matched)))
+		if len(res.Skipped) > 0 {
+			printLineToUser(fmt.Sprintf("Skipped files which aren't known mods: %s", strings.Join(res.Skipped, ", ")))
+		}
+		if len(res.Matched) < len(res.Recovered) {
+			printLineToUser("Mods without their records are replaced on the next install.")
+		}
+		if len(UserModConfig.ClientMods) == 0 {
+			printLineToUser("Client mods couldn't be recovered; add them again with add.")
+		}
+		printToUser(fmt.Sprintf("The corrupt file was kept as %s.", kept))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+
+	configCmd.AddCommand(configRepairCmd)
+
+	configRepairScan = configRepairCmd.Flags().Bool("scan", false, "Rebuild the installations from the mods folder, even if there's a good copy of the records.")
+}
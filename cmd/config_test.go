@@ -0,0 +1,72 @@
+package cmd_test
+
+import (
+	"bytes"
+	"errors"
+	"mcmods/cmd"
+	"mcmods/mc"
+	. "mcmods/testdata"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Config Cmd", func() {
+	var td *rootTestData
+	var errBuffer *bytes.Buffer
+
+	configPath := filepath.Join("/minecraft", mc.ModFolderName, mc.ModConfigFileName)
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+		errBuffer = bytes.NewBufferString("")
+		cmd.RootCmd.SetErr(errBuffer)
+
+		cmd.ViperInstance.Set(mc.InstallPathKey, "/minecraft")
+		Expect(afero.WriteFile(td.fs, configPath, []byte(`{"modInst`), 0644)).To(BeNil())
+		td.cfgIoSpy.LoadReturn = nil
+		td.cfgIoSpy.LoadErr = &mc.CorruptConfigError{Path: configPath, Err: errors.New("unexpected end of JSON input")}
+	})
+
+	AfterEach(func() {
+		cmd.RootCmd.SetErr(nil)
+	})
+
+	It("restores the last good copy of the records", func() {
+		Expect(afero.WriteFile(td.fs, configPath+mc.ModConfigBackupSuffix, []byte(`{"schemaVersion": 2, "modInstallations": {}, "clientMods": []}`), 0644)).To(BeNil())
+		cmd.RootCmd.SetArgs([]string{"config", "repair"})
+
+		executeAndVerifyOutput(td.outBuffer, "Restored the last good copy of the installation records. The corrupt file was kept as mods/mcmods-install.json.corrupt.", true)
+
+		Expect(*td.cfgIoSpy.Saved).To(BeTrue())
+		Expect(errBuffer.String()).To(BeEmpty(), "repairs don't warn about the corrupt records")
+	})
+
+	It("rebuilds the records from the mods folder without a good copy", func() {
+		Expect(afero.WriteFile(td.fs, filepath.Join("/minecraft", mc.ModInstallPath(TestingServerRequired1.CliName)), []byte("r1"), 0644)).To(BeNil())
+		Expect(afero.WriteFile(td.fs, filepath.Join("/minecraft", mc.ModInstallPath("stranger")), []byte("?"), 0644)).To(BeNil())
+		cmd.RootCmd.SetArgs([]string{"config", "repair"})
+
+		executeAndVerifyOutput(td.outBuffer, `Rebuilt the installation records from the mods folder: 1 mod(s), 0 with their records from the history.
+Skipped files which aren't known mods: stranger.jar
+Mods without their records are replaced on the next install.
+Client mods couldn't be recovered; add them again with add.
+The corrupt file was kept as mods/mcmods-install.json.corrupt.`, true)
+
+		Expect(*td.cfgIoSpy.Saved).To(BeTrue())
+		Expect(cmd.UserModConfig.ModInstallations).To(HaveKey(TestingServerRequired1.CliName))
+	})
+
+	It("keeps read-only commands working with a warning", func() {
+		cmd.ToolVersion = "1.2.3"
+		cmd.RootCmd.SetArgs([]string{"version"})
+
+		executeAndVerifyOutput(td.outBuffer, "1.2.3", true)
+
+		Expect(errBuffer.String()).To(ContainSubstring("Warning: the install config"))
+		Expect(errBuffer.String()).To(ContainSubstring("config repair"))
+		Expect(cmd.UserModConfig.ModInstallations).To(BeEmpty())
+	})
+})
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/input"
+	"mcmods/mc"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// CredentialsKeyEnvVar is the environment variable holding the session key
+	// printed by credentials unlock
+	CredentialsKeyEnvVar = "MCMODS_CREDENTIALS_KEY"
+
+	// CredentialsPassphraseEnvVar is the environment variable the credentials
+	// passphrase can be given in
+	CredentialsPassphraseEnvVar = "MCMODS_CREDENTIALS_PASSPHRASE"
+)
+
+var (
+	// PassphrasePrompt asks the user for the credentials passphrase
+	PassphrasePrompt = input.NewPasswordPrompt("Credentials passphrase: ")
+
+	// PassphraseConfirmPrompt asks the user to repeat a new passphrase
+	PassphraseConfirmPrompt = input.NewPasswordPrompt("Repeat the passphrase: ")
+
+	// the unlocked store, so the passphrase is only asked for once per command
+	credStore *mc.CredentialStore
+)
+
+// credentialsCmd represents the credentials command
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials [command]",
+	Short: "Manage the encrypted store of server passwords and download tokens",
+	Long: `
+The credential store is an opt-in file holding the passwords of remotes and the
+tokens of private download hosts, encrypted with a passphrase. It's created by
+the first credentials set, and stored in $HOME/` + mc.CredentialsFileName + `.
+
+Once stored, a remote's password is used whenever the remote is given with
+--remote and no password is given. Host tokens are sent as bearer tokens to
+their host, unless httpAuth in the config has credentials for the host.
+
+The store is unlocked by asking for the passphrase, once per command. To unlock
+it for the rest of a shell session, run:
+ $ eval "$(mcmods credentials unlock)"
+For automation, the passphrase can also be given in the ` + CredentialsPassphraseEnvVar + `
+environment variable.`,
+}
+
+// credentialsSetCmd represents the credentials set command
+var credentialsSetCmd = &cobra.Command{
+	Use:   "set <remote|host> <name>",
+	Short: "Store the password of a remote, or the token of a download host",
+	Long: `
+Stores a secret, replacing any with the same name. The secret is asked for, or
+read from the first line of stdin when it isn't a terminal.
+
+Examples:
+ $ credentials set remote survival
+ $ credentials set host builds.example.com`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kind, name := args[0], args[1]
+
+		label := "Token"
+		switch kind {
+		case mc.CredentialRemote:
+			if _, err := mc.GetRemote(name); err != nil {
+				return err
+			}
+			label = "Password"
+		case mc.CredentialHost:
+		default:
+			return fmt.Errorf("unknown credential kind %q: use %s or %s", kind, mc.CredentialRemote, mc.CredentialHost)
+		}
+
+		store, err := openCredentials(cmd, true)
+		if err != nil {
+			return err
+		}
+
+		secretPrompt := input.NewPasswordPrompt(fmt.Sprintf("%s for %s: ", label, name))
+		secret, err := secretPrompt.GetInput(cmd.ErrOrStderr(), cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+
+		if err = store.Set(kind, name, secret); err != nil {
+			return err
+		}
+		if err = store.Save(); err != nil {
+			return err
+		}
+
+		printToUser("Credential saved.")
+		return nil
+	},
+}
+
+// credentialsListCmd represents the credentials list command
+var credentialsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the stored credentials",
+	Long: `
+Prints out the kind and name of each stored credential. Secrets are never
+printed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openCredentials(cmd, false)
+		if err != nil {
+			return err
+		}
+
+		if store == nil || len(store.List()) == 0 {
+			printToUser("No credentials.")
+			return nil
+		}
+
+		lines := []string{}
+		for _, c := range store.List() {
+			lines = append(lines, fmt.Sprintf("%-6s  %s", c.Kind, c.Name))
+		}
+		printToUser(strings.Join(lines, "\n"))
+		return nil
+	},
+}
+
+// credentialsRemoveCmd represents the credentials remove command
+var credentialsRemoveCmd = &cobra.Command{
+	Use:   "remove <remote|host> <name>",
+	Short: "Remove a stored credential",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openCredentials(cmd, false)
+		if err != nil {
+			return err
+		}
+		if store == nil {
+			return errors.New("no credentials are stored")
+		}
+
+		if err = store.Remove(args[0], args[1]); err != nil {
+			return err
+		}
+		if err = store.Save(); err != nil {
+			return err
+		}
+
+		printToUser("Credential removed.")
+		return nil
+	},
+}
+
+// credentialsUnlockCmd represents the credentials unlock command
+var credentialsUnlockCmd = &cobra.Command{
+	Use:   "unlock",
+	Short: "Print a session key which unlocks the store without the passphrase",
+	Long: `
+Asks for the passphrase, and prints a shell command setting the session key in
+the ` + CredentialsKeyEnvVar + ` environment variable. Run it with eval to unlock
+the store for the rest of the shell session:
+ $ eval "$(mcmods credentials unlock)"
+
+The session key decrypts the store as well as the passphrase does, so keep it
+out of scripts and logs. It stops working when the store is created again.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openCredentials(cmd, false)
+		if err != nil {
+			return err
+		}
+		if store == nil {
+			return errors.New("no credentials are stored; add some with credentials set")
+		}
+
+		printToUser(fmt.Sprintf("export %s=%s", CredentialsKeyEnvVar, store.SessionKey()))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(credentialsCmd)
+
+	credentialsCmd.AddCommand(credentialsSetCmd)
+	credentialsCmd.AddCommand(credentialsListCmd)
+	credentialsCmd.AddCommand(credentialsRemoveCmd)
+	credentialsCmd.AddCommand(credentialsUnlockCmd)
+}
+
+// openCredentials unlocks the credential store with the session key, the
+// passphrase environment variable, or by asking for the passphrase. Returns nil
+// if the store doesn't exist, unless create is true.
+func openCredentials(cmd *cobra.Command, create bool) (*mc.CredentialStore, error) {
+	if credStore != nil {
+		return credStore, nil
+	}
+
+	path, err := mc.CredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := mc.CredentialStoreExists(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !exists && !create:
+		return nil, nil
+	case !exists:
+		credStore, err = createCredentials(cmd, path)
+	case os.Getenv(CredentialsKeyEnvVar) != "":
+		credStore, err = mc.OpenCredentialStoreWithKey(path, os.Getenv(CredentialsKeyEnvVar))
+	default:
+		var passphrase string
+		if passphrase, err = getPassphrase(cmd, PassphrasePrompt); err == nil {
+			credStore, err = mc.OpenCredentialStore(path, passphrase)
+		}
+	}
+	return credStore, err
+}
+
+func createCredentials(cmd *cobra.Command, path string) (*mc.CredentialStore, error) {
+	passphrase := os.Getenv(CredentialsPassphraseEnvVar)
+	if passphrase == "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Creating the credential store at %s.\n", path)
+
+		var err error
+		if passphrase, err = getPassphrase(cmd, PassphrasePrompt); err != nil {
+			return nil, err
+		}
+
+		repeated, err := getPassphrase(cmd, PassphraseConfirmPrompt)
+		if err != nil {
+			return nil, err
+		}
+		if repeated != passphrase {
+			return nil, errors.New("the passphrases don't match")
+		}
+	}
+
+	return mc.NewCredentialStore(path, passphrase)
+}
+
+func getPassphrase(cmd *cobra.Command, prompt input.Prompt) (string, error) {
+	if passphrase := os.Getenv(CredentialsPassphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	return prompt.GetInput(cmd.ErrOrStderr(), cmd.InOrStdin())
+}
+
+// storedRemotePassword returns the remote's password from the credential
+// store, or an empty string if there's no store or no password for the remote
+func storedRemotePassword(cmd *cobra.Command, name string) (string, error) {
+	store, err := openCredentials(cmd, false)
+	if err != nil || store == nil {
+		return "", err
+	}
+
+	pw, _ := store.Get(mc.CredentialRemote, name)
+	return pw, nil
+}
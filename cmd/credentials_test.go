@@ -0,0 +1,135 @@
+package cmd_test
+
+import (
+	"mcmods/cmd"
+	"mcmods/mc"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+)
+
+var _ = Describe("Credentials Cmd", func() {
+	var td *rootTestData
+
+	survival := mc.Remote{Name: "survival", Server: "mc.example.com:21", User: "admin"}
+	passphrase := "correct horse"
+
+	// setSecret runs credentials set, then resets the vars so the store is
+	// unlocked again by the next command
+	setSecret := func(kind, name, secret string) {
+		cmd.RootCmd.SetIn(strings.NewReader(secret + "\n"))
+		cmd.RootCmd.SetArgs([]string{"credentials", "set", kind, name})
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		td.outBuffer.Reset()
+		cmd.ResetVars()
+	}
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.RemotesKey, []interface{}{})
+		cmd.ViperInstance.Set(mc.DefaultRemoteKey, "")
+		Expect(mc.SetRemote(survival)).To(BeNil())
+
+		os.Setenv(cmd.CredentialsPassphraseEnvVar, passphrase)
+	})
+
+	AfterEach(func() {
+		os.Unsetenv(cmd.CredentialsPassphraseEnvVar)
+		os.Unsetenv(cmd.CredentialsKeyEnvVar)
+	})
+
+	It("prints a message when there are no credentials", func() {
+		os.Unsetenv(cmd.CredentialsPassphraseEnvVar)
+		cmd.RootCmd.SetArgs([]string{"credentials", "list"})
+
+		executeAndVerifyOutput(td.outBuffer, "No credentials.", true)
+	})
+
+	It("stores credentials, and lists them without the secrets", func() {
+		setSecret(mc.CredentialRemote, survival.Name, "ftp-pw")
+		setSecret(mc.CredentialHost, "builds.example.com", "token")
+		cmd.RootCmd.SetArgs([]string{"credentials", "list"})
+
+		executeAndVerifyOutput(td.outBuffer, "host    builds.example.com\nremote  survival", true)
+	})
+
+	It("asks for the passphrase twice when creating the store", func() {
+		os.Unsetenv(cmd.CredentialsPassphraseEnvVar)
+		cmd.PassphrasePrompt = noOpPrompt{ReturnStr: passphrase}
+		cmd.PassphraseConfirmPrompt = noOpPrompt{ReturnStr: "typo"}
+		cmd.RootCmd.SetIn(strings.NewReader("ftp-pw\n"))
+		cmd.RootCmd.SetArgs([]string{"credentials", "set", mc.CredentialRemote, survival.Name})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+
+	It("rejects unknown remotes and kinds", func() {
+		cmd.RootCmd.SetArgs([]string{"credentials", "set", mc.CredentialRemote, "unknown"})
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+
+		cmd.RootCmd.SetArgs([]string{"credentials", "set", "other", "name"})
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+
+	It("removes credentials", func() {
+		setSecret(mc.CredentialRemote, survival.Name, "ftp-pw")
+		cmd.RootCmd.SetArgs([]string{"credentials", "remove", mc.CredentialRemote, survival.Name})
+
+		executeAndVerifyOutput(td.outBuffer, "Credential removed.", true)
+
+		path, _ := mc.CredentialsPath()
+		store, err := mc.OpenCredentialStore(path, passphrase)
+		Expect(err).To(BeNil())
+		Expect(store.List()).To(BeEmpty())
+	})
+
+	It("prints a session key which unlocks the store", func() {
+		setSecret(mc.CredentialRemote, survival.Name, "ftp-pw")
+		cmd.RootCmd.SetArgs([]string{"credentials", "unlock"})
+
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		out := td.outBuffer.String()
+		Expect(out).To(HavePrefix("export " + cmd.CredentialsKeyEnvVar + "="))
+
+		cmd.ResetVars()
+		td.outBuffer.Reset()
+		os.Unsetenv(cmd.CredentialsPassphraseEnvVar)
+		os.Setenv(cmd.CredentialsKeyEnvVar, strings.TrimPrefix(out, "export "+cmd.CredentialsKeyEnvVar+"="))
+		cmd.RootCmd.SetArgs([]string{"credentials", "list"})
+
+		executeAndVerifyOutput(td.outBuffer, "remote  survival", true)
+	})
+
+	It("doesn't ask for the passphrase for installs which don't download from its hosts", func() {
+		setSecret(mc.CredentialHost, "builds.example.com", "token")
+		os.Unsetenv(cmd.CredentialsPassphraseEnvVar)
+		cmd.CreateDownloaderFunc = cmd.CreateDefaultDownloader
+		cmd.Filter = emptyFilter{Return: []*mc.Mod{}}
+		cmd.Installer = emptyInstaller{}
+		cmd.RootCmd.SetArgs([]string{"install"})
+
+		executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+	})
+
+	It("connects to remotes with the stored password", func() {
+		setSecret(mc.CredentialRemote, survival.Name, "ftp-pw")
+		var ftpArgs *mc.FTPArgs
+		cmd.CreateFsFunc = func(args *mc.FTPArgs) (mc.FileSystem, error) {
+			ftpArgs = args
+			return mc.LocalFileSystem{Fs: td.fs}, nil
+		}
+		cmd.RootCmd.Run = func(cmd *cobra.Command, args []string) {}
+		defer func() { cmd.RootCmd.Run = nil }()
+		cmd.RootCmd.SetArgs([]string{"--remote", survival.Name})
+
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		Expect(ftpArgs.Pw).To(Equal("ftp-pw"))
+	})
+})
@@ -3,11 +3,16 @@ package cmd
 import (
 	"fmt"
 	"mcmods/mc"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var (
+	// Now returns the current time, for the ages of installs. Exported for
+	// testing
+	Now = time.Now
+
 	describeMap = map[string]func(string) error{}
 )
 
@@ -63,6 +68,10 @@ func describeMod(modName string) error {
 	printToUser(fmt.Sprintf("\n%s (%s)\n-----\n%s\nWebsite:  %s\nLatest package:  %s",
 		m.FriendlyName, m.CliName, m.Description, m.DetailsURL, m.LatestURL))
 
+	for _, mirror := range m.MirrorURLs {
+		printToUser(fmt.Sprintf("\nMirror:  %s", mirror))
+	}
+
 	return nil
 }
 
@@ -96,10 +105,47 @@ func describeInstall(modName string) error {
 
 	if exists {
 		printToUser(fmt.Sprintf("\n%s (%s)\n-----\nInstall timestamp:  %s\nUp-to-date:  %t",
-			m.FriendlyName, m.CliName, i.Timestamp, m.LatestURL == i.DownloadURL))
+			m.FriendlyName, m.CliName, formatInstallTime(i.Timestamp, Now()), mc.IsUpToDate(m, i)))
+
+		if i.SourceURL != "" && i.SourceURL != i.DownloadURL {
+			printToUser(fmt.Sprintf("\nDownloaded from mirror:  %s", i.SourceURL))
+		}
 	} else {
 		printToUser("Not Installed.")
 	}
 
 	return nil
 }
+
+// formatInstallTime formats the install time in local time, with its age
+func formatInstallTime(t, now time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s (%s)", t.Local().Format("2006-01-02 15:04"), formatAge(now.Sub(t)))
+}
+
+// formatAge describes the age roughly, in the largest whole unit
+func formatAge(age time.Duration) string {
+	day := 24 * time.Hour
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * day},
+		{"month", 30 * day},
+		{"day", day},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	for _, u := range units {
+		if n := int(age / u.size); n > 0 {
+			if n == 1 {
+				return fmt.Sprintf("1 %s ago", u.name)
+			}
+			return fmt.Sprintf("%d %ss ago", n, u.name)
+		}
+	}
+	return "just now"
+}
@@ -8,6 +8,7 @@ import (
 	"mcmods/mc"
 	. "mcmods/testdata"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -57,6 +58,17 @@ var _ = Describe("Describe Cmd", func() {
 
 			executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
 		})
+
+		It("lists the mod's mirrors", func() {
+			m := TestingClientMod1
+			m.MirrorURLs = []string{"https://mirror_1/mod.jar", "https://mirror_2/mod.jar"}
+			expectedOutput := fmt.Sprintf("\n%s (%s)\n-----\n%s\nWebsite:  %s\nLatest package:  %s\nMirror:  %s\nMirror:  %s",
+				m.FriendlyName, m.CliName, m.Description, m.DetailsURL, m.LatestURL, m.MirrorURLs[0], m.MirrorURLs[1])
+
+			cmd.RootCmd.SetArgs([]string{"describe", "mod", m.CliName})
+
+			executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
+		})
 	})
 
 	Context("group", func() {
@@ -91,6 +103,16 @@ var _ = Describe("Describe Cmd", func() {
 	})
 
 	Context("install", func() {
+		BeforeEach(func() {
+			cmd.Now = func() time.Time {
+				return time.Date(2021, 6, 4, 12, 30, 0, 0, time.UTC)
+			}
+		})
+
+		installed := func() string {
+			return TestingConfig.ModInstallations[TestingClientMod1.CliName].Timestamp.Local().Format("2006-01-02 15:04") + " (3 days ago)"
+		}
+
 		It("no mod name returns an error", func() {
 			cmd.RootCmd.SetArgs([]string{"describe", "install"})
 
@@ -110,13 +132,40 @@ var _ = Describe("Describe Cmd", func() {
 		It("describes the install", func() {
 			m := TestingClientMod1
 			expectedOutput := fmt.Sprintf("\n%s (%s)\n-----\nInstall timestamp:  %s\nUp-to-date:  %t",
-				m.FriendlyName, m.CliName, "123", false)
+				m.FriendlyName, m.CliName, installed(), false)
 
 			cmd.RootCmd.SetArgs([]string{"describe", "install", TestingClientMod1.CliName})
 
 			executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
 		})
 
+		It("shows the mirror the install was downloaded from", func() {
+			m := TestingClientMod1
+			mirror := "https://mirror_1/mod.jar"
+			install := TestingConfig.ModInstallations[m.CliName]
+			install.SourceURL = mirror
+			TestingConfig.ModInstallations[m.CliName] = install
+			expectedOutput := fmt.Sprintf("\n%s (%s)\n-----\nInstall timestamp:  %s\nUp-to-date:  %t\nDownloaded from mirror:  %s",
+				m.FriendlyName, m.CliName, installed(), false, mirror)
+
+			cmd.RootCmd.SetArgs([]string{"describe", "install", m.CliName})
+
+			executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
+		})
+
+		It("shows when the install time is unknown", func() {
+			m := TestingClientMod1
+			install := TestingConfig.ModInstallations[m.CliName]
+			install.Timestamp = time.Time{}
+			TestingConfig.ModInstallations[m.CliName] = install
+			expectedOutput := fmt.Sprintf("\n%s (%s)\n-----\nInstall timestamp:  unknown\nUp-to-date:  %t",
+				m.FriendlyName, m.CliName, false)
+
+			cmd.RootCmd.SetArgs([]string{"describe", "install", m.CliName})
+
+			executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
+		})
+
 		It("informs when not installed", func() {
 			expectedOutput := fmt.Sprintf("Not Installed.")
 
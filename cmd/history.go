@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"mcmods/mc"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// HistoryDateFormat is the format of the dates given to history
+	HistoryDateFormat = "2006-01-02"
+)
+
+var (
+	historyMod    *string
+	historySince  *string
+	historyUntil  *string
+	historyTarget *string
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the history of mod installs, updates and uninstalls",
+	Long: `
+History prints the log of changes to the installed mods, oldest first. Every
+install, update and uninstall is added to the log when the installation records
+are saved, with the URLs and hashes before and after, and the user and machine
+which made the change. Each save is numbered as a revision, which the rollback
+command can return to. The log is kept next to the installation records, so
+each target has its own.
+
+The target is the one the command connects to, like any other command, or the
+install given with --target:
+ local           the local Minecraft install
+ local:<dir>     another Minecraft directory
+ remote:<name>   a named remote, see the remote command
+
+Dates are given as YYYY-MM-DD in local time, or in RFC 3339 format. Both ends
+of the range are inclusive.
+
+Examples:
+ $ history
+ $ history --mod fabric-api
+ $ history --target remote:survival --since 2021-06-01 --until 2021-06-07`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter := mc.HistoryFilter{Mod: *historyMod}
+
+		var err error
+		if filter.Since, err = parseHistoryDate(*historySince, false); err != nil {
+			return err
+		}
+		if filter.Until, err = parseHistoryDate(*historyUntil, true); err != nil {
+			return err
+		}
+
+		// problems from here on aren't usage errors
+		cmd.SilenceUsage = true
+
+		target := fs
+		if *historyTarget != "" {
+			if target, err = openInstall(cmd, *historyTarget); err != nil {
+				return err
+			}
+			defer target.Close()
+		}
+
+		events, err := mc.ReadHistory(target)
+		if err != nil {
+			return err
+		}
+
+		lines := []string{}
+		for _, e := range events {
+			if filter.Match(e) {
+				lines = append(lines, formatHistoryEvent(e))
+			}
+		}
+
+		if len(lines) == 0 {
+			printToUser("No history.")
+			return nil
+		}
+		for _, line := range lines {
+			printLineToUser(line)
+		}
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	flags := historyCmd.Flags()
+
+	historyMod = flags.String("mod", "", "Only show the history of the mod with this CLI name.")
+	historySince = flags.String("since", "", "Only show changes on or after this date.")
+	historyUntil = flags.String("until", "", "Only show changes on or before this date.")
+	historyTarget = flags.String("target", "", fmt.Sprintf("The install to show the history of: %s, %s<dir> or %s<name>.", InstallLocal, InstallLocal+":", InstallRemotePrefix))
+}
+
+// parseHistoryDate parses a date or time. The end of a range given as a date
+// is moved to the start of the next day, since the filter excludes it.
+func parseHistoryDate(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		if end {
+			t = t.Add(time.Nanosecond)
+		}
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(HistoryDateFormat, s, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q; use YYYY-MM-DD", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func formatHistoryEvent(e mc.HistoryEvent) string {
+	detail := e.NewURL
+	switch e.Event {
+	case mc.HistoryUninstall:
+		detail = e.OldURL
+	case mc.HistoryUpdate:
+		if e.OldURL != e.NewURL {
+			detail = fmt.Sprintf("%s -> %s", e.OldURL, e.NewURL)
+		} else {
+			detail = fmt.Sprintf("%s (sha256 %s -> %s)", e.NewURL, shortHash(e.OldSHA256), shortHash(e.NewSHA256))
+		}
+	}
+
+	by := e.User
+	if e.Host != "" {
+		by += "@" + e.Host
+	}
+	return fmt.Sprintf("r%-3d  %s  %-9s  %s  %s  by %s", e.Revision, e.Time.Local().Format("2006-01-02 15:04"), e.Event, e.Mod, detail, by)
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "none"
+	}
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
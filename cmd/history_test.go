@@ -0,0 +1,66 @@
+package cmd_test
+
+import (
+	"mcmods/cmd"
+	"mcmods/mc"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("History Cmd", func() {
+	var td *rootTestData
+
+	day := func(d int) time.Time {
+		return time.Date(2021, 6, d, 12, 0, 0, 0, time.Local)
+	}
+	line := func(revision string, d int, event, detail string) string {
+		return revision + "  " + day(d).Format("2006-01-02 15:04") + "  " + event + "  mod-a  " + detail + "  by alice@build-box\n"
+	}
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.InstallPathKey, "/minecraft")
+		cmd.TargetFs = td.fs
+		mc.HistoryActor = func() (string, string) {
+			return "alice", "build-box"
+		}
+
+		v1 := map[string]mc.ModInstallation{"mod-a": {DownloadURL: "https://a/1", SHA256: "1111"}}
+		v2 := map[string]mc.ModInstallation{"mod-a": {DownloadURL: "https://a/2", SHA256: "2222"}}
+		local := mc.LocalFileSystem{Fs: td.fs}
+		Expect(mc.AppendHistory(local, mc.DiffInstallations(nil, v1, day(1)))).To(BeNil())
+		Expect(mc.AppendHistory(local, mc.DiffInstallations(v1, v2, day(8)))).To(BeNil())
+		Expect(mc.AppendHistory(local, mc.DiffInstallations(nil, map[string]mc.ModInstallation{"mod-b": {DownloadURL: "https://b"}}, day(8)))).To(BeNil())
+		Expect(mc.AppendHistory(local, mc.DiffInstallations(v2, nil, day(15)))).To(BeNil())
+	})
+
+	It("prints the history of the target", func() {
+		cmd.RootCmd.SetArgs([]string{"history", "--mod", "mod-a"})
+
+		executeAndVerifyOutput(td.outBuffer,
+			line("r1  ", 1, "install  ", "https://a/1")+
+				line("r2  ", 8, "update   ", "https://a/1 -> https://a/2")+
+				line("r4  ", 15, "uninstall", "https://a/2"), true)
+	})
+
+	It("filters by an inclusive date range", func() {
+		cmd.RootCmd.SetArgs([]string{"history", "--mod", "mod-a", "--since", "2021-06-02", "--until", "2021-06-08"})
+
+		executeAndVerifyOutput(td.outBuffer, line("r2  ", 8, "update   ", "https://a/1 -> https://a/2"), true)
+	})
+
+	It("reads the history of another install", func() {
+		cmd.RootCmd.SetArgs([]string{"history", "--target", "local:/other"})
+
+		executeAndVerifyOutput(td.outBuffer, "No history.", true)
+	})
+
+	It("returns an error for invalid dates", func() {
+		cmd.RootCmd.SetArgs([]string{"history", "--since", "last tuesday"})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+})
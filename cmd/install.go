@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
 	"mcmods/mc"
+	"strings"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
 
@@ -10,11 +14,14 @@ const (
 	// ServerOnlyGroupKey is the name of the mod group for mods which should only
 	// be installed on the server
 	ServerOnlyGroupKey = "server-only"
+
+	// ZipTargetPrefix is the prefix of install targets which are zip archives
+	ZipTargetPrefix = "zip:"
 )
 
 var (
 	// CreateDownloaderFunc initializes the ModDownloader
-	CreateDownloaderFunc func(fs mc.FileSystem) mc.ModDownloader = CreateDefaultDownloader
+	CreateDownloaderFunc func(fs mc.FileSystem) (mc.ModDownloader, error) = CreateDefaultDownloader
 
 	// NameMapper creates a map of all mods to their CLI name
 	NameMapper = mc.NewModNameMapper()
@@ -25,11 +32,16 @@ var (
 	// Installer installs mods
 	Installer = mc.NewModInstaller()
 
+	// TargetFs is the file system install targets and local mirror endpoints
+	// are on. Exported for testing
+	TargetFs = afero.NewOsFs()
+
 	force      *bool
 	fullServer *bool
 	clientOnly *bool
 	xMods      *[]string
 	xGroups    *[]string
+	target     *string
 )
 
 // installCmd represents the install command
@@ -43,28 +55,91 @@ Users unfamiliar with configuring mods are encouraged to do a plain install
 with all the required and recommended mods. Simply run the install command with
 no arguments.
 
-IMPORTANT: Disconnect from VPN to avoid issues when downloading mods.
+Downloads go through a Cloudflare bypass, which can fail over a VPN. If mods
+fail to download, try disconnecting from the VPN, or use the HTTP options to
+set a proxy (--proxy), extra trusted CAs (--ca-bundle), or turn the bypass off
+(--no-cloudflare-bypass). The options can be stored in the config file: see
+the "Installing Mods" docs.
 
 For advanced users wanting to use a custom set of performance-related mods or
 those who simply don't want the optional mods on their machine, see the argument
 descriptions for more about filtering out mods.
 
+The mode (--client-only or --full-server) and exclude flags are saved for the
+target after a successful install, and reused by installs without any of them,
+so updates keep the same mods. Giving any of them replaces the saved selection
+(see selection --help).
+
 --force can be used to invoke a download even if the latest version of the mods
 already exist locally. Otherwise, the tool skips if the latest URL matches the
 URL at the time of download.
 
+Before any jars are changed, the mods folder is backed up, unless automatic
+backups are off for the target (see backup --help).
+
 To perform a server install, use the --full-server option the FTP info:
-  $ install --full-server --user <ftp-user> --password <pw> --server <server>
+  $ install --full-server --user <ftp-user> --ftp-server <server>
+
+The password is prompted for. For scripts, give it in the MCMODS_PASSWORD
+environment variable or on stdin with --password-stdin.
+
+To keep the password private, connect over TLS with --ftp-tls explicit or
+--ftp-tls implicit. See the "Installing Mods" docs for self-signed certificates.
+
+For groups with more than one server, store each one as a named remote and use
+it with --remote <name> (see remote --help).
+
+Players with more than one Minecraft instance can store each one as a profile,
+with its own install path and selection of mods, and use it with
+--profile <name> (see profile --help). The selection of the profile is added to
+the exclude flags.
+
+For servers which only offer SFTP, add --protocol sftp (or give the server as
+an sftp:// URL). Log in with a password, or with a private key:
+  $ install --full-server --protocol sftp --user <user> --key-file <key>
 
-The FTP server and user are stored, so they're only needed on the first
-command. Password is needed every time. `,
+The FTP server, user, and protocol are stored, so they're only needed on the
+first command. The password or key file is needed every time.
+
+To share the mods with players who can't run the tool, install them into a zip
+archive instead, with the same filtering:
+  $ install --target zip:path/to/pack.zip
+
+The archive contains the mods folder and a manifest of the mods, and is meant
+to be extracted into the Minecraft directory. The client mods added to the
+local installation are included, but it isn't changed or locked, and no server
+is connected to. `,
+	Annotations: locksInstall,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if *target != "" && (remoteName != "" || ftpServer != "" || ftpUser != "" || serverProtocol != "") {
+			return errors.New("--target installs don't connect to a server, so --remote, --ftp-server, --user and --protocol can't be given")
+		}
+
+		// zip archives aren't install targets with a saved selection
+		saveSelection := false
+		if *target == "" {
+			var err error
+			if saveSelection, err = loadSelection(cmd); err != nil {
+				return err
+			}
+		}
+		selection := selectionFromFlags(*clientOnly, *fullServer, *xGroups, *xMods)
+
 		if !*fullServer {
 			if *clientOnly {
 				*xGroups = getServerModGroupNames(mc.ServerGroups)
 			} else {
 				*xGroups = append(*xGroups, ServerOnlyGroupKey)
 			}
+
+			if profile := mc.CurrentProfile(); profile != nil {
+				*xGroups = appendMissing(*xGroups, profile.ExcludedGroups()...)
+				*xMods = appendMissing(*xMods, profile.ExcludedMods...)
+			}
+		}
+
+		if *target != "" {
+			return installToTarget(*target)
 		}
 
 		mods, err := Filter.FilterAllMods(*xGroups, *xMods, UserModConfig, *force)
@@ -72,7 +147,17 @@ command. Password is needed every time. `,
 			return err
 		}
 
-		dl := CreateDownloaderFunc(fs)
+		dl, err := CreateDownloaderFunc(fs)
+		if err != nil {
+			return err
+		}
+
+		if len(mods) > 0 {
+			if err = autoBackup(); err != nil {
+				return err
+			}
+		}
+
 		err = Installer.InstallMods(dl, mods, UserModConfig)
 		if err != nil {
 			return err
@@ -83,6 +168,15 @@ command. Password is needed every time. `,
 			return err
 		}
 
+		if saveSelection {
+			if err = mc.SetSelection(connectedTarget, selection); err != nil {
+				return err
+			}
+			if err = ViperInstance.WriteConfig(); err != nil {
+				return err
+			}
+		}
+
 		printToUser("Install completed.")
 		return nil
 	},
@@ -102,6 +196,126 @@ func init() {
 	xMods = flags.StringSlice("x-mod", []string{}, "Exclude specific Mods from the install (client or server). Specify multiple mods by separating the names with commas, no spaces.")
 
 	xGroups = flags.StringSliceP("x-group", "x", []string{}, "Exclude Server Mod Groups from the install. The 'server-only' group is automatically excluded. Specify multiple mods by separating the names with commas, no spaces.")
+
+	target = flags.String("target", "", "Install into a new zip archive instead, e.g. zip:pack.zip. Every selected mod is downloaded, and the local installation isn't changed.")
+}
+
+// installToTarget installs the filtered mods into a new zip archive, along
+// with a manifest. The installation records are only kept in the manifest.
+func installToTarget(t string) error {
+	if !strings.HasPrefix(t, ZipTargetPrefix) || len(t) == len(ZipTargetPrefix) {
+		return fmt.Errorf("unknown install target %q: use %s<path>", t, ZipTargetPrefix)
+	}
+	archivePath := strings.TrimPrefix(t, ZipTargetPrefix)
+
+	// nothing is installed in a new archive
+	cfg := &mc.UserModConfig{
+		ModInstallations: map[string]mc.ModInstallation{},
+		ClientMods:       UserModConfig.ClientMods,
+	}
+	mods, err := Filter.FilterAllMods(*xGroups, *xMods, cfg, true)
+	if err != nil {
+		return err
+	}
+
+	zfs, err := mc.NewZipFileSystem(TargetFs, archivePath)
+	if err != nil {
+		return err
+	}
+	defer zfs.Close()
+
+	dl, err := CreateDownloaderFunc(zfs)
+	if err != nil {
+		return err
+	}
+
+	if err = Installer.InstallMods(dl, mods, cfg); err != nil {
+		return err
+	}
+	if err = mc.WritePackManifest(zfs, mods, cfg); err != nil {
+		return err
+	}
+	if err = zfs.Finish(); err != nil {
+		return err
+	}
+
+	printToUser(fmt.Sprintf("Wrote %d mod(s) to %s.", len(mods), archivePath))
+	return nil
+}
+
+// loadSelection sets the mode and exclude flags from the selection saved for
+// the connected target, unless any of them are given. Returns true if they're
+// given, so the selection can be saved after the install.
+func loadSelection(cmd *cobra.Command) (bool, error) {
+	for _, name := range []string{"client-only", "full-server", "x-group", "x-mod"} {
+		if cmd.Flags().Changed(name) {
+			return true, nil
+		}
+	}
+
+	saved, ok, err := mc.GetSelection(connectedTarget)
+	if err != nil || !ok || saved.IsEmpty() {
+		return false, err
+	}
+
+	*clientOnly = saved.Mode == mc.SelectionClientOnly
+	*fullServer = saved.Mode == mc.SelectionFullServer
+	*xGroups = append((*xGroups)[:0], saved.ExcludedGroups...)
+	*xMods = append((*xMods)[:0], saved.ExcludedMods...)
+	printLineToUser(fmt.Sprintf("Using the saved selection for %s: %s.", mc.BackupTargetName(connectedTarget), describeSelection(saved)))
+	return false, nil
+}
+
+// selectionFromFlags makes a selection from the values of the mode and
+// exclude flags. --full-server wins over --client-only, as it does in install.
+func selectionFromFlags(clientOnly, fullServer bool, xGroups, xMods []string) mc.Selection {
+	s := mc.Selection{
+		ExcludedGroups: append([]string{}, xGroups...),
+		ExcludedMods:   append([]string{}, xMods...),
+	}
+	if fullServer {
+		s.Mode = mc.SelectionFullServer
+	} else if clientOnly {
+		s.Mode = mc.SelectionClientOnly
+	}
+	return s
+}
+
+// describeSelection summarizes the selection in a line
+func describeSelection(s mc.Selection) string {
+	parts := []string{}
+	switch s.Mode {
+	case mc.SelectionClientOnly:
+		parts = append(parts, "client mods only")
+	case mc.SelectionFullServer:
+		parts = append(parts, "every server mod")
+	default:
+		parts = append(parts, "client and server mods")
+	}
+	if len(s.ExcludedGroups) > 0 {
+		parts = append(parts, "excluding groups "+strings.Join(s.ExcludedGroups, ","))
+	}
+	if len(s.ExcludedMods) > 0 {
+		parts = append(parts, "excluding mods "+strings.Join(s.ExcludedMods, ","))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// appendMissing appends the values which aren't in the slice yet
+func appendMissing(s []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, existing := range s {
+			if existing == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s = append(s, v)
+		}
+	}
+	return s
 }
 
 func getServerModGroupNames(m map[string]*mc.ServerGroup) []string {
@@ -112,7 +326,18 @@ func getServerModGroupNames(m map[string]*mc.ServerGroup) []string {
 	return keys
 }
 
-// CreateDefaultDownloader initializes a new mod downloader with a real HTTP Client
-func CreateDefaultDownloader(fs mc.FileSystem) mc.ModDownloader {
-	return mc.NewModDownloader(mc.NewHTTPClient(), fs)
+// CreateDefaultDownloader initializes a new mod downloader with a real HTTP
+// Client, configured by the HTTP options, which keeps downloads in the jar
+// cache
+func CreateDefaultDownloader(fs mc.FileSystem) (mc.ModDownloader, error) {
+	opts, err := getHTTPOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	hc, err := mc.NewConfiguredHTTPClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return mc.NewModDownloader(hc, fs, mc.DefaultJarCache()), nil
 }
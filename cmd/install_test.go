@@ -1,14 +1,19 @@
 package cmd_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"errors"
 	"mcmods/cmd"
 	"mcmods/mc"
 	. "mcmods/testdata"
+	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
 )
 
 func TestCmds(t *testing.T) {
@@ -24,8 +29,8 @@ var _ = Describe("Install Cmd", func() {
 		td = rootCmdTestSetup()
 
 		dl = &fakeDownloader{}
-		cmd.CreateDownloaderFunc = func(fs mc.FileSystem) mc.ModDownloader {
-			return dl
+		cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+			return dl, nil
 		}
 	})
 
@@ -227,11 +232,102 @@ var _ = Describe("Install Cmd", func() {
 		})
 	})
 
+	Context("--target", func() {
+		archivePath := filepath.Join("/packs", "pack.zip")
+
+		BeforeEach(func() {
+			cmd.TargetFs = td.fs
+			cmd.Installer = mc.NewModInstaller()
+			cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+				return writingDownloader{Fs: fs}, nil
+			}
+		})
+
+		It("installs the filtered mods into a zip archive with a manifest", func() {
+			af := false
+			cmd.Filter = filterVerifier{
+				XGroups: []string{cmd.ServerOnlyGroupKey},
+				XMods:   []string{},
+				Cfg: &mc.UserModConfig{
+					ModInstallations: map[string]mc.ModInstallation{},
+					ClientMods:       TestingConfig.ClientMods,
+				},
+				Force:       true,
+				Visited:     &af,
+				emptyFilter: emptyFilter{Return: []*mc.Mod{TestingClientMod1, TestingServerRequired1}},
+			}
+			cmd.RootCmd.SetArgs([]string{"install", "--target", "zip:" + archivePath})
+
+			executeAndVerifyOutput(td.outBuffer, "Wrote 2 mod(s) to "+archivePath+".", true)
+
+			b, err := afero.ReadFile(td.fs, archivePath)
+			Expect(err).To(BeNil())
+			zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+			Expect(err).To(BeNil())
+			names := []string{}
+			for _, f := range zr.File {
+				names = append(names, f.Name)
+			}
+			Expect(names).To(ConsistOf(
+				"mods/",
+				"mods/"+TestingClientMod1.CliName+".jar",
+				"mods/"+TestingServerRequired1.CliName+".jar",
+				mc.PackManifestFileName,
+			))
+			Expect(*td.cfgIoSpy.Saved).To(BeFalse())
+		})
+
+		It("doesn't connect to the server or lock the install", func() {
+			cmd.Filter = emptyFilter{Return: []*mc.Mod{TestingServerRequired1}}
+			cmd.ViperInstance.Set(mc.FTPServerKey, "mc.example.com")
+			connected := false
+			cmd.CreateFsFunc = func(ftpArgs *mc.FTPArgs) (mc.FileSystem, error) {
+				connected = ftpArgs != nil
+				return mc.LocalFileSystem{Fs: td.fs}, nil
+			}
+			held, err := mc.AcquireLock(mc.LocalFileSystem{Fs: td.fs}, time.Now(), time.Hour, false)
+			Expect(err).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"install", "--full-server", "--target", "zip:" + archivePath})
+
+			executeAndVerifyOutput(td.outBuffer, "Wrote 1 mod(s) to "+archivePath+".", true)
+
+			Expect(connected).To(BeFalse())
+			lock, _ := mc.ReadLock(mc.LocalFileSystem{Fs: td.fs})
+			Expect(lock.Token).To(Equal(held.Token))
+		})
+
+		It("rejects server flags", func() {
+			cmd.Filter = emptyFilter{}
+			cmd.RootCmd.SetArgs([]string{"install", "--remote", "survival", "--target", "zip:" + archivePath})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+
+		It("rejects unknown targets", func() {
+			cmd.Filter = emptyFilter{}
+			cmd.RootCmd.SetArgs([]string{"install", "--target", "tar:" + archivePath})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+
+		It("doesn't leave an archive behind when installing fails", func() {
+			cmd.Filter = emptyFilter{Return: []*mc.Mod{TestingClientMod1}}
+			cmd.Installer = emptyInstaller{Return: errors.New("install err")}
+			cmd.RootCmd.SetArgs([]string{"install", "--target", "zip:" + archivePath})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+
+			exists, _ := afero.Exists(td.fs, archivePath)
+			Expect(exists).To(BeFalse())
+		})
+	})
+
 	Context("CreateDefaultDownloader", func() {
 		It("returns an initialized downloader", func() {
 			mcfs := mc.LocalFileSystem{Fs: td.fs}
-			dl := cmd.CreateDefaultDownloader(mcfs)
+			dl, err := cmd.CreateDefaultDownloader(mcfs)
 
+			Expect(err).To(BeNil())
 			Expect(dl).ToNot(BeNil())
 
 			concrete := dl.(*mc.ModDownloaderImpl)
@@ -239,6 +335,34 @@ var _ = Describe("Install Cmd", func() {
 			Expect(concrete.Fs).ToNot(BeNil())
 			Expect(concrete.HTTPClient).ToNot(BeNil())
 		})
+
+		It("returns errors from configuring the HTTP client", func() {
+			cmd.ViperInstance.Set(mc.HTTPProxyKey, "not a proxy url")
+			defer cmd.ViperInstance.Set(mc.HTTPProxyKey, "")
+
+			_, err := cmd.CreateDefaultDownloader(mc.LocalFileSystem{Fs: td.fs})
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("uses the HTTP flags over the config", func() {
+			cmd.CreateDownloaderFunc = cmd.CreateDefaultDownloader
+			cmd.Filter = emptyFilter{}
+			cmd.RootCmd.SetArgs([]string{"install", "--proxy", "not a proxy url"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+
+		It("returns errors from creating the downloader during install", func() {
+			dlErr := errors.New("downloader error")
+			cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+				return nil, dlErr
+			}
+			cmd.Filter = emptyFilter{}
+			cmd.RootCmd.SetArgs([]string{"install"})
+
+			Expect(cmd.RootCmd.Execute()).To(Equal(dlErr))
+		})
 	})
 })
 
@@ -331,6 +455,28 @@ func (i clientConfigIoSpy) Save(cfg *mc.UserModConfig) error {
 
 type fakeDownloader struct{}
 
-func (fakeDownloader) Download(mod *mc.Mod, relPath string) error {
-	return nil
+func (fakeDownloader) Download(mod *mc.Mod, relPath string) (*mc.DownloadResult, error) {
+	return &mc.DownloadResult{URL: mod.LatestURL}, nil
+}
+
+// writes the mod's name as its content
+type writingDownloader struct {
+	Fs mc.FileSystem
+}
+
+func (d writingDownloader) Download(mod *mc.Mod, relPath string) (*mc.DownloadResult, error) {
+	content := []byte(mod.CliName)
+	if err := d.Fs.WriteFile(bytes.NewReader(content), relPath); err != nil {
+		return nil, err
+	}
+	return &mc.DownloadResult{URL: mod.LatestURL, SHA256: mc.HashSHA256(content), Size: int64(len(content))}, nil
+}
+
+// fails every download
+type failingDownloader struct {
+	Err error
+}
+
+func (d failingDownloader) Download(mod *mc.Mod, relPath string) (*mc.DownloadResult, error) {
+	return nil, d.Err
 }
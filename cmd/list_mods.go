@@ -3,6 +3,8 @@ package cmd
 import (
 	"errors"
 	"mcmods/mc"
+	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -13,6 +15,10 @@ var (
 	listClient       *bool
 	listServer       *bool
 	listGroup        *string
+
+	listInstalledSince  *string
+	listInstalledBefore *string
+	listSortInstalled   *bool
 )
 
 // modCmd represents the mod command
@@ -28,10 +34,33 @@ Examples:
  $ list mods --client --not-installed
  $ list mods --group performance
  $ list mods --server --installed
+ $ list mods --installed-since 2021-06-01 --sort-installed
  
  Providing both --installed and --not-installed is the same as providing
- neither. The --client and --server flags work similarly.`,
+ neither. The --client and --server flags work similarly.
+
+ The install time filters only show installed mods. Dates are given as
+ YYYY-MM-DD in local time, or in RFC 3339 format. Mods installed by older
+ versions of the tool may have no install time, so they never match.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := parseHistoryDate(*listInstalledSince, false)
+		if err != nil {
+			return err
+		}
+		before, err := parseHistoryDate(*listInstalledBefore, false)
+		if err != nil {
+			return err
+		}
+
+		timeFiltered := !since.IsZero() || !before.IsZero()
+		if timeFiltered {
+			if *listNotInstalled && !*listInstalled {
+				return errors.New("--installed-since and --installed-before can't be combined with --not-installed")
+			}
+			*listInstalled = true
+			*listNotInstalled = false
+		}
+
 		if !*listInstalled && !*listNotInstalled {
 			*listInstalled = true
 			*listNotInstalled = true
@@ -52,21 +81,35 @@ Examples:
 			*listServer = true
 		}
 
+		mods := []*mc.Mod{}
 		if *listServer {
-			for _, mod := range getServerMods() {
-				printLineToUser(mod.CliName)
-			}
+			mods = append(mods, getServerMods()...)
 		}
-
+		clientMods := []*mc.Mod{}
 		if *listClient {
-			mods := getClientMods()
-			max := len(mods) - 1
-			for i, mod := range mods {
-				if i == max {
-					printToUser(mod.CliName)
-				} else {
-					printLineToUser(mod.CliName)
-				}
+			clientMods = getClientMods()
+			mods = append(mods, clientMods...)
+		}
+
+		if timeFiltered {
+			mods = filterInstallTime(mods, since, before)
+			clientMods = filterInstallTime(clientMods, since, before)
+		}
+		if *listSortInstalled {
+			sortByInstallTime(mods)
+		}
+
+		// every server mod is printed with a newline, and only a client mod
+		// ends the output without one
+		max := len(mods) - 1
+		if len(clientMods) == 0 {
+			max = -1
+		}
+		for i, mod := range mods {
+			if i == max {
+				printToUser(mod.CliName)
+			} else {
+				printLineToUser(mod.CliName)
 			}
 		}
 		return nil
@@ -87,6 +130,12 @@ func init() {
 	listServer = flags.BoolP("server", "s", false, "Show only server mods.")
 
 	listGroup = flags.StringP("group", "g", "", "Show only mods from the specified group.")
+
+	listInstalledSince = flags.String("installed-since", "", "Show only mods installed on or after this date or time.")
+
+	listInstalledBefore = flags.String("installed-before", "", "Show only mods installed before this date or time.")
+
+	listSortInstalled = flags.Bool("sort-installed", false, "Sort the mods by install time, oldest first. Mods which aren't installed come last.")
 }
 
 func getClientMods() []*mc.Mod {
@@ -115,3 +164,33 @@ func getMods(mods []*mc.Mod, apndTgt []*mc.Mod) []*mc.Mod {
 
 	return apndTgt
 }
+
+// filterInstallTime returns the mods installed in the time range. Since is
+// inclusive, and before is exclusive. Either can be zero for an open range.
+func filterInstallTime(mods []*mc.Mod, since, before time.Time) []*mc.Mod {
+	filtered := []*mc.Mod{}
+	for _, mod := range mods {
+		t := UserModConfig.ModInstallations[mod.CliName].Timestamp
+		if t.IsZero() || t.Before(since) || !before.IsZero() && !t.Before(before) {
+			continue
+		}
+		filtered = append(filtered, mod)
+	}
+	return filtered
+}
+
+// sortByInstallTime sorts the mods by install time, oldest first, followed by
+// the mods with no install time by name
+func sortByInstallTime(mods []*mc.Mod) {
+	sort.SliceStable(mods, func(i, j int) bool {
+		a := UserModConfig.ModInstallations[mods[i].CliName].Timestamp
+		b := UserModConfig.ModInstallations[mods[j].CliName].Timestamp
+		switch {
+		case a.IsZero() && b.IsZero():
+			return mods[i].CliName < mods[j].CliName
+		case a.IsZero() || b.IsZero():
+			return b.IsZero()
+		}
+		return a.Before(b)
+	})
+}
@@ -35,6 +35,16 @@ var _ = Describe("List Cmd", func() {
 
 				executeAndVerifyOutput(td.outBuffer, serverModOutput, false)
 			})
+
+			It("ends each line with a newline", func() {
+				cmd.RootCmd.SetArgs([]string{"list", "mods", "--server"})
+
+				Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+				out := td.outBuffer.String()
+				Expect(out).To(HaveSuffix("\n"))
+				Expect(strings.Split(strings.TrimSuffix(out, "\n"), "\n")).To(ConsistOf(TestingServerCliNames))
+			})
 		})
 
 		Context("client and server", func() {
@@ -129,6 +139,32 @@ var _ = Describe("List Cmd", func() {
 			})
 		})
 
+		Context("install time", func() {
+			It("shows only mods installed in the range", func() {
+				cmd.RootCmd.SetArgs([]string{"list", "mods", "--installed-since", "2021-06-01T00:00:00Z", "--installed-before", "2021-06-02T00:00:00Z"})
+
+				executeAndVerifyOutput(td.outBuffer, TestingClientMod1.CliName, true)
+			})
+
+			It("sorts the mods by install time, oldest first", func() {
+				cmd.RootCmd.SetArgs([]string{"list", "mods", "--installed", "--sort-installed"})
+
+				executeAndVerifyOutput(td.outBuffer, TestingClientMod1.CliName+"\n"+TestingServerRequired1.CliName, true)
+			})
+
+			It("returns an error when combined with the not installed switch", func() {
+				cmd.RootCmd.SetArgs([]string{"list", "mods", "--installed-since", "2021-06-01", "--not-installed"})
+
+				Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+			})
+
+			It("returns an error for invalid dates", func() {
+				cmd.RootCmd.SetArgs([]string{"list", "mods", "--installed-before", "yesterday"})
+
+				Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+			})
+		})
+
 		Context("group", func() {
 			requiredGroupName := "required"
 			invalidGroup := "invalid"
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/mc"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lsLong *bool
+)
+
+// lsCmd represents the ls command
+var lsCmd = &cobra.Command{
+	Use:   "ls [path]",
+	Short: "List the files in the mods folder",
+	Long: `
+Ls lists what's actually in the mods folder of the target, which is the local
+Minecraft install, or the server when connecting with a password or key file.
+Directories end with a slash. An optional path lists a folder or file inside
+the mods folder.
+
+Examples:
+ $ ls
+ $ ls --long
+ $ ls -l --password <pw>`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		relPath := mc.ModFolderName
+		if len(args) > 0 {
+			relPath = filepath.Join(relPath, args[0])
+		}
+
+		fi, err := fs.Stat(relPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s doesn't exist", filepath.ToSlash(relPath))
+		} else if err != nil {
+			return err
+		}
+
+		infos := []mc.FileInfo{fi}
+		if fi.IsDir {
+			if infos, err = fs.ReadDir(relPath); err != nil {
+				return err
+			}
+		}
+
+		if len(infos) == 0 {
+			printToUser("No files.")
+			return nil
+		}
+
+		lines := make([]string, 0, len(infos))
+		for _, info := range infos {
+			lines = append(lines, formatFileInfo(info, *lsLong))
+		}
+		printToUser(strings.Join(lines, "\n"))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(lsCmd)
+
+	lsLong = lsCmd.Flags().BoolP("long", "l", false, "Include the size and modification time of each file.")
+}
+
+func formatFileInfo(fi mc.FileInfo, long bool) string {
+	name := fi.Name
+	if fi.IsDir {
+		name += "/"
+	}
+	if !long {
+		return name
+	}
+
+	modTime := "-"
+	if !fi.ModTime.IsZero() {
+		modTime = fi.ModTime.Local().Format("2006-01-02 15:04")
+	}
+	return fmt.Sprintf("%10d  %16s  %s", fi.Size, modTime, name)
+}
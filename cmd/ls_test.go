@@ -0,0 +1,63 @@
+package cmd_test
+
+import (
+	"mcmods/cmd"
+	"mcmods/mc"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Ls Cmd", func() {
+	var td *rootTestData
+	var modsDir string
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.InstallPathKey, "/minecraft")
+		modsDir = filepath.Join("/minecraft", mc.ModFolderName)
+		Expect(td.fs.MkdirAll(filepath.Join(modsDir, "sub"), 0755)).To(BeNil())
+		Expect(afero.WriteFile(td.fs, filepath.Join(modsDir, "b.jar"), []byte("12345"), 0644)).To(BeNil())
+		Expect(afero.WriteFile(td.fs, filepath.Join(modsDir, "a.jar"), []byte("1"), 0644)).To(BeNil())
+	})
+
+	It("lists the mods folder sorted by name", func() {
+		cmd.RootCmd.SetArgs([]string{"ls"})
+
+		executeAndVerifyOutput(td.outBuffer, "a.jar\nb.jar\nsub/", true)
+	})
+
+	It("lists the size and modification time with --long", func() {
+		modTime := time.Date(2022, 1, 2, 3, 4, 0, 0, time.Local)
+		Expect(td.fs.Chtimes(filepath.Join(modsDir, "b.jar"), modTime, modTime)).To(BeNil())
+		cmd.RootCmd.SetArgs([]string{"ls", "--long", "b.jar"})
+
+		executeAndVerifyOutput(td.outBuffer, "         5  2022-01-02 03:04  b.jar", true)
+	})
+
+	It("lists a folder inside the mods folder", func() {
+		Expect(afero.WriteFile(td.fs, filepath.Join(modsDir, "sub", "c.jar"), []byte{}, 0644)).To(BeNil())
+		cmd.RootCmd.SetArgs([]string{"ls", "sub"})
+
+		executeAndVerifyOutput(td.outBuffer, "c.jar", true)
+	})
+
+	It("prints a message for empty folders", func() {
+		cmd.RootCmd.SetArgs([]string{"ls", "sub"})
+
+		executeAndVerifyOutput(td.outBuffer, "No files.", true)
+	})
+
+	It("returns an error for missing paths", func() {
+		cmd.RootCmd.SetArgs([]string{"ls", "missing"})
+
+		err := cmd.RootCmd.Execute()
+
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("mods/missing"))
+	})
+})
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"mcmods/mc"
 
 	"github.com/spf13/cobra"
@@ -25,10 +26,21 @@ and an absolute path:
  $ mcpath --set /absolute/path/to/.minecraft
 
 Note: No validation is done on the provided path. Make sure it's correct!
-Surround the path with double-quotes if it contains spaces. `,
+Surround the path with double-quotes if it contains spaces.
+
+While a profile is in use, the path of the profile is printed and set instead
+(see profile --help).`,
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		if *path == "" {
 			printToUser(mc.GetInstallPath())
+		} else if profile := mc.CurrentProfile(); profile != nil {
+			updated := *profile
+			updated.InstallPath = *path
+			if err = mc.SetProfile(updated); err != nil {
+				return err
+			}
+			cobra.CheckErr(ViperInstance.WriteConfig())
+			printToUser(fmt.Sprintf("Path updated for profile %s.", profile.Name))
 		} else {
 			ViperInstance.Set(mc.InstallPathKey, path)
 			cobra.CheckErr(ViperInstance.WriteConfig())
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/mc"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// InstallLocal selects the local Minecraft install in commands which
+	// work with more than one install. Followed by a : and a directory, it
+	// selects another local Minecraft directory.
+	InstallLocal = "local"
+
+	// InstallRemotePrefix prefixes the name of a remote to select it in
+	// commands which work with more than one install
+	InstallRemotePrefix = "remote:"
+)
+
+var (
+	// Mirrorer copies installations between file systems
+	Mirrorer = mc.NewInstallMirrorer()
+
+	mirrorFrom *string
+	mirrorTo   *string
+)
+
+// mirrorCmd represents the mirror command
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Copy the installed mods from one install to another",
+	Long: `
+Mirror makes the mods installed on one target match another, without
+downloading them again. Each jar the tool installed on the source is copied to
+the destination along with its installation record, unless the destination
+already has the same jar. Jars installed by the tool on the destination, but not
+on the source, are deleted. Jars the tool didn't install are left alone.
+
+The source and destination are one of
+ local           the local Minecraft install
+ local:<dir>     another Minecraft directory, e.g. a local test server
+ remote:<name>   a named remote, see the remote command
+
+Examples:
+ $ mirror --from local --to remote:survival
+ $ mirror --from local:/srv/test-server --to remote:survival
+ $ mirror --from remote:survival --to remote:creative
+
+Passwords for remotes are taken from the credential store, or asked for. The
+source jars are checked against their recorded hashes before they're copied;
+run verify --repair on the source if they don't match.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if *mirrorFrom == "" || *mirrorTo == "" {
+			return errors.New("both --from and --to are required")
+		}
+		if *mirrorFrom == *mirrorTo {
+			return errors.New("--from and --to must be different")
+		}
+
+		// problems from here on aren't usage errors
+		cmd.SilenceUsage = true
+
+		src, err := openInstall(cmd, *mirrorFrom)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		dst, err := openInstall(cmd, *mirrorTo)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+
+		srcCfg, err := ConfigIoFunc(src).LoadOrNew()
+		if err != nil {
+			return err
+		}
+
+		staleAfter := mc.GetLockStaleAfter()
+		lock, err := mc.AcquireLock(dst, time.Now(), staleAfter, breakLock)
+		if err != nil {
+			return err
+		}
+		stopLockRefresh := mc.KeepLockFresh(dst, lock, staleAfter)
+		defer func() {
+			stopLockRefresh()
+			mc.ReleaseLock(dst, lock)
+		}()
+
+		dstIo := ConfigIoFunc(dst)
+		dstCfg, err := dstIo.LoadOrNew()
+		if err != nil {
+			return err
+		}
+		if dstCfg.SchemaVersion > mc.CurrentSchemaVersion {
+			return mc.ErrNewerSchemaVersion
+		}
+
+		res, err := Mirrorer.Mirror(src, srcCfg, dst, dstCfg)
+		if len(res.Copied) > 0 || len(res.Deleted) > 0 {
+			// record whatever was copied before any error
+			if saveErr := dstIo.Save(dstCfg); err == nil {
+				err = saveErr
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		printToUser(fmt.Sprintf("Copied %d mod(s), deleted %d, %d unchanged.", len(res.Copied), len(res.Deleted), res.Unchanged))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(mirrorCmd)
+
+	mirrorFrom = mirrorCmd.Flags().String("from", "", fmt.Sprintf("The install to copy from: %s, %s<dir> or %s<name>.", InstallLocal, InstallLocal+":", InstallRemotePrefix))
+	mirrorTo = mirrorCmd.Flags().String("to", "", fmt.Sprintf("The install to copy to: %s, %s<dir> or %s<name>.", InstallLocal, InstallLocal+":", InstallRemotePrefix))
+}
+
+// openInstall opens the file system of an install selected by InstallLocal,
+// a local directory, or a remote
+func openInstall(cmd *cobra.Command, endpoint string) (mc.FileSystem, error) {
+	switch {
+	case endpoint == InstallLocal:
+		return mc.LocalFileSystem{Fs: TargetFs}, nil
+	case strings.HasPrefix(endpoint, InstallLocal+":"):
+		dir := strings.TrimPrefix(endpoint, InstallLocal+":")
+		if dir == "" {
+			return nil, fmt.Errorf("no directory given in %s", endpoint)
+		}
+		return mc.LocalFileSystem{Fs: TargetFs, Dir: dir}, nil
+	case strings.HasPrefix(endpoint, InstallRemotePrefix):
+		name := strings.TrimPrefix(endpoint, InstallRemotePrefix)
+		remote, err := mc.GetRemote(name)
+		if err != nil {
+			return nil, err
+		}
+
+		args := remote.FTPArgs()
+		args.KeyFile = sftpKeyFile
+		if args.Pw, err = storedRemotePassword(cmd, name); err != nil {
+			return nil, err
+		}
+		if args.Pw == "" && args.KeyFile == "" {
+			printLineToUser(fmt.Sprintf("Connecting to remote %s", name))
+			if args.Pw, err = PasswordPrompt.GetInput(cmd.ErrOrStderr(), cmd.InOrStdin()); err != nil {
+				return nil, err
+			}
+		}
+		setConnectionArgs(args)
+
+		return CreateFsFunc(args)
+	default:
+		return nil, fmt.Errorf("unknown install %q; use %s, %s<dir> or %s<name>", endpoint, InstallLocal, InstallLocal+":", InstallRemotePrefix)
+	}
+}
@@ -0,0 +1,91 @@
+package cmd_test
+
+import (
+	"errors"
+	"mcmods/cmd"
+	"mcmods/mc"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Mirror Cmd", func() {
+	var td *rootTestData
+	var ftpArgs *mc.FTPArgs
+
+	content := []byte("jar content")
+	survival := mc.Remote{Name: "survival", Server: "mc.example.com:21", User: "admin", BaseDir: "/minecraft"}
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.InstallPathKey, "/minecraft")
+		cmd.ViperInstance.Set(mc.RemotesKey, []interface{}{})
+		cmd.ViperInstance.Set(mc.DefaultRemoteKey, "")
+		Expect(mc.SetRemote(survival)).To(BeNil())
+
+		cmd.TargetFs = td.fs
+		cmd.Mirrorer = mc.NewInstallMirrorer()
+		cmd.ConfigIoFunc = mc.NewUserModConfigIo
+		cmd.PasswordPrompt = noOpPrompt{ReturnStr: "pw"}
+
+		// the remote is another directory of the same file system
+		ftpArgs = nil
+		cmd.CreateFsFunc = func(args *mc.FTPArgs) (mc.FileSystem, error) {
+			if args == nil {
+				return mc.LocalFileSystem{Fs: td.fs}, nil
+			}
+			ftpArgs = args
+			return mc.LocalFileSystem{Fs: td.fs, Dir: "/server"}, nil
+		}
+
+		src := mc.LocalFileSystem{Fs: td.fs, Dir: "/test-server"}
+		Expect(afero.WriteFile(td.fs, filepath.Join("/test-server", mc.ModInstallPath("mod-a")), content, 0644)).To(BeNil())
+		Expect(mc.NewUserModConfigIo(src).Save(&mc.UserModConfig{ModInstallations: map[string]mc.ModInstallation{
+			"mod-a": {DownloadURL: "https://a", SHA256: mc.HashSHA256(content), Size: int64(len(content))},
+		}})).To(BeNil())
+	})
+
+	It("copies the installs to a remote and saves its records", func() {
+		cmd.RootCmd.SetArgs([]string{"mirror", "--from", "local:/test-server", "--to", "remote:survival"})
+
+		executeAndVerifyOutput(td.outBuffer, "Connecting to remote survival\nCopied 1 mod(s), deleted 0, 0 unchanged.", true)
+
+		Expect(ftpArgs.Server).To(Equal(survival.Server))
+		Expect(ftpArgs.BaseDir).To(Equal(survival.BaseDir))
+		Expect(ftpArgs.Pw).To(Equal("pw"))
+		Expect(ftpArgs.TimeoutMs).To(Equal(cmd.FTPTimeoutMs))
+
+		b, err := afero.ReadFile(td.fs, filepath.Join("/server", mc.ModInstallPath("mod-a")))
+		Expect(err).To(BeNil())
+		Expect(b).To(Equal(content))
+
+		cfg, err := mc.NewUserModConfigIo(mc.LocalFileSystem{Fs: td.fs, Dir: "/server"}).LoadOrNew()
+		Expect(err).To(BeNil())
+		Expect(cfg.ModInstallations).To(HaveKey("mod-a"))
+	})
+
+	It("mirrors to the local install", func() {
+		cmd.RootCmd.SetArgs([]string{"mirror", "--from", "local:/test-server", "--to", "local"})
+
+		executeAndVerifyOutput(td.outBuffer, "Copied 1 mod(s), deleted 0, 0 unchanged.", true)
+
+		exists, _ := afero.Exists(td.fs, filepath.Join("/minecraft", mc.ModInstallPath("mod-a")))
+		Expect(exists).To(BeTrue())
+	})
+
+	It("returns an error for missing or unknown installs", func() {
+		cmd.RootCmd.SetArgs([]string{"mirror", "--from", "local"})
+		Expect(cmd.RootCmd.Execute()).To(Equal(errors.New("both --from and --to are required")))
+
+		cmd.ResetVars()
+		cmd.RootCmd.SetArgs([]string{"mirror", "--from", "local", "--to", "server"})
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+
+		cmd.ResetVars()
+		cmd.RootCmd.SetArgs([]string{"mirror", "--from", "local", "--to", "remote:unknown"})
+		Expect(cmd.RootCmd.Execute()).To(Equal(errors.New("unknown remote: unknown")))
+	})
+})
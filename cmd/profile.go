@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/mc"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	profilePath        *string
+	profileGameVersion *string
+	profileModLoader   *string
+	profileGroups      *[]string
+	profileXMods       *[]string
+	profileUseNone     *bool
+)
+
+// profileCmd represents the profile command
+var profileCmd = &cobra.Command{
+	Use:   "profile [command]",
+	Short: "Manage the Minecraft instances mods are installed in",
+	Long: `
+Profiles are named Minecraft instances on this machine, for players running
+more than one. Each profile has its own install path, Minecraft version and mod
+loader, and selection of server mod groups and excluded mods. Use a profile
+with any command by giving --profile <name>:
+ $ install --profile creative
+
+The active profile is used when none is given; without one, the global
+settings are used (see mcpath --help). Installation records are kept in the
+mods folder of each profile's install path, so they're kept separately.
+
+See
+ $ profile create --help
+for more information on creating profiles.`,
+}
+
+// profileCreateCmd represents the profile create command
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create or update a profile",
+	Long: `
+Adds a named Minecraft instance to the tool's config, or replaces the profile
+with the same name. Names use lower case letters, numbers, - and _.
+
+The path is the absolute path of the instance's Minecraft directory, which
+contains the mods folder. Each profile needs its own directory.
+
+Install only installs the selected server mod groups of the profile, or every
+group if none are selected, and never installs its excluded mods. The Minecraft
+version and mod loader are used by bump; the global ones are used if they
+aren't given.
+
+Examples:
+ $ profile create vanilla-plus --path /home/me/.minecraft
+ $ profile create creative --path /home/me/creative --group required --x-mod some-mod
+ $ profile create prism --path /home/me/.local/share/PrismLauncher/instances/yams/.minecraft --game-version 1.18.1`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profile := mc.Profile{
+			Name:         args[0],
+			InstallPath:  *profilePath,
+			GameVersion:  *profileGameVersion,
+			ModLoader:    *profileModLoader,
+			Groups:       append([]string{}, *profileGroups...),
+			ExcludedMods: append([]string{}, *profileXMods...),
+		}
+
+		if profile.InstallPath == "" {
+			return errors.New("A profile requires --path")
+		}
+
+		_, err := mc.GetProfile(profile.Name)
+		existed := err == nil
+
+		if err = mc.SetProfile(profile); err != nil {
+			return err
+		}
+
+		if err = ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		if existed {
+			printToUser("Profile updated.")
+		} else {
+			printToUser("Profile created.")
+		}
+		return nil
+	},
+}
+
+// profileListCmd represents the profile list command
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles",
+	Long: `
+Prints out the profiles. The active profile is marked with a *.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profiles, err := mc.GetProfiles()
+		if err != nil {
+			return err
+		}
+
+		if len(profiles) == 0 {
+			printToUser("No profiles.")
+			return nil
+		}
+
+		active := ViperInstance.GetString(mc.ActiveProfileKey)
+		for i, p := range profiles {
+			marker := " "
+			if p.Name == active {
+				marker = "*"
+			}
+
+			line := fmt.Sprintf("%s %s  %s", marker, p.Name, p.InstallPath)
+			if p.GameVersion != "" || p.ModLoader != "" {
+				line += "  version: " + strings.TrimSpace(p.GameVersion+" "+p.ModLoader)
+			}
+			if len(p.Groups) > 0 {
+				line += "  groups: " + strings.Join(p.Groups, ",")
+			}
+			if len(p.ExcludedMods) > 0 {
+				line += "  excluding: " + strings.Join(p.ExcludedMods, ",")
+			}
+
+			if i == len(profiles)-1 {
+				printToUser(line)
+			} else {
+				printLineToUser(line)
+			}
+		}
+		return nil
+	},
+}
+
+// profileUseCmd represents the profile use command
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name|--none>",
+	Short: "Set the profile used when none is given",
+	Long: `
+Sets the profile used by every command run without --profile. Use --none to go
+back to the global settings.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if *profileUseNone == (len(args) > 0) {
+			return errors.New("Specify either a profile name or --none")
+		}
+
+		name := ""
+		if len(args) > 0 {
+			if _, err := mc.GetProfile(args[0]); err != nil {
+				return err
+			}
+			name = args[0]
+		}
+
+		ViperInstance.Set(mc.ActiveProfileKey, name)
+		if err := ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		if name == "" {
+			printToUser("Using the global settings.")
+		} else {
+			printToUser(fmt.Sprintf("Using profile %s.", name))
+		}
+		return nil
+	},
+}
+
+// profileDeleteCmd represents the profile delete command
+var profileDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a profile",
+	Long: `
+Removes the profile from the tool's config. Nothing is changed in its Minecraft
+directory, including its mods and installation records.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := mc.RemoveProfile(args[0]); err != nil {
+			return err
+		}
+
+		if err := ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		printToUser("Profile deleted.")
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(profileCmd)
+
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+
+	flags := profileCreateCmd.Flags()
+
+	profilePath = flags.String("path", "", "The absolute path of the instance's Minecraft directory.")
+	profileGameVersion = flags.String("game-version", "", "The Minecraft version mods are resolved for, e.g. 1.18.1.")
+	profileModLoader = flags.String("mod-loader", "", "The mod loader mods are resolved for, e.g. fabric.")
+	profileGroups = flags.StringSlice("group", []string{}, "The server mod groups to install. Every group is installed if none are given. Separate names with commas, no spaces.")
+	profileXMods = flags.StringSlice("x-mod", []string{}, "Mods to never install in the instance. Separate names with commas, no spaces.")
+
+	profileUseNone = profileUseCmd.Flags().Bool("none", false, "Stop using a profile, and use the global settings.")
+}
+
+// selectProfile returns the profile given with --profile, or else the active
+// profile, or nil if there is neither
+func selectProfile() (*mc.Profile, error) {
+	name := profileName
+	active := name == ""
+	if active {
+		name = ViperInstance.GetString(mc.ActiveProfileKey)
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, err := mc.GetProfile(name)
+	if err != nil && active {
+		return nil, fmt.Errorf("the active profile %s doesn't exist; change it with profile use", name)
+	} else if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
@@ -0,0 +1,192 @@
+package cmd_test
+
+import (
+	"mcmods/cmd"
+	"mcmods/mc"
+	. "mcmods/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Profile Cmd", func() {
+	var td *rootTestData
+
+	vanilla := mc.Profile{Name: "vanilla-plus", InstallPath: "/home/me/.minecraft", GameVersion: "1.18.1", ModLoader: "fabric"}
+	creative := mc.Profile{Name: "creative", InstallPath: "/home/me/creative", Groups: []string{"required"}, ExcludedMods: []string{"mod1"}}
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.ProfilesKey, []interface{}{})
+		cmd.ViperInstance.Set(mc.InstallPathKey, "/global/.minecraft")
+	})
+
+	Context("create", func() {
+		It("stores the profile", func() {
+			cmd.RootCmd.SetArgs([]string{"profile", "create", "creative", "--path", creative.InstallPath, "--group", "required", "--x-mod", "mod1"})
+
+			executeAndVerifyOutput(td.outBuffer, "Profile created.", true)
+
+			p, err := mc.GetProfile("creative")
+			Expect(err).To(BeNil())
+			Expect(p.InstallPath).To(Equal(creative.InstallPath))
+			Expect(p.Groups).To(Equal(creative.Groups))
+			Expect(p.ExcludedMods).To(Equal(creative.ExcludedMods))
+			Expect(cmd.ViperInstance.GetString(mc.InstallPathKey)).To(Equal("/global/.minecraft"))
+		})
+
+		It("updates existing profiles", func() {
+			Expect(mc.SetProfile(vanilla)).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"profile", "create", vanilla.Name, "--path", "/home/me/other"})
+
+			executeAndVerifyOutput(td.outBuffer, "Profile updated.", true)
+
+			p, _ := mc.GetProfile(vanilla.Name)
+			Expect(p.InstallPath).To(Equal("/home/me/other"))
+		})
+
+		It("requires the path", func() {
+			cmd.RootCmd.SetArgs([]string{"profile", "create", "creative"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+
+		It("rejects unknown groups", func() {
+			cmd.RootCmd.SetArgs([]string{"profile", "create", "creative", "--path", creative.InstallPath, "--group", "unknown"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+	})
+
+	Context("list", func() {
+		It("prints a message when there are no profiles", func() {
+			cmd.RootCmd.SetArgs([]string{"profile", "list"})
+
+			executeAndVerifyOutput(td.outBuffer, "No profiles.", true)
+		})
+
+		It("prints the profiles, marking the active one", func() {
+			Expect(mc.SetProfile(vanilla)).To(BeNil())
+			Expect(mc.SetProfile(creative)).To(BeNil())
+			cmd.ViperInstance.Set(mc.ActiveProfileKey, vanilla.Name)
+			cmd.RootCmd.SetArgs([]string{"profile", "list"})
+			expectedOutput := "  creative  /home/me/creative  groups: required  excluding: mod1\n" +
+				"* vanilla-plus  /home/me/.minecraft  version: 1.18.1 fabric"
+
+			executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
+		})
+	})
+
+	Context("use", func() {
+		It("sets the active profile", func() {
+			Expect(mc.SetProfile(vanilla)).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"profile", "use", vanilla.Name})
+
+			executeAndVerifyOutput(td.outBuffer, "Using profile vanilla-plus.", true)
+
+			Expect(cmd.ViperInstance.GetString(mc.ActiveProfileKey)).To(Equal(vanilla.Name))
+		})
+
+		It("goes back to the global settings, even when the active profile is unknown", func() {
+			cmd.ViperInstance.Set(mc.ActiveProfileKey, "deleted")
+			cmd.RootCmd.SetArgs([]string{"profile", "use", "--none"})
+
+			executeAndVerifyOutput(td.outBuffer, "Using the global settings.", true)
+
+			Expect(cmd.ViperInstance.GetString(mc.ActiveProfileKey)).To(BeEmpty())
+		})
+
+		It("returns an error for unknown profiles", func() {
+			cmd.RootCmd.SetArgs([]string{"profile", "use", "unknown"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+	})
+
+	Context("delete", func() {
+		It("deletes the profile", func() {
+			Expect(mc.SetProfile(vanilla)).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"profile", "delete", vanilla.Name})
+
+			executeAndVerifyOutput(td.outBuffer, "Profile deleted.", true)
+
+			_, err := mc.GetProfile(vanilla.Name)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("returns an error for unknown profiles", func() {
+			cmd.RootCmd.SetArgs([]string{"profile", "delete", "unknown"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+	})
+
+	Context("using a profile", func() {
+		BeforeEach(func() {
+			Expect(mc.SetProfile(vanilla)).To(BeNil())
+			Expect(mc.SetProfile(creative)).To(BeNil())
+		})
+
+		It("uses the install path of the profile given", func() {
+			cmd.RootCmd.SetArgs([]string{"mcpath", "--profile", creative.Name})
+
+			executeAndVerifyOutput(td.outBuffer, creative.InstallPath, true)
+		})
+
+		It("uses the active profile when none is given", func() {
+			cmd.ViperInstance.Set(mc.ActiveProfileKey, vanilla.Name)
+			cmd.RootCmd.SetArgs([]string{"mcpath"})
+
+			executeAndVerifyOutput(td.outBuffer, vanilla.InstallPath, true)
+		})
+
+		It("sets the path of the profile with mcpath", func() {
+			cmd.RootCmd.SetArgs([]string{"mcpath", "--profile", creative.Name, "--set", "/home/me/creative2"})
+
+			executeAndVerifyOutput(td.outBuffer, "Path updated for profile creative.", true)
+
+			p, _ := mc.GetProfile(creative.Name)
+			Expect(p.InstallPath).To(Equal("/home/me/creative2"))
+			Expect(cmd.ViperInstance.GetString(mc.InstallPathKey)).To(Equal("/global/.minecraft"))
+		})
+
+		It("installs only the selected groups, without the excluded mods", func() {
+			visited := false
+			cmd.Filter = filterVerifier{
+				XGroups: []string{"server-only", "optional", "performance"},
+				XMods:   creative.ExcludedMods,
+				Cfg:     TestingConfig,
+				Visited: &visited,
+				emptyFilter: emptyFilter{
+					Return: []*mc.Mod{},
+				},
+			}
+			cmd.Installer = emptyInstaller{}
+			cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+				return fakeDownloader{}, nil
+			}
+			cmd.RootCmd.SetArgs([]string{"install", "--profile", creative.Name})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			Expect(visited).To(BeTrue())
+		})
+
+		It("keeps the backups of each profile separately", func() {
+			mc.SetAutoBackup(cmd.InstallLocal, true)
+			cmd.RootCmd.SetArgs([]string{"backup", "auto", "off", "--profile", creative.Name})
+
+			executeAndVerifyOutput(td.outBuffer, "Automatic backups turned off for profile-creative.", true)
+
+			Expect(mc.AutoBackupEnabled("profile-creative")).To(BeFalse())
+			Expect(mc.AutoBackupEnabled(cmd.InstallLocal)).To(BeTrue())
+		})
+
+		It("keeps the profile commands working with an unknown profile", func() {
+			cmd.RootCmd.SetArgs([]string{"profile", "list", "--profile", "unknown"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"mcmods/mc"
+
+	"github.com/spf13/cobra"
+)
+
+// remoteCmd represents the remote command
+var remoteCmd = &cobra.Command{
+	Use:   "remote [command]",
+	Short: "Manage named servers to install mods on",
+	Long: `
+Remotes are named profiles of servers, for groups running more than one server.
+Each remote stores the server's host, user, protocol and the Minecraft directory
+on the server. Use a remote with any command by giving --remote <name> instead
+of --ftp-server and --user:
+ $ install --full-server --remote survival
+
+The password is asked for, unless it's given or stored with credentials set.
+
+The default remote is used whenever a password or key file is given without
+--remote or --ftp-server. Installation records are stored in the mods folder
+of each remote, so they're kept separately.
+
+See
+ $ remote add --help
+for more information on adding remotes.`,
+}
+
+// remoteListCmd represents the remote list command
+var remoteListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the remotes",
+	Long: `
+Prints out the remotes. The default remote is marked with a *.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remotes, err := mc.GetRemotes()
+		if err != nil {
+			return err
+		}
+
+		if len(remotes) == 0 {
+			printToUser("No remotes.")
+			return nil
+		}
+
+		defaultName := ViperInstance.GetString(mc.DefaultRemoteKey)
+		for i, r := range remotes {
+			marker := " "
+			if r.Name == defaultName {
+				marker = "*"
+			}
+
+			line := fmt.Sprintf("%s %s  %s@%s", marker, r.Name, r.User, r.Server)
+			if r.Protocol != "" {
+				line += fmt.Sprintf("  protocol: %s", r.Protocol)
+			}
+			if r.BaseDir != "" {
+				line += fmt.Sprintf("  dir: %s", r.BaseDir)
+			}
+
+			if i == len(remotes)-1 {
+				printToUser(line)
+			} else {
+				printLineToUser(line)
+			}
+		}
+		return nil
+	},
+}
+
+// remoteRemoveCmd represents the remote remove command
+var remoteRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a remote",
+	Long: `
+Removes the remote from the tool's config. Nothing is changed on the server.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := mc.RemoveRemote(args[0]); err != nil {
+			return err
+		}
+
+		if err := ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		printToUser("Remote removed.")
+		return nil
+	},
+}
+
+// remoteSetDefaultCmd represents the remote set-default command
+var remoteSetDefaultCmd = &cobra.Command{
+	Use:   "set-default <name>",
+	Short: "Set the remote used when none is given",
+	Long: `
+Sets the remote used when a password or key file is given without --remote or
+--ftp-server.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := mc.GetRemote(args[0]); err != nil {
+			return err
+		}
+
+		ViperInstance.Set(mc.DefaultRemoteKey, args[0])
+		if err := ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		printToUser(fmt.Sprintf("Default remote set to %s.", args[0]))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(remoteCmd)
+
+	remoteCmd.AddCommand(remoteListCmd)
+	remoteCmd.AddCommand(remoteRemoveCmd)
+	remoteCmd.AddCommand(remoteSetDefaultCmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/mc"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	remoteServer   *string
+	remoteUser     *string
+	remoteProtocol *string
+	remoteBaseDir  *string
+)
+
+// remoteAddCmd represents the remote add command
+var remoteAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add or update a remote",
+	Long: `
+Adds a named server to the tool's config, or replaces the remote with the same
+name. Names use lower case letters, numbers, - and _.
+
+The base directory is the Minecraft directory on the server, which contains the
+mods folder. It's relative to the login directory unless it starts with a /.
+
+Examples:
+ $ remote add survival --server mc.example.com:21 --user admin
+ $ remote add creative --server sftp://test.example.com --user builder --base-dir /srv/creative
+
+Servers run through a Pterodactyl-compatible hosting panel can be reached over
+the panel's file API instead of FTP. The server is the panel's URL, the user is
+the server identifier shown in the panel's URLs, and the password is a client
+API key from the panel's account settings:
+ $ remote add hosted --protocol pterodactyl --server https://panel.example.com --user 1a7ce997
+
+Passwords aren't stored with the remote. Store one in the encrypted credential
+store with credentials set remote <name>, or give it with each command.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		remote := mc.Remote{
+			Name:     args[0],
+			Server:   *remoteServer,
+			User:     *remoteUser,
+			Protocol: *remoteProtocol,
+			BaseDir:  *remoteBaseDir,
+		}
+
+		if remote.Server == "" || remote.User == "" {
+			return errors.New("A remote requires --server and --user")
+		}
+
+		switch remote.FTPArgs().GetProtocol() {
+		case mc.FTPProtocol, mc.SFTPProtocol, mc.PterodactylProtocol:
+		default:
+			return fmt.Errorf("unknown protocol: %s", remote.Protocol)
+		}
+
+		_, err := mc.GetRemote(remote.Name)
+		existed := err == nil
+
+		if err = mc.SetRemote(remote); err != nil {
+			return err
+		}
+
+		if err = ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		if existed {
+			printToUser("Remote updated.")
+		} else {
+			printToUser("Remote added.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	remoteCmd.AddCommand(remoteAddCmd)
+
+	flags := remoteAddCmd.Flags()
+
+	remoteServer = flags.String("server", "", "The server's host:port, or an sftp:// or ftps:// URL. The panel's URL for the panel protocol.")
+	remoteUser = flags.StringP("user", "u", "", "The username for logging in to the server. The server identifier for the panel protocol.")
+	remoteProtocol = flags.String("protocol", "", fmt.Sprintf("The protocol: %s, %s or %s. Defaults to %s, or %s for %s servers.", mc.FTPProtocol, mc.SFTPProtocol, mc.PterodactylProtocol, mc.FTPProtocol, mc.SFTPProtocol, mc.SFTPScheme))
+	remoteBaseDir = flags.String("base-dir", "", "The Minecraft directory on the server, which contains the mods folder.")
+}
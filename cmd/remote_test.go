@@ -0,0 +1,206 @@
+package cmd_test
+
+import (
+	"mcmods/cmd"
+	"mcmods/mc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+)
+
+var _ = Describe("Remote Cmd", func() {
+	var td *rootTestData
+
+	survival := mc.Remote{Name: "survival", Server: "mc.example.com:21", User: "admin", BaseDir: "/minecraft"}
+	creative := mc.Remote{Name: "creative", Server: "sftp://test.example.com", User: "builder", Protocol: mc.SFTPProtocol}
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.RemotesKey, []interface{}{})
+		cmd.ViperInstance.Set(mc.DefaultRemoteKey, "")
+	})
+
+	Context("add", func() {
+		It("stores the remote", func() {
+			cmd.RootCmd.SetArgs([]string{"remote", "add", "survival", "--server", survival.Server, "--user", survival.User, "--base-dir", survival.BaseDir})
+
+			executeAndVerifyOutput(td.outBuffer, "Remote added.", true)
+
+			r, err := mc.GetRemote("survival")
+			Expect(err).To(BeNil())
+			Expect(r).To(Equal(survival))
+		})
+
+		It("doesn't change the global server settings", func() {
+			cmd.ViperInstance.Set(mc.FTPUserKey, "global")
+			cmd.ViperInstance.Set(mc.ServerProtocolKey, "")
+			cmd.RootCmd.SetArgs([]string{"remote", "add", "creative", "--server", creative.Server, "-u", creative.User, "--protocol", mc.SFTPProtocol})
+
+			executeAndVerifyOutput(td.outBuffer, "Remote added.", true)
+
+			Expect(cmd.ViperInstance.GetString(mc.FTPUserKey)).To(Equal("global"))
+			Expect(cmd.ViperInstance.GetString(mc.ServerProtocolKey)).To(BeEmpty())
+			r, err := mc.GetRemote("creative")
+			Expect(err).To(BeNil())
+			Expect(r).To(Equal(creative))
+		})
+
+		It("updates existing remotes", func() {
+			Expect(mc.SetRemote(survival)).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"remote", "add", "survival", "--server", "new:21", "--user", survival.User})
+
+			executeAndVerifyOutput(td.outBuffer, "Remote updated.", true)
+
+			r, _ := mc.GetRemote("survival")
+			Expect(r.Server).To(Equal("new:21"))
+		})
+
+		It("requires the server and user", func() {
+			cmd.RootCmd.SetArgs([]string{"remote", "add", "survival", "--server", survival.Server})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+
+		It("accepts hosting panel remotes", func() {
+			cmd.RootCmd.SetArgs([]string{"remote", "add", "hosted", "--protocol", mc.PterodactylProtocol, "--server", "https://panel.example.com", "--user", "1a7ce997"})
+
+			executeAndVerifyOutput(td.outBuffer, "Remote added.", true)
+
+			r, err := mc.GetRemote("hosted")
+			Expect(err).To(BeNil())
+			Expect(r.FTPArgs().GetProtocol()).To(Equal(mc.PterodactylProtocol))
+		})
+
+		It("rejects unknown protocols", func() {
+			cmd.RootCmd.SetArgs([]string{"remote", "add", "survival", "--server", survival.Server, "--user", survival.User, "--protocol", "gopher"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+	})
+
+	Context("list", func() {
+		It("prints a message when there are no remotes", func() {
+			cmd.RootCmd.SetArgs([]string{"remote", "list"})
+
+			executeAndVerifyOutput(td.outBuffer, "No remotes.", true)
+		})
+
+		It("prints the remotes, marking the default", func() {
+			Expect(mc.SetRemote(survival)).To(BeNil())
+			Expect(mc.SetRemote(creative)).To(BeNil())
+			cmd.ViperInstance.Set(mc.DefaultRemoteKey, survival.Name)
+			cmd.RootCmd.SetArgs([]string{"remote", "list"})
+			expectedOutput := "  creative  builder@sftp://test.example.com  protocol: sftp\n" +
+				"* survival  admin@mc.example.com:21  dir: /minecraft"
+
+			executeAndVerifyOutput(td.outBuffer, expectedOutput, true)
+		})
+	})
+
+	Context("remove", func() {
+		It("removes the remote", func() {
+			Expect(mc.SetRemote(survival)).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"remote", "remove", survival.Name})
+
+			executeAndVerifyOutput(td.outBuffer, "Remote removed.", true)
+
+			_, err := mc.GetRemote(survival.Name)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("returns an error for unknown remotes", func() {
+			cmd.RootCmd.SetArgs([]string{"remote", "remove", "unknown"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+	})
+
+	Context("set-default", func() {
+		It("sets the default remote", func() {
+			Expect(mc.SetRemote(survival)).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"remote", "set-default", survival.Name})
+
+			executeAndVerifyOutput(td.outBuffer, "Default remote set to survival.", true)
+
+			Expect(cmd.ViperInstance.GetString(mc.DefaultRemoteKey)).To(Equal(survival.Name))
+		})
+
+		It("returns an error for unknown remotes", func() {
+			cmd.RootCmd.SetArgs([]string{"remote", "set-default", "unknown"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+	})
+
+	Context("connecting", func() {
+		var ftpArgs *mc.FTPArgs
+
+		BeforeEach(func() {
+			Expect(mc.SetRemote(survival)).To(BeNil())
+			Expect(mc.SetRemote(creative)).To(BeNil())
+			cmd.ViperInstance.Set(mc.FTPServerKey, "legacy:21")
+			cmd.ViperInstance.Set(mc.FTPUserKey, "legacy-user")
+
+			ftpArgs = nil
+			cmd.CreateFsFunc = func(args *mc.FTPArgs) (mc.FileSystem, error) {
+				ftpArgs = args
+				return mc.LocalFileSystem{Fs: td.fs}, nil
+			}
+			cmd.RootCmd.Run = func(cmd *cobra.Command, args []string) {}
+		})
+
+		AfterEach(func() {
+			cmd.RootCmd.Run = nil
+		})
+
+		It("uses the named remote", func() {
+			cmd.RootCmd.SetArgs([]string{"--remote", creative.Name, "--password", "pw"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs.Server).To(Equal(creative.Server))
+			Expect(ftpArgs.User).To(Equal(creative.User))
+			Expect(ftpArgs.Protocol).To(Equal(creative.Protocol))
+			Expect(ftpArgs.Pw).To(Equal("pw"))
+		})
+
+		It("uses the default remote when given a password", func() {
+			cmd.ViperInstance.Set(mc.DefaultRemoteKey, survival.Name)
+			cmd.RootCmd.SetArgs([]string{"--password", "pw"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs.Server).To(Equal(survival.Server))
+			Expect(ftpArgs.BaseDir).To(Equal(survival.BaseDir))
+		})
+
+		It("uses the global server without a default remote", func() {
+			cmd.RootCmd.SetArgs([]string{"--password", "pw"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs.Server).To(Equal("legacy:21"))
+			Expect(ftpArgs.User).To(Equal("legacy-user"))
+		})
+
+		It("prefers the server flags over the default remote", func() {
+			cmd.ViperInstance.Set(mc.DefaultRemoteKey, survival.Name)
+			cmd.RootCmd.SetArgs([]string{"--password", "pw", "--ftp-server", "flag:21"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs.Server).To(Equal("flag:21"))
+		})
+
+		It("uses the local file system without a password", func() {
+			cmd.ViperInstance.Set(mc.DefaultRemoteKey, survival.Name)
+			cmd.RootCmd.SetArgs([]string{})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/mc"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Rollbacker returns installations to an earlier state
+	Rollbacker = mc.NewInstallRollbacker()
+
+	rollbackTo *string
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Return the installed mods to an earlier state",
+	Long: `
+Rollback returns the mods folder and installation records of the target to the
+way they were at an earlier revision of the install history, undoing the changes
+since. By default, the last revision is undone. The target is the local
+Minecraft install, or the server when connecting with a password or key file.
+
+Each save of the installation records is a revision, numbered in the output of
+the history command. Give --to a revision number, or a date or time to return to
+the last revision made by then. Dates are given as YYYY-MM-DD in local time, or
+in RFC 3339 format.
+
+Examples:
+ $ rollback
+ $ rollback --to 12
+ $ rollback --to 2021-06-01 --remote survival
+
+Jars are restored from the local jar cache, which keeps every downloaded jar, or
+downloaded again from their recorded URLs and checked against their recorded
+hashes. Mods which can't be restored are reported and left as they are. Mods
+installed before the history was kept are never changed. The rollback is a new
+revision of the history, so it can be undone with another rollback.`,
+	Args:        cobra.NoArgs,
+	Annotations: locksInstall,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		events, err := mc.ReadHistory(fs)
+		if err != nil {
+			return err
+		}
+
+		latest := mc.LatestRevision(events)
+		if latest == 0 {
+			return errors.New("there's no install history on the target to roll back with")
+		}
+
+		revision, err := parseRollbackTarget(*rollbackTo, events)
+		if err != nil {
+			return err
+		}
+
+		// problems from here on aren't usage errors
+		cmd.SilenceUsage = true
+
+		dl, err := CreateDownloaderFunc(fs)
+		if err != nil {
+			return err
+		}
+
+		state := mc.InstallStateAt(events, revision)
+		res := Rollbacker.Rollback(fs, dl, mc.DefaultJarCache(), UserModConfig, state)
+
+		if len(res.Restored) > 0 || len(res.Removed) > 0 {
+			if err = cfgIo.Save(UserModConfig); err != nil {
+				return err
+			}
+		}
+
+		for _, f := range res.Failed {
+			printLineToUser(fmt.Sprintf("%s  not restored: %s", f.Mod, f.Reason))
+		}
+
+		summary := fmt.Sprintf("Rolled back to revision %d: restored %d mod(s), removed %d, %d unchanged.", revision, len(res.Restored), len(res.Removed), res.Unchanged)
+		if len(res.Failed) > 0 {
+			printLineToUser(summary)
+			return fmt.Errorf("%d mod(s) couldn't be restored", len(res.Failed))
+		}
+		printToUser(summary)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(rollbackCmd)
+
+	rollbackTo = rollbackCmd.Flags().String("to", "", "The revision, date or time to return to. Defaults to the revision before the last.")
+}
+
+// parseRollbackTarget returns the revision given as a number, or the last
+// revision made by the date or time, defaulting to the one before the last
+func parseRollbackTarget(to string, events []mc.HistoryEvent) (int, error) {
+	latest := mc.LatestRevision(events)
+	if to == "" {
+		return latest - 1, nil
+	}
+
+	if revision, err := strconv.Atoi(to); err == nil {
+		if revision < 0 || revision > latest {
+			return 0, fmt.Errorf("revision %d doesn't exist; the latest is %d", revision, latest)
+		}
+		return revision, nil
+	}
+
+	until, err := parseHistoryDate(to, true)
+	if err != nil {
+		return 0, fmt.Errorf("invalid --to %q; use a revision number, YYYY-MM-DD or an RFC 3339 time", to)
+	}
+
+	revision := 0
+	for _, e := range events {
+		if e.Time.Before(until) && e.Revision > revision {
+			revision = e.Revision
+		}
+	}
+	return revision, nil
+}
@@ -0,0 +1,92 @@
+package cmd_test
+
+import (
+	"errors"
+	"mcmods/cmd"
+	"mcmods/mc"
+	. "mcmods/testdata"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Rollback Cmd", func() {
+	var td *rootTestData
+
+	v1 := mc.ModInstallation{DownloadURL: "https://x/1", SHA256: mc.HashSHA256([]byte("v1"))}
+	v2 := mc.ModInstallation{DownloadURL: "https://x/2", SHA256: mc.HashSHA256([]byte("v2"))}
+	jarPath := filepath.Join("/minecraft", mc.ModInstallPath("mod-x"))
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.InstallPathKey, "/minecraft")
+		cmd.Rollbacker = mc.NewInstallRollbacker()
+		cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+			return failingDownloader{Err: errors.New("404 Not Found")}, nil
+		}
+
+		// revision 1 installs v1 on the 1st, revision 2 updates to v2 on the 8th
+		local := mc.LocalFileSystem{Fs: td.fs}
+		Expect(mc.AppendHistory(local, mc.DiffInstallations(nil, map[string]mc.ModInstallation{"mod-x": v1}, time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local)))).To(BeNil())
+		Expect(mc.AppendHistory(local, mc.DiffInstallations(map[string]mc.ModInstallation{"mod-x": v1}, map[string]mc.ModInstallation{"mod-x": v2}, time.Date(2021, 6, 8, 12, 0, 0, 0, time.Local)))).To(BeNil())
+
+		TestingConfig.ModInstallations["mod-x"] = v2
+		Expect(afero.WriteFile(td.fs, jarPath, []byte("v2"), 0644)).To(BeNil())
+		Expect(mc.DefaultJarCache().Put([]byte("v1"))).To(BeNil())
+	})
+
+	It("undoes the last revision from the jar cache and saves", func() {
+		cmd.RootCmd.SetArgs([]string{"rollback"})
+
+		executeAndVerifyOutput(td.outBuffer, "Rolled back to revision 1: restored 1 mod(s), removed 0, 0 unchanged.", true)
+
+		Expect(*td.cfgIoSpy.Saved).To(BeTrue())
+		Expect(TestingConfig.ModInstallations["mod-x"]).To(Equal(v1))
+		b, _ := afero.ReadFile(td.fs, jarPath)
+		Expect(string(b)).To(Equal("v1"))
+	})
+
+	It("returns to the last revision made by a date", func() {
+		cmd.RootCmd.SetArgs([]string{"rollback", "--to", "2021-06-07"})
+
+		executeAndVerifyOutput(td.outBuffer, "Rolled back to revision 1: restored 1 mod(s), removed 0, 0 unchanged.", true)
+	})
+
+	It("returns to a revision", func() {
+		cmd.RootCmd.SetArgs([]string{"rollback", "--to", "0"})
+
+		executeAndVerifyOutput(td.outBuffer, "Rolled back to revision 0: restored 0 mod(s), removed 1, 0 unchanged.", true)
+
+		Expect(TestingConfig.ModInstallations).ToNot(HaveKey("mod-x"))
+		exists, _ := afero.Exists(td.fs, jarPath)
+		Expect(exists).To(BeFalse())
+	})
+
+	It("reports the mods which couldn't be restored", func() {
+		Expect(td.fs.RemoveAll(mc.DefaultJarCache().Dir)).To(BeNil())
+		cmd.RootCmd.SetArgs([]string{"rollback"})
+
+		err := cmd.RootCmd.Execute()
+
+		Expect(err).To(Equal(errors.New("1 mod(s) couldn't be restored")))
+		Expect(td.outBuffer.String()).To(HavePrefix("mod-x  not restored: the jar isn't cached and couldn't be downloaded: 404 Not Found\n"))
+		Expect(*td.cfgIoSpy.Saved).To(BeFalse())
+	})
+
+	It("returns an error for unknown revisions", func() {
+		cmd.RootCmd.SetArgs([]string{"rollback", "--to", "3"})
+
+		Expect(cmd.RootCmd.Execute()).To(Equal(errors.New("revision 3 doesn't exist; the latest is 2")))
+	})
+
+	It("returns an error when there's no history", func() {
+		Expect(td.fs.Remove(filepath.Join("/minecraft", mc.ModFolderName, mc.HistoryFileName))).To(BeNil())
+		cmd.RootCmd.SetArgs([]string{"rollback"})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+	})
+})
@@ -1,15 +1,37 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"mcmods/input"
 	"mcmods/mc"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/spf13/viper"
 )
 
+const (
+	// PasswordEnvVar is the environment variable the server password can be
+	// given in
+	PasswordEnvVar = "MCMODS_PASSWORD"
+
+	// lockAnnotation marks the commands which save the installation records,
+	// so the install is locked while they run
+	lockAnnotation = "locksInstall"
+
+	// repairAnnotation marks the commands which replace the installation
+	// records, so they run even when the records are corrupt
+	repairAnnotation = "repairsConfig"
+
+	// targetNameAnnotation marks the commands which only use the name of the
+	// target, so it isn't connected to
+	targetNameAnnotation = "usesTargetName"
+)
+
 var (
 	// CreateFsFunc creates the file system for FTP/Local. Exported for testing
 	CreateFsFunc func(ftpArgs *mc.FTPArgs) (mc.FileSystem, error) = mc.NewFs
@@ -24,6 +46,9 @@ var (
 	// ViperInstance is the common instance of viper shared through the package
 	ViperInstance = viper.GetViper()
 
+	// PasswordPrompt asks the user for the server password when it isn't given
+	PasswordPrompt = input.NewPasswordPrompt("Server password: ")
+
 	// FTPTimeoutMs is the maximum amount of time for the FTP connection to
 	// succeed before giving up and returning an error
 	FTPTimeoutMs uint = 5000
@@ -33,7 +58,47 @@ var (
 	cfgFile   string
 	ftpUser   string
 	ftpPw     string
+	pwStdin   bool
 	ftpServer string
+
+	remoteName  string
+	profileName string
+
+	// connectedTarget names the target fs is connected to, for its backups
+	connectedTarget string
+
+	// heldLock is the install lock taken by this run, if any, and
+	// stopLockRefresh stops refreshing it
+	heldLock        *mc.InstallLock
+	stopLockRefresh func()
+	breakLock       bool
+
+	// locksInstall are the annotations of commands which lock the install
+	locksInstall = map[string]string{lockAnnotation: "true"}
+
+	// repairsConfig are the annotations of commands which replace corrupt
+	// installation records
+	repairsConfig = map[string]string{lockAnnotation: "true", repairAnnotation: "true"}
+
+	// namesTarget are the annotations of commands which only use the target's
+	// name
+	namesTarget = map[string]string{targetNameAnnotation: "true"}
+
+	serverProtocol string
+	sftpKeyFile    string
+	sftpKnownHosts string
+
+	ftpTLS            string
+	ftpTLSFingerprint string
+	ftpMode           string
+	ftpTLSSkipVerify  bool
+
+	httpProxy          string
+	httpConnectTimeout time.Duration
+	httpReadTimeout    time.Duration
+	httpUserAgent      string
+	httpCABundle       string
+	httpNoBypass       bool
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -45,35 +110,112 @@ This tool installs and updates Minecraft mods for connecting to the server
 called CDP YAMS. The server is private, and only available by invite. To
 inquire about an invite, please call 1-888-PISS-OFF and ask for Dianne.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		var ftpArgs *mc.FTPArgs
-		var err error
+		// the profile commands still work when the active profile is unknown,
+		// so it can be changed
+		profile, err := selectProfile()
+		if cmd.Parent() != profileCmd {
+			cobra.CheckErr(err)
+		}
+		mc.UseProfile(profile)
 
-		if ftpPw != "" {
-			ftpArgs = &mc.FTPArgs{
-				Server:    ViperInstance.GetString(mc.FTPServerKey),
-				User:      ViperInstance.GetString(mc.FTPUserKey),
-				Pw:        ftpPw,
-				TimeoutMs: FTPTimeoutMs,
-			}
+		if cmd.Annotations[targetNameAnnotation] != "" {
+			ftpArgs, err := getServerArgs(credentialsGiven())
+			cobra.CheckErr(err)
+			connectedTarget = installTargetName(ftpArgs)
+			fs = nil
+			return
+		}
+
+		// zip exports don't touch the target: the client mods are read from
+		// the local install, which isn't locked
+		var ftpArgs *mc.FTPArgs
+		if *target == "" {
+			ftpArgs, err = getFTPArgs(cmd)
+			cobra.CheckErr(err)
 		}
 
 		fs, err = CreateFsFunc(ftpArgs)
 		cobra.CheckErr(err)
+		connectedTarget = installTargetName(ftpArgs)
 
 		cfgIo = ConfigIoFunc(fs)
 
+		if takesLock(cmd) {
+			staleAfter := mc.GetLockStaleAfter()
+			lock, err := mc.AcquireLock(fs, time.Now(), staleAfter, breakLock)
+			cobra.CheckErr(err)
+			heldLock = &lock
+			stopLockRefresh = mc.KeepLockFresh(fs, lock, staleAfter)
+		}
+
 		UserModConfig, err = cfgIo.LoadOrNew()
-		cobra.CheckErr(err)
+		if err != nil && !tolerateCorruptConfig(cmd, err) {
+			releaseLock(cmd.ErrOrStderr())
+			cobra.CheckErr(err)
+		}
+
+		// refused before the command changes any files, since the records
+		// couldn't be saved after
+		if takesLock(cmd) && UserModConfig.SchemaVersion > mc.CurrentSchemaVersion {
+			releaseLock(cmd.ErrOrStderr())
+			cobra.CheckErr(mc.ErrNewerSchemaVersion)
+		}
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
-		fs.Close()
+		releaseLock(cmd.ErrOrStderr())
+		if fs != nil {
+			fs.Close()
+		}
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	cobra.CheckErr(RootCmd.Execute())
+	err := RootCmd.Execute()
+
+	// the post run is skipped when a command fails
+	releaseLock(RootCmd.ErrOrStderr())
+	cobra.CheckErr(err)
+}
+
+// tolerateCorruptConfig replaces corrupt installation records with empty ones
+// for commands which only read them, after warning the user, and for commands
+// which repair them. Returns false if the command can't run with the error.
+func tolerateCorruptConfig(cmd *cobra.Command, err error) bool {
+	corrupt := &mc.CorruptConfigError{}
+	if !errors.As(err, &corrupt) {
+		return false
+	}
+
+	if cmd.Annotations[repairAnnotation] == "" {
+		if takesLock(cmd) {
+			return false
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s. Mods are shown as not installed until then.\n", err)
+	}
+
+	cfg := mc.NewUserModConfig()
+	UserModConfig = &cfg
+	return true
+}
+
+// takesLock returns true if the command saves the installation records of the
+// target, so it locks the install. Zip exports only read the local ones.
+func takesLock(cmd *cobra.Command) bool {
+	return cmd.Annotations[lockAnnotation] != "" && *target == ""
+}
+
+// releaseLock releases the install lock if this run holds it
+func releaseLock(errOut io.Writer) {
+	if heldLock == nil {
+		return
+	}
+	stopLockRefresh()
+	if err := mc.ReleaseLock(fs, *heldLock); err != nil {
+		fmt.Fprintf(errOut, "The install lock couldn't be released: %s\n", err)
+	}
+	heldLock = nil
 }
 
 func init() {
@@ -85,7 +227,26 @@ func init() {
 
 	RootCmd.PersistentFlags().StringVar(&ftpServer, "ftp-server", "", "The FTP server for managing server-side mods. Stored, only needed on the first command.")
 	RootCmd.PersistentFlags().StringVarP(&ftpUser, "user", "u", "", "The FTP username. Stored, only needed on the first command.")
-	RootCmd.PersistentFlags().StringVarP(&ftpPw, "password", "p", "", "The FTP password. Not stored, needed every time.")
+	RootCmd.PersistentFlags().StringVarP(&ftpPw, "password", "p", "", fmt.Sprintf("The server password. Visible in shell history; prefer the prompt, --password-stdin or the %s environment variable. Not stored.", PasswordEnvVar))
+	RootCmd.PersistentFlags().BoolVar(&pwStdin, "password-stdin", false, "Read the server password from the first line of stdin, for automation.")
+	RootCmd.PersistentFlags().BoolVar(&breakLock, "break-lock", false, "Take over the install lock even if another run holds it. Only for locks left behind by interrupted runs.")
+	RootCmd.PersistentFlags().StringVar(&remoteName, "remote", "", "The named remote to connect to, instead of --ftp-server and --user. See the remote command.")
+	RootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "The Minecraft instance profile to use, instead of the active one. See the profile command.")
+	RootCmd.PersistentFlags().StringVar(&serverProtocol, "protocol", "", fmt.Sprintf("The protocol for connecting to the server: %s, %s or %s. Defaults to %s, or %s for %s servers. Stored.", mc.FTPProtocol, mc.SFTPProtocol, mc.PterodactylProtocol, mc.FTPProtocol, mc.SFTPProtocol, mc.SFTPScheme))
+	RootCmd.PersistentFlags().StringVar(&sftpKeyFile, "key-file", "", "Private key for logging in over SFTP. The password decrypts the key if it's encrypted. Not stored, needed every time.")
+	RootCmd.PersistentFlags().StringVar(&sftpKnownHosts, "known-hosts", "", "The known_hosts file used to verify SFTP servers (default is $HOME/.ssh/known_hosts). Stored.")
+
+	RootCmd.PersistentFlags().StringVar(&ftpTLS, "ftp-tls", "", fmt.Sprintf("TLS for FTP connections: %s (AUTH TLS), %s, or %s. Defaults to %s, or %s for %s servers. Stored.", mc.FTPTLSExplicit, mc.FTPTLSImplicit, mc.FTPTLSNone, mc.FTPTLSNone, mc.FTPTLSImplicit, mc.FTPSScheme))
+	RootCmd.PersistentFlags().StringVar(&ftpTLSFingerprint, "ftp-tls-fingerprint", "", "Trust only the FTP server certificate with this SHA-256 fingerprint, e.g. for self-signed certificates. Stored.")
+	RootCmd.PersistentFlags().StringVar(&ftpMode, "ftp-mode", "", fmt.Sprintf("The FTP data connection mode: %s (default, falls back to PASV), %s or %s. Stored.", mc.FTPModeEPSV, mc.FTPModePASV, mc.FTPModeActive))
+	RootCmd.PersistentFlags().BoolVar(&ftpTLSSkipVerify, "ftp-tls-skip-verify", false, "Don't verify the FTP server's certificate. Insecure; prefer --ftp-tls-fingerprint. Stored; use --ftp-tls-skip-verify=false to undo.")
+
+	RootCmd.PersistentFlags().StringVar(&httpProxy, "proxy", "", fmt.Sprintf("Proxy URL for downloads, or '%s' to use the HTTP(S)_PROXY environment variables. Overrides the config.", mc.EnvironmentProxy))
+	RootCmd.PersistentFlags().DurationVar(&httpConnectTimeout, "connect-timeout", 0, "Timeout for connecting to download servers, e.g. 30s. Overrides the config.")
+	RootCmd.PersistentFlags().DurationVar(&httpReadTimeout, "read-timeout", 0, "Timeout for waiting on data from download servers, e.g. 1m. Overrides the config.")
+	RootCmd.PersistentFlags().StringVar(&httpUserAgent, "user-agent", "", "User agent sent to download servers. Overrides the config.")
+	RootCmd.PersistentFlags().StringVar(&httpCABundle, "ca-bundle", "", "Path to a PEM file of extra CA certificates to trust. Overrides the config.")
+	RootCmd.PersistentFlags().BoolVar(&httpNoBypass, "no-cloudflare-bypass", false, "Don't disguise downloads as browser requests to get past Cloudflare.")
 }
 
 // initViper reads in a config file through Viper
@@ -124,11 +285,204 @@ func initViper() {
 		ViperInstance.Set(mc.FTPServerKey, ftpServer)
 	}
 
+	if serverProtocol != "" {
+		updatedCfg = true
+		ViperInstance.Set(mc.ServerProtocolKey, serverProtocol)
+	}
+	if sftpKnownHosts != "" {
+		updatedCfg = true
+		ViperInstance.Set(mc.SFTPKnownHostsKey, sftpKnownHosts)
+	}
+	if ftpTLS != "" {
+		updatedCfg = true
+		ViperInstance.Set(mc.FTPTLSKey, ftpTLS)
+	}
+	if ftpTLSFingerprint != "" {
+		updatedCfg = true
+		ViperInstance.Set(mc.FTPTLSFingerprintKey, ftpTLSFingerprint)
+	}
+	if ftpMode != "" {
+		updatedCfg = true
+		ViperInstance.Set(mc.FTPModeKey, ftpMode)
+	}
+	if RootCmd.PersistentFlags().Changed("ftp-tls-skip-verify") {
+		updatedCfg = true
+		ViperInstance.Set(mc.FTPTLSSkipVerifyKey, ftpTLSSkipVerify)
+	}
+
 	if updatedCfg {
 		ViperInstance.WriteConfig()
 	}
 }
 
+// getFTPArgs returns the args for connecting to the server, or nil to use the
+// local file system. The server is selected like in getServerArgs, with any
+// password given also selecting it. A remote's password is read from the
+// credential store if it's not given, otherwise the user is asked for it.
+func getFTPArgs(cmd *cobra.Command) (*mc.FTPArgs, error) {
+	pw, err := getGivenPassword(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	args, err := getServerArgs(pw != "" || sftpKeyFile != "")
+	if args == nil || err != nil {
+		return nil, err
+	}
+
+	if pw == "" && sftpKeyFile == "" && args.Remote != "" {
+		if pw, err = storedRemotePassword(cmd, args.Remote); err != nil {
+			return nil, err
+		}
+	}
+	if pw == "" && sftpKeyFile == "" {
+		if pw, err = PasswordPrompt.GetInput(cmd.ErrOrStderr(), cmd.InOrStdin()); err != nil {
+			return nil, err
+		}
+	}
+
+	args.Pw = pw
+	args.KeyFile = sftpKeyFile
+	setConnectionArgs(args)
+
+	return args, nil
+}
+
+// getServerArgs returns the args of the selected server, without its
+// password, or nil for the local install. The server is selected with
+// --remote, --ftp-server or --user, when credentials are given, or when install
+// --full-server is run with a stored server or default remote, using the named
+// remote, the server flags, or the default remote, in that order.
+func getServerArgs(credentials bool) (*mc.FTPArgs, error) {
+	serverFlags := ftpServer != "" || ftpUser != "" || serverProtocol != ""
+	if remoteName != "" && serverFlags {
+		return nil, errors.New("--remote can't be combined with --ftp-server, --user or --protocol; update the remote with remote add instead")
+	}
+
+	// the server and user are only needed on the first command, so full
+	// server installs go to the stored server
+	storedServer := ViperInstance.GetString(mc.FTPServerKey) != "" || ViperInstance.GetString(mc.DefaultRemoteKey) != ""
+	selected := remoteName != "" || ftpServer != "" || ftpUser != "" || (*fullServer && storedServer)
+	if !selected && !credentials {
+		return nil, nil
+	}
+
+	name := remoteName
+	if name == "" && !serverFlags {
+		name = ViperInstance.GetString(mc.DefaultRemoteKey)
+	}
+
+	if name != "" {
+		remote, err := mc.GetRemote(name)
+		if err != nil {
+			return nil, err
+		}
+		return remote.FTPArgs(), nil
+	}
+	return &mc.FTPArgs{
+		Server:   ViperInstance.GetString(mc.FTPServerKey),
+		User:     ViperInstance.GetString(mc.FTPUserKey),
+		Protocol: ViperInstance.GetString(mc.ServerProtocolKey),
+	}, nil
+}
+
+// credentialsGiven returns true if a password or key file is given, without
+// reading it
+func credentialsGiven() bool {
+	return pwStdin || ftpPw != "" || os.Getenv(PasswordEnvVar) != "" || sftpKeyFile != ""
+}
+
+// setConnectionArgs sets the stored connection settings which apply to every
+// server
+func setConnectionArgs(args *mc.FTPArgs) {
+	args.TimeoutMs = FTPTimeoutMs
+	args.KnownHostsFile = ViperInstance.GetString(mc.SFTPKnownHostsKey)
+	args.TLSMode = ViperInstance.GetString(mc.FTPTLSKey)
+	args.TLSFingerprint = ViperInstance.GetString(mc.FTPTLSFingerprintKey)
+	args.TLSSkipVerify = ViperInstance.GetBool(mc.FTPTLSSkipVerifyKey)
+	args.Mode = ViperInstance.GetString(mc.FTPModeKey)
+	args.KeepAlive = mc.DefaultFTPKeepAlive
+	if ViperInstance.IsSet(mc.FTPKeepAliveKey) {
+		args.KeepAlive = ViperInstance.GetDuration(mc.FTPKeepAliveKey)
+	}
+}
+
+// getGivenPassword returns the password from the flag, stdin, or the
+// environment, in that order. Returns an empty string if none was given.
+func getGivenPassword(cmd *cobra.Command) (string, error) {
+	if pwStdin {
+		if ftpPw != "" {
+			return "", errors.New("--password and --password-stdin can't be combined")
+		}
+
+		// only the first line is read, so the rest of stdin is left for prompts
+		pw, err := input.ReadLine(cmd.InOrStdin())
+		if err != nil {
+			return "", err
+		}
+		if pw != "" {
+			return pw, nil
+		}
+		return "", errors.New("--password-stdin was given, but no password was read from stdin")
+	}
+
+	if ftpPw != "" {
+		return ftpPw, nil
+	}
+	return os.Getenv(PasswordEnvVar), nil
+}
+
+// getHTTPOptions reads the HTTP options from viper, overridden by any flags
+func getHTTPOptions() (mc.HTTPOptions, error) {
+	opts, err := mc.GetHTTPOptions()
+	if err != nil {
+		return opts, err
+	}
+
+	if httpProxy != "" {
+		opts.Proxy = httpProxy
+	}
+	if httpConnectTimeout != 0 {
+		opts.ConnectTimeout = httpConnectTimeout
+	}
+	if httpReadTimeout != 0 {
+		opts.ReadTimeout = httpReadTimeout
+	}
+	if httpUserAgent != "" {
+		opts.UserAgent = httpUserAgent
+	}
+	if httpCABundle != "" {
+		opts.CABundle = httpCABundle
+	}
+	if httpNoBypass {
+		opts.CloudflareBypass = false
+	}
+
+	// tokens in the credential store are only used for hosts without credentials
+	// in the config. The store is only opened for downloads from its hosts,
+	// since opening it can ask for the passphrase.
+	path, err := mc.CredentialsPath()
+	if err != nil {
+		return opts, err
+	}
+	exists, err := mc.CredentialStoreExists(path)
+	if err != nil || !exists {
+		return opts, err
+	}
+	if opts.StoredCredentialHosts, err = mc.CredentialStoreHosts(path); err != nil {
+		return opts, err
+	}
+	opts.StoredCredentials = func() (map[string]mc.HostCredential, error) {
+		store, err := openCredentials(RootCmd, false)
+		if err != nil || store == nil {
+			return nil, err
+		}
+		return store.HostCredentials(), nil
+	}
+
+	return opts, nil
+}
+
 // Print the complete output of the command to a user. Does not append a new
 // line
 func printToUser(txt string) {
@@ -148,17 +502,95 @@ func ResetVars() {
 	cfgFile = ""
 	ftpUser = ""
 	ftpPw = ""
+	pwStdin = false
 	ftpServer = ""
+	remoteName = ""
+	profileName = ""
+	mc.UseProfile(nil)
+	heldLock = nil
+	stopLockRefresh = nil
+	breakLock = false
+	serverProtocol = ""
+	sftpKeyFile = ""
+	sftpKnownHosts = ""
+	ftpTLS = ""
+	ftpTLSFingerprint = ""
+	ftpMode = ""
+	ftpTLSSkipVerify = false
+	if f := RootCmd.PersistentFlags().Lookup("ftp-tls-skip-verify"); f != nil {
+		f.Changed = false
+	}
+	httpProxy = ""
+	httpConnectTimeout = 0
+	httpReadTimeout = 0
+	httpUserAgent = ""
+	httpCABundle = ""
+	httpNoBypass = false
+
+	// config cmd
+	*configRepairScan = false
+
+	// credentials cmd
+	credStore = nil
 
 	// add cmd
 	*serverMod = false
 
+	// backup cmd
+	*backupKeep = 0
+	if f := backupPruneCmd.Flags().Lookup("keep"); f != nil {
+		f.Changed = false
+	}
+
+	// bump cmd
+	*bumpAll = false
+	*bumpDryRun = false
+	*bumpServer = false
+	*gameVersion = ""
+	*modLoader = ""
+
 	// install cmd
 	*force = false
 	*fullServer = false
 	*clientOnly = false
 	*xMods = (*xMods)[:0]
 	*xGroups = (*xGroups)[:0]
+	*target = ""
+	for _, name := range []string{"client-only", "full-server", "x-group", "x-mod"} {
+		if f := installCmd.Flags().Lookup(name); f != nil {
+			f.Changed = false
+		}
+	}
+
+	// history cmd
+	*historyMod = ""
+	*historySince = ""
+	*historyUntil = ""
+	*historyTarget = ""
+
+	// ls cmd
+	*lsLong = false
+
+	// profile cmd
+	*profilePath = ""
+	*profileGameVersion = ""
+	*profileModLoader = ""
+	*profileGroups = (*profileGroups)[:0]
+	*profileXMods = (*profileXMods)[:0]
+	*profileUseNone = false
+
+	// selection cmd
+	*selectionClientOnly = false
+	*selectionFullServer = false
+	*selectionXGroups = (*selectionXGroups)[:0]
+	*selectionXMods = (*selectionXMods)[:0]
+
+	// rollback cmd
+	*rollbackTo = ""
+
+	// mirror cmd
+	*mirrorFrom = ""
+	*mirrorTo = ""
 
 	// list mods cmd
 	*listInstalled = false
@@ -166,6 +598,18 @@ func ResetVars() {
 	*listClient = false
 	*listServer = false
 	*listGroup = ""
+	*listInstalledSince = ""
+	*listInstalledBefore = ""
+	*listSortInstalled = false
+
+	// remote add cmd
+	*remoteServer = ""
+	*remoteUser = ""
+	*remoteProtocol = ""
+	*remoteBaseDir = ""
+
+	// verify cmd
+	*repair = false
 
 	// mcpath cmd
 	*path = ""
@@ -2,9 +2,13 @@ package cmd_test
 
 import (
 	"bytes"
+	"errors"
 	"mcmods/cmd"
 	"mcmods/mc"
 	. "mcmods/testdata"
+	"os"
+	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -56,8 +60,273 @@ var _ = Describe("Describe Cmd", func() {
 			Expect(err).To(BeNil())
 		})
 	})
+
+	Context("FTPS", func() {
+		BeforeEach(func() {
+			cmd.RootCmd.Run = func(cmd *cobra.Command, args []string) {}
+		})
+
+		AfterEach(func() {
+			cmd.RootCmd.Run = nil
+		})
+
+		It("stores the TLS settings and passes them to the CreateFsFunc", func() {
+			fp := "AB:CD"
+			cmd.RootCmd.SetArgs([]string{"--password", "pw", "--ftp-tls", mc.FTPTLSExplicit, "--ftp-tls-fingerprint", fp, "--ftp-tls-skip-verify"})
+			cmd.CreateFsFunc = func(ftpArgs *mc.FTPArgs) (mc.FileSystem, error) {
+				Expect(ftpArgs.TLSMode).To(Equal(mc.FTPTLSExplicit))
+				Expect(ftpArgs.TLSFingerprint).To(Equal(fp))
+				Expect(ftpArgs.TLSSkipVerify).To(BeTrue())
+				return mc.LocalFileSystem{Fs: td.fs}, nil
+			}
+
+			err := cmd.RootCmd.Execute()
+
+			Expect(err).To(BeNil())
+			Expect(cmd.ViperInstance.GetString(mc.FTPTLSKey)).To(Equal(mc.FTPTLSExplicit))
+			Expect(cmd.ViperInstance.GetString(mc.FTPTLSFingerprintKey)).To(Equal(fp))
+			Expect(cmd.ViperInstance.GetBool(mc.FTPTLSSkipVerifyKey)).To(BeTrue())
+		})
+
+		It("stores the FTP mode, and passes it with the keepalive interval", func() {
+			cmd.ViperInstance.Set(mc.FTPKeepAliveKey, "10s")
+			cmd.RootCmd.SetArgs([]string{"--password", "pw", "--ftp-mode", mc.FTPModePASV})
+			cmd.CreateFsFunc = func(ftpArgs *mc.FTPArgs) (mc.FileSystem, error) {
+				Expect(ftpArgs.Mode).To(Equal(mc.FTPModePASV))
+				Expect(ftpArgs.KeepAlive).To(Equal(10 * time.Second))
+				return mc.LocalFileSystem{Fs: td.fs}, nil
+			}
+
+			err := cmd.RootCmd.Execute()
+
+			Expect(err).To(BeNil())
+			Expect(cmd.ViperInstance.GetString(mc.FTPModeKey)).To(Equal(mc.FTPModePASV))
+			cmd.ViperInstance.Set(mc.FTPModeKey, "")
+			cmd.ViperInstance.Set(mc.FTPKeepAliveKey, mc.DefaultFTPKeepAlive)
+		})
+
+		It("stores turning off skip verify", func() {
+			cmd.ViperInstance.Set(mc.FTPTLSSkipVerifyKey, true)
+			cmd.RootCmd.SetArgs([]string{"--ftp-tls-skip-verify=false"})
+
+			err := cmd.RootCmd.Execute()
+
+			Expect(err).To(BeNil())
+			Expect(cmd.ViperInstance.GetBool(mc.FTPTLSSkipVerifyKey)).To(BeFalse())
+		})
+
+		It("leaves skip verify alone when the flag isn't given", func() {
+			cmd.ViperInstance.Set(mc.FTPTLSSkipVerifyKey, true)
+			cmd.RootCmd.SetArgs([]string{})
+
+			err := cmd.RootCmd.Execute()
+
+			Expect(err).To(BeNil())
+			Expect(cmd.ViperInstance.GetBool(mc.FTPTLSSkipVerifyKey)).To(BeTrue())
+			cmd.ViperInstance.Set(mc.FTPTLSSkipVerifyKey, false)
+		})
+	})
+
+	Context("Passwords", func() {
+		var ftpArgs *mc.FTPArgs
+
+		BeforeEach(func() {
+			ftpArgs = nil
+			cmd.CreateFsFunc = func(args *mc.FTPArgs) (mc.FileSystem, error) {
+				ftpArgs = args
+				return mc.LocalFileSystem{Fs: td.fs}, nil
+			}
+			cmd.RootCmd.Run = func(cmd *cobra.Command, args []string) {}
+		})
+
+		AfterEach(func() {
+			cmd.RootCmd.Run = nil
+		})
+
+		It("prompts for the password when connecting without one", func() {
+			cmd.PasswordPrompt = noOpPrompt{ReturnStr: "prompted"}
+			cmd.RootCmd.SetArgs([]string{"--ftp-server", "server:21", "--user", "user"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs.Pw).To(Equal("prompted"))
+		})
+
+		It("reads the password from the first line of stdin, leaving the rest", func() {
+			stdin := strings.NewReader("from-stdin\nnext\n")
+			cmd.RootCmd.SetIn(stdin)
+			cmd.RootCmd.SetArgs([]string{"--ftp-server", "server:21", "--password-stdin"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs.Pw).To(Equal("from-stdin"))
+			Expect(stdin.Len()).To(Equal(len("next\n")))
+		})
+
+		It("reads the password from the environment", func() {
+			os.Setenv(cmd.PasswordEnvVar, "from-env")
+			cmd.RootCmd.SetArgs([]string{})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs).ToNot(BeNil())
+			Expect(ftpArgs.Pw).To(Equal("from-env"))
+		})
+
+		It("prefers the flag over the environment", func() {
+			os.Setenv(cmd.PasswordEnvVar, "from-env")
+			cmd.RootCmd.SetArgs([]string{"--password", "from-flag"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs.Pw).To(Equal("from-flag"))
+		})
+
+		It("prompts for the password of the stored server for full server installs", func() {
+			cmd.ViperInstance.Set(mc.FTPServerKey, "server:21")
+			cmd.ViperInstance.Set(mc.FTPUserKey, "user")
+			cmd.PasswordPrompt = noOpPrompt{ReturnStr: "prompted"}
+			cmd.Filter = emptyFilter{Return: []*mc.Mod{}}
+			cmd.Installer = emptyInstaller{}
+			cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+				return fakeDownloader{}, nil
+			}
+			cmd.RootCmd.SetArgs([]string{"install", "--full-server"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs).ToNot(BeNil())
+			Expect(ftpArgs.Server).To(Equal("server:21"))
+			Expect(ftpArgs.Pw).To(Equal("prompted"))
+		})
+
+		It("installs full server locally without a stored server", func() {
+			cmd.Filter = emptyFilter{Return: []*mc.Mod{}}
+			cmd.Installer = emptyInstaller{}
+			cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+				return fakeDownloader{}, nil
+			}
+			cmd.RootCmd.SetArgs([]string{"install", "--full-server"})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs).To(BeNil())
+		})
+
+		It("uses the local file system without a server or password", func() {
+			cmd.RootCmd.SetArgs([]string{})
+
+			Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+			Expect(ftpArgs).To(BeNil())
+		})
+	})
+
+	Context("SFTP", func() {
+		BeforeEach(func() {
+			cmd.RootCmd.Run = func(cmd *cobra.Command, args []string) {}
+		})
+
+		AfterEach(func() {
+			cmd.RootCmd.Run = nil
+		})
+
+		It("stores the protocol and known_hosts file", func() {
+			cmd.RootCmd.SetArgs([]string{"--protocol", mc.SFTPProtocol, "--known-hosts", "/known_hosts"})
+
+			err := cmd.RootCmd.Execute()
+
+			Expect(err).To(BeNil())
+			Expect(cmd.ViperInstance.GetString(mc.ServerProtocolKey)).To(Equal(mc.SFTPProtocol))
+			Expect(cmd.ViperInstance.GetString(mc.SFTPKnownHostsKey)).To(Equal("/known_hosts"))
+		})
+
+		It("connects to the server with only a key file", func() {
+			cmd.RootCmd.SetArgs([]string{"--protocol", mc.SFTPProtocol, "--known-hosts", "/known_hosts", "--key-file", "/id_ed25519"})
+			cmd.CreateFsFunc = func(ftpArgs *mc.FTPArgs) (mc.FileSystem, error) {
+				Expect(ftpArgs).ToNot(BeNil())
+				Expect(ftpArgs.Protocol).To(Equal(mc.SFTPProtocol))
+				Expect(ftpArgs.KeyFile).To(Equal("/id_ed25519"))
+				Expect(ftpArgs.KnownHostsFile).To(Equal("/known_hosts"))
+				return mc.LocalFileSystem{Fs: td.fs}, nil
+			}
+
+			err := cmd.RootCmd.Execute()
+
+			Expect(err).To(BeNil())
+		})
+	})
+
+	Context("install lock", func() {
+		var local mc.FileSystem
+		var heldDuringInstall *mc.InstallLock
+
+		BeforeEach(func() {
+			local = mc.LocalFileSystem{Fs: td.fs}
+			heldDuringInstall = nil
+
+			cmd.Filter = emptyFilter{Return: []*mc.Mod{}}
+			cmd.Installer = lockSpyInstaller{Fs: local, Held: &heldDuringInstall}
+			cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+				return fakeDownloader{}, nil
+			}
+		})
+
+		It("holds the lock while saving commands run", func() {
+			cmd.RootCmd.SetArgs([]string{"install"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			Expect(heldDuringInstall).ToNot(BeNil())
+			held, err := mc.ReadLock(local)
+			Expect(err).To(BeNil())
+			Expect(held).To(BeNil(), "the lock should be released")
+		})
+
+		It("takes over stale locks", func() {
+			_, err := mc.AcquireLock(local, time.Now().Add(-2*mc.DefaultLockStaleAfter), mc.DefaultLockStaleAfter, false)
+			Expect(err).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"install"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+		})
+
+		It("takes over held locks with --break-lock", func() {
+			_, err := mc.AcquireLock(local, time.Now(), mc.DefaultLockStaleAfter, false)
+			Expect(err).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"install", "--break-lock"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			held, _ := mc.ReadLock(local)
+			Expect(held).To(BeNil())
+		})
+
+		It("doesn't lock commands which only read", func() {
+			lock, err := mc.AcquireLock(local, time.Now(), mc.DefaultLockStaleAfter, false)
+			Expect(err).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"history"})
+
+			executeAndVerifyOutput(td.outBuffer, "No history.", true)
+
+			held, _ := mc.ReadLock(local)
+			Expect(held.Token).To(Equal(lock.Token))
+		})
+	})
 })
 
+// records the install lock held while installing
+type lockSpyInstaller struct {
+	Fs   mc.FileSystem
+	Held **mc.InstallLock
+}
+
+func (i lockSpyInstaller) InstallMods(downloader mc.ModDownloader, mods []*mc.Mod, cfg *mc.UserModConfig) error {
+	lock, err := mc.ReadLock(i.Fs)
+	*i.Held = lock
+	return err
+}
+
 func rootCmdTestSetup() *rootTestData {
 	cmd.ResetVars()
 
@@ -74,8 +343,17 @@ func rootCmdTestSetup() *rootTestData {
 	}
 
 	cmd.ViperInstance.SetFs(rootData.fs)
+	mc.CredentialsFs = rootData.fs
+	mc.JarCacheFs = rootData.fs
+	mc.BackupFs = rootData.fs
+	cmd.Now = time.Now
 
 	mc.ServerGroups = TestingServerGroups
+	cmd.ViperInstance.Set(mc.ActiveProfileKey, "")
+	cmd.ViperInstance.Set(mc.SelectionsKey, map[string]interface{}{})
+	cmd.ViperInstance.Set(mc.FTPServerKey, "")
+	cmd.ViperInstance.Set(mc.FTPUserKey, "")
+	cmd.ViperInstance.Set(mc.DefaultRemoteKey, "")
 
 	cmd.CreateFsFunc = func(ftpArgs *mc.FTPArgs) (mc.FileSystem, error) {
 		return mc.LocalFileSystem{Fs: rootData.fs}, nil
@@ -85,7 +363,15 @@ func rootCmdTestSetup() *rootTestData {
 		return rootData.cfgIoSpy
 	}
 
+	cmd.PasswordPrompt = noOpPrompt{ReturnErr: errors.New("unexpected password prompt")}
+
 	cmd.RootCmd.SetOut(rootData.outBuffer)
+	cmd.RootCmd.SetIn(nil)
+	os.Unsetenv(cmd.PasswordEnvVar)
+	os.Unsetenv(cmd.CredentialsKeyEnvVar)
+	os.Unsetenv(cmd.CredentialsPassphraseEnvVar)
+	cmd.PassphrasePrompt = noOpPrompt{ReturnErr: errors.New("unexpected passphrase prompt")}
+	cmd.PassphraseConfirmPrompt = cmd.PassphrasePrompt
 
 	return rootData
 }
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mcmods/mc"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// allocated here since root's init resets them before this file's init runs
+	selectionClientOnly = new(bool)
+	selectionFullServer = new(bool)
+	selectionXGroups    = new([]string)
+	selectionXMods      = new([]string)
+)
+
+// selectionCmd represents the selection command
+var selectionCmd = &cobra.Command{
+	Use:   "selection [command]",
+	Short: "Manage the mods chosen for installs on the target",
+	Long: fmt.Sprintf(`
+Install saves its mode (--client-only or --full-server) and exclude flags for
+the target, and reuses them when none of them are given, so updates keep
+installing the same mods. The target is the local Minecraft install, the
+profile given with --profile, or the server when connecting to one. Selections
+are stored in the tool's config, under %s, by the target's name.
+
+Examples:
+ $ selection show
+ $ selection set -x performance --x-mod some-mod
+ $ selection set --full-server --remote survival
+ $ selection clear`, mc.SelectionsKey),
+}
+
+// selectionShowCmd represents the selection show command
+var selectionShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the selection saved for the target",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := mc.BackupTargetName(connectedTarget)
+		s, ok, err := mc.GetSelection(connectedTarget)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			printToUser(fmt.Sprintf("No saved selection for %s.", name))
+			return nil
+		}
+		printToUser(fmt.Sprintf("Selection for %s: %s.", name, describeSelection(s)))
+		return nil
+	},
+}
+
+// selectionSetCmd represents the selection set command
+var selectionSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Save the selection for the target without installing",
+	Long: `
+Replaces the selection saved for the target with the one given, as if install
+was run with the same flags. Without flags, installs use the client and server
+mods, without the server-only ones.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if *selectionClientOnly && *selectionFullServer {
+			return errors.New("Use either --client-only or --full-server")
+		}
+
+		s := selectionFromFlags(*selectionClientOnly, *selectionFullServer, *selectionXGroups, *selectionXMods)
+		if err := mc.SetSelection(connectedTarget, s); err != nil {
+			return err
+		}
+		if err := ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		printToUser(fmt.Sprintf("Selection saved for %s.", mc.BackupTargetName(connectedTarget)))
+		return nil
+	},
+}
+
+// selectionClearCmd represents the selection clear command
+var selectionClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Forget the selection saved for the target",
+	Long: `
+Removes the selection saved for the target, so installs without flags use the
+client and server mods, without the server-only ones.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := mc.BackupTargetName(connectedTarget)
+		cleared, err := mc.ClearSelection(connectedTarget)
+		if err != nil {
+			return err
+		}
+
+		if !cleared {
+			printToUser(fmt.Sprintf("No saved selection for %s.", name))
+			return nil
+		}
+		if err = ViperInstance.WriteConfig(); err != nil {
+			return err
+		}
+
+		printToUser(fmt.Sprintf("Selection cleared for %s.", name))
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(selectionCmd)
+
+	selectionCmd.AddCommand(selectionShowCmd)
+	selectionCmd.AddCommand(selectionSetCmd)
+	selectionCmd.AddCommand(selectionClearCmd)
+
+	flags := selectionSetCmd.Flags()
+
+	flags.BoolVarP(selectionClientOnly, "client-only", "c", false, "Only install your client mods.")
+	flags.BoolVar(selectionFullServer, "full-server", false, "Install all the server mods. Ignores exclude flags.")
+	flags.StringSliceVarP(selectionXGroups, "x-group", "x", []string{}, "Server Mod Groups to exclude. Separate names with commas, no spaces.")
+	flags.StringSliceVar(selectionXMods, "x-mod", []string{}, "Mods to exclude. Separate names with commas, no spaces.")
+}
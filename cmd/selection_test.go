@@ -0,0 +1,158 @@
+package cmd_test
+
+import (
+	"mcmods/cmd"
+	"mcmods/mc"
+	. "mcmods/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Selection Cmd", func() {
+	var td *rootTestData
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+	})
+
+	Context("install", func() {
+		var visited bool
+
+		BeforeEach(func() {
+			visited = false
+			cmd.Installer = emptyInstaller{}
+			cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+				return fakeDownloader{}, nil
+			}
+		})
+
+		verifyFilter := func(xGroups []string, xMods []string) {
+			cmd.Filter = filterVerifier{
+				XGroups: xGroups,
+				XMods:   xMods,
+				Cfg:     TestingConfig,
+				Visited: &visited,
+				emptyFilter: emptyFilter{
+					Return: []*mc.Mod{},
+				},
+			}
+		}
+
+		It("saves the selection given with flags", func() {
+			verifyFilter([]string{"performance", cmd.ServerOnlyGroupKey}, []string{"mod1"})
+			cmd.RootCmd.SetArgs([]string{"install", "-x", "performance", "--x-mod", "mod1"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			s, ok, _ := mc.GetSelection(cmd.InstallLocal)
+			Expect(ok).To(BeTrue())
+			Expect(s.Mode).To(BeEmpty())
+			Expect(s.ExcludedGroups).To(Equal([]string{"performance"}))
+			Expect(s.ExcludedMods).To(Equal([]string{"mod1"}))
+		})
+
+		It("reuses the saved selection without flags", func() {
+			Expect(mc.SetSelection(cmd.InstallLocal, mc.Selection{ExcludedGroups: []string{"performance"}, ExcludedMods: []string{"mod1"}})).To(BeNil())
+			verifyFilter([]string{"performance", cmd.ServerOnlyGroupKey}, []string{"mod1"})
+			cmd.RootCmd.SetArgs([]string{"install"})
+
+			executeAndVerifyOutput(td.outBuffer, `Using the saved selection for local: client and server mods, excluding groups performance, excluding mods mod1.
+Install completed.`, true)
+
+			Expect(visited).To(BeTrue())
+		})
+
+		It("reuses a saved mode", func() {
+			Expect(mc.SetSelection(cmd.InstallLocal, mc.Selection{Mode: mc.SelectionClientOnly})).To(BeNil())
+			verifyFilter(TestingServerGroupNames, []string{})
+			cmd.RootCmd.SetArgs([]string{"install"})
+
+			executeAndVerifyOutput(td.outBuffer, `Using the saved selection for local: client mods only.
+Install completed.`, true)
+
+			Expect(visited).To(BeTrue())
+		})
+
+		It("replaces the saved selection when any flag is given", func() {
+			Expect(mc.SetSelection(cmd.InstallLocal, mc.Selection{ExcludedGroups: []string{"performance"}, ExcludedMods: []string{"mod1"}})).To(BeNil())
+			verifyFilter(TestingServerGroupNames, []string{})
+			cmd.RootCmd.SetArgs([]string{"install", "--client-only"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			s, _, _ := mc.GetSelection(cmd.InstallLocal)
+			Expect(s.Mode).To(Equal(mc.SelectionClientOnly))
+			Expect(s.ExcludedGroups).To(BeEmpty())
+			Expect(s.ExcludedMods).To(BeEmpty())
+		})
+
+		It("keeps the selection of each target separately", func() {
+			Expect(mc.SetSelection(cmd.InstallLocal, mc.Selection{Mode: mc.SelectionClientOnly})).To(BeNil())
+			cmd.ViperInstance.Set(mc.ProfilesKey, []interface{}{})
+			Expect(mc.SetProfile(mc.Profile{Name: "creative", InstallPath: "/home/me/creative"})).To(BeNil())
+			verifyFilter([]string{cmd.ServerOnlyGroupKey}, []string{})
+			cmd.RootCmd.SetArgs([]string{"install", "--profile", "creative"})
+
+			executeAndVerifyOutput(td.outBuffer, "Install completed.", true)
+
+			Expect(visited).To(BeTrue())
+		})
+	})
+
+	Context("show", func() {
+		It("prints the saved selection", func() {
+			Expect(mc.SetSelection(cmd.InstallLocal, mc.Selection{ExcludedMods: []string{"mod1", "modtwo"}})).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"selection", "show"})
+
+			executeAndVerifyOutput(td.outBuffer, "Selection for local: client and server mods, excluding mods mod1,modtwo.", true)
+		})
+
+		It("prints a message when there's no saved selection", func() {
+			cmd.RootCmd.SetArgs([]string{"selection", "show"})
+
+			executeAndVerifyOutput(td.outBuffer, "No saved selection for local.", true)
+		})
+	})
+
+	Context("set", func() {
+		It("saves the selection", func() {
+			cmd.RootCmd.SetArgs([]string{"selection", "set", "--full-server"})
+
+			executeAndVerifyOutput(td.outBuffer, "Selection saved for local.", true)
+
+			s, _, _ := mc.GetSelection(cmd.InstallLocal)
+			Expect(s.Mode).To(Equal(mc.SelectionFullServer))
+		})
+
+		It("returns an error for conflicting modes", func() {
+			cmd.RootCmd.SetArgs([]string{"selection", "set", "--full-server", "--client-only"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+
+		It("returns an error for unknown groups", func() {
+			cmd.RootCmd.SetArgs([]string{"selection", "set", "-x", "unknown"})
+
+			Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+		})
+	})
+
+	Context("clear", func() {
+		It("forgets the saved selection", func() {
+			Expect(mc.SetSelection(cmd.InstallLocal, mc.Selection{Mode: mc.SelectionClientOnly})).To(BeNil())
+			cmd.RootCmd.SetArgs([]string{"selection", "clear"})
+
+			executeAndVerifyOutput(td.outBuffer, "Selection cleared for local.", true)
+
+			_, ok, _ := mc.GetSelection(cmd.InstallLocal)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("prints a message when there's no saved selection", func() {
+			cmd.RootCmd.SetArgs([]string{"selection", "clear"})
+
+			executeAndVerifyOutput(td.outBuffer, "No saved selection for local.", true)
+		})
+	})
+})
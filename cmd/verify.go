@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"mcmods/mc"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// Verifier checks the installation records against the file system
+	Verifier = mc.NewInstallVerifier()
+
+	// allocated here since root's init resets it before this file's init runs
+	repair = new(bool)
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check installed mods against the installation records",
+	Long: `
+Verify checks that each mod the tool has installed still exists in the mods
+folder of the target, with the recorded size and content hash. It also reports
+jars in the mods folder which the tool didn't install. The target is the local
+Minecraft install, or the server when connecting with a password or key file.
+
+Use --repair to re-download the mods which are missing or changed. Unknown jars
+are only reported, never removed, so they're still problems after repairing.
+
+Examples:
+ $ verify
+ $ verify --repair
+ $ verify --repair --password <pw>
+
+Mods installed before the tool recorded sizes and hashes are only checked for
+existence. The exit code is non-zero if problems are found and not repaired.`,
+	Annotations: locksInstall,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// problems aren't usage errors
+		cmd.SilenceUsage = true
+
+		issues, err := Verifier.Verify(fs, UserModConfig)
+		if err != nil {
+			return err
+		}
+
+		if len(issues) == 0 {
+			printToUser(fmt.Sprintf("All %d installed mods verified.", len(UserModConfig.ModInstallations)))
+			return nil
+		}
+
+		for _, issue := range issues {
+			line := fmt.Sprintf("%s  %s", filepath.ToSlash(issue.Path), issue.Problem)
+			if issue.Detail != "" {
+				line += fmt.Sprintf(" (%s)", issue.Detail)
+			}
+			printLineToUser(line)
+		}
+
+		if !*repair {
+			return fmt.Errorf("%d problem(s) found; use --repair to re-download broken mods", len(issues))
+		}
+
+		mods := getRepairMods(issues)
+		left := len(issues) - len(mods)
+		if len(mods) == 0 {
+			printLineToUser("No mods to repair.")
+			return unrepairedError(left)
+		}
+
+		dl, err := CreateDownloaderFunc(fs)
+		if err != nil {
+			return err
+		}
+
+		if err = Installer.InstallMods(dl, mods, UserModConfig); err != nil {
+			return err
+		}
+
+		if err = cfgIo.Save(UserModConfig); err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("Repaired %d mod(s).", len(mods))
+		if left > 0 {
+			printLineToUser(msg)
+			return unrepairedError(left)
+		}
+		printToUser(msg)
+		return nil
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().BoolVar(repair, "repair", false, "Re-download mods which are missing or changed.")
+}
+
+// getRepairMods returns the definitions of the mods with broken installations,
+// skipping mods which are no longer defined
+func getRepairMods(issues []mc.VerifyIssue) []*mc.Mod {
+	allMods := NameMapper.MapAllMods(UserModConfig.ClientMods)
+
+	mods := []*mc.Mod{}
+	for _, issue := range issues {
+		if !issue.Broken() {
+			continue
+		}
+
+		mod, exists := allMods[issue.CliName]
+		if !exists {
+			printLineToUser(fmt.Sprintf("%s  skipped: the mod is no longer defined", issue.CliName))
+			continue
+		}
+		mods = append(mods, mod)
+	}
+	return mods
+}
+
+// unrepairedError is returned for the problems --repair can't fix, so the exit
+// code is non-zero
+func unrepairedError(count int) error {
+	return fmt.Errorf("%d problem(s) weren't repaired: unknown jars are only reported, and mods which are no longer defined can't be downloaded", count)
+}
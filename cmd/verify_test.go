@@ -0,0 +1,117 @@
+package cmd_test
+
+import (
+	"errors"
+	"mcmods/cmd"
+	"mcmods/mc"
+	. "mcmods/testdata"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Verify Cmd", func() {
+	var td *rootTestData
+	var dl mc.ModDownloader
+
+	content := []byte("jar content")
+
+	writeJar := func(cliName string, b []byte) {
+		p := filepath.Join("/minecraft", mc.ModInstallPath(cliName))
+		Expect(afero.WriteFile(td.fs, p, b, 0644)).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		td = rootCmdTestSetup()
+
+		cmd.ViperInstance.Set(mc.InstallPathKey, "/minecraft")
+		cmd.NameMapper = fakeNameMapper{Map: TestingCliModMap}
+		cmd.Verifier = mc.NewInstallVerifier()
+
+		dl = fakeDownloader{}
+		cmd.CreateDownloaderFunc = func(fs mc.FileSystem) (mc.ModDownloader, error) {
+			return dl, nil
+		}
+		cmd.Installer = emptyInstaller{}
+
+		for name, installation := range TestingConfig.ModInstallations {
+			installation.SHA256 = mc.HashSHA256(content)
+			installation.Size = int64(len(content))
+			TestingConfig.ModInstallations[name] = installation
+		}
+	})
+
+	It("prints a summary when everything matches", func() {
+		writeJar(TestingClientMod1.CliName, content)
+		writeJar(TestingServerRequired1.CliName, content)
+		cmd.RootCmd.SetArgs([]string{"verify"})
+
+		executeAndVerifyOutput(td.outBuffer, "All 2 installed mods verified.", true)
+	})
+
+	It("reports problems and returns an error", func() {
+		writeJar(TestingClientMod1.CliName, []byte("changed"))
+		writeJar("unknown", content)
+		cmd.RootCmd.SetArgs([]string{"verify"})
+
+		err := cmd.RootCmd.Execute()
+
+		Expect(err).ToNot(BeNil())
+		Expect(td.outBuffer.String()).To(Equal(
+			"mods/" + TestingClientMod1.CliName + ".jar  size mismatch (expected 11 bytes, found 7)\n" +
+				"mods/" + TestingServerRequired1.CliName + ".jar  missing\n" +
+				"mods/unknown.jar  unknown jar\n"))
+	})
+
+	It("re-installs the broken mods with --repair and saves", func() {
+		visited := false
+		cmd.Installer = installerVerifier{
+			Downloader: dl,
+			Mods:       []*mc.Mod{TestingClientMod1, TestingServerRequired1},
+			Cfg:        TestingConfig,
+			Visited:    &visited,
+		}
+		writeJar(TestingClientMod1.CliName, []byte("jar CONTENT"))
+		cmd.RootCmd.SetArgs([]string{"verify", "--repair"})
+
+		Expect(cmd.RootCmd.Execute()).To(BeNil())
+
+		Expect(visited).To(BeTrue())
+		Expect(*td.cfgIoSpy.Saved).To(BeTrue())
+		Expect(td.outBuffer.String()).To(HaveSuffix("Repaired 2 mod(s)."))
+	})
+
+	It("returns an error for the problems left after repairing", func() {
+		writeJar(TestingClientMod1.CliName, []byte("jar CONTENT"))
+		writeJar("unknown", content)
+		cmd.RootCmd.SetArgs([]string{"verify", "--repair"})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+
+		Expect(*td.cfgIoSpy.Saved).To(BeTrue())
+		Expect(td.outBuffer.String()).To(HaveSuffix("Repaired 2 mod(s).\n"))
+	})
+
+	It("doesn't install anything, and returns an error, when only unknown jars are found", func() {
+		writeJar(TestingClientMod1.CliName, content)
+		writeJar(TestingServerRequired1.CliName, content)
+		writeJar("unknown", content)
+		cmd.RootCmd.SetArgs([]string{"verify", "--repair"})
+
+		Expect(cmd.RootCmd.Execute()).ToNot(BeNil())
+
+		Expect(*td.cfgIoSpy.Saved).To(BeFalse())
+		Expect(td.outBuffer.String()).To(HaveSuffix("No mods to repair.\n"))
+	})
+
+	It("returns errors from installing", func() {
+		installErr := errors.New("install err")
+		cmd.Installer = emptyInstaller{Return: installErr}
+		cmd.RootCmd.SetArgs([]string{"verify", "--repair"})
+
+		Expect(cmd.RootCmd.Execute()).To(Equal(installErr))
+		Expect(*td.cfgIoSpy.Saved).To(BeFalse())
+	})
+})
@@ -2,8 +2,23 @@ package input
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var (
+	// IsTerminal reports whether the file descriptor is a terminal. Exported
+	// for testing
+	IsTerminal = term.IsTerminal
+
+	// ReadPassword reads a line from the terminal without echoing it. Exported
+	// for testing
+	ReadPassword = term.ReadPassword
 )
 
 // Prompt is a way of soliciting input from the user by posing a question and
@@ -38,6 +53,65 @@ func NewYesNoPrompt(promptText string) Prompt {
 	}
 }
 
+type passwordPrompt struct {
+	PromptText string
+}
+
+// NewPasswordPrompt creates a new Prompt which reads a password without echo
+// when the reader is a terminal, or else reads a line
+func NewPasswordPrompt(promptText string) Prompt {
+	return passwordPrompt{PromptText: promptText}
+}
+
+// GetInput prints to the user on the writer, and reads the password from the
+// reader. Returns an error if the password is empty.
+func (p passwordPrompt) GetInput(w io.Writer, r io.Reader) (string, error) {
+	fmt.Fprint(w, p.PromptText)
+
+	var pw string
+	if f, ok := r.(*os.File); ok && IsTerminal(int(f.Fd())) {
+		b, err := ReadPassword(int(f.Fd()))
+		fmt.Fprintln(w) // the user's enter key isn't echoed either
+		if err != nil {
+			return "", err
+		}
+		pw = string(b)
+	} else {
+		line, err := ReadLine(r)
+		if err != nil {
+			return "", err
+		}
+		pw = line
+	}
+
+	if pw == "" {
+		return "", errors.New("no password entered")
+	}
+	return pw, nil
+}
+
+// ReadLine reads up to the end of the line a byte at a time, so nothing after
+// the line is consumed from the reader for the next prompt or command
+func ReadLine(r io.Reader) (string, error) {
+	var sb strings.Builder
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n == 1 {
+			if b[0] == '\n' {
+				break
+			}
+			sb.WriteByte(b[0])
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimRight(sb.String(), "\r"), nil
+}
+
 // GetInput prints to the user on the writer, and reads their input from the reader
 func (p prompt) GetInput(w io.Writer, r io.Reader) (string, error) {
 	doPrompt := true
@@ -7,9 +7,11 @@ import (
 	"io/ioutil"
 	"mcmods/input"
 	. "mcmods/testdata"
+	"os"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"golang.org/x/term"
 )
 
 var _ = Describe("Prompts", func() {
@@ -148,6 +150,67 @@ var _ = Describe("Prompts", func() {
 			verifyOutput(outBuffer, expectedOutput)
 		})
 	})
+
+	Describe("Password Prompt", func() {
+		promptText := "password: "
+		p := input.NewPasswordPrompt(promptText)
+
+		AfterEach(func() {
+			input.IsTerminal = term.IsTerminal
+			input.ReadPassword = term.ReadPassword
+		})
+
+		It("reads a line when the reader isn't a terminal", func() {
+			inBuffer.WriteString("secret\r\nignored\n")
+
+			str, err := p.GetInput(outBuffer, inBuffer)
+
+			Expect(err).To(BeNil())
+			Expect(str).To(Equal("secret"))
+			verifyOutput(outBuffer, promptText)
+		})
+
+		It("leaves the following lines for the next prompt", func() {
+			inBuffer.WriteString("first\nsecond\n")
+
+			first, err1 := p.GetInput(outBuffer, inBuffer)
+			second, err2 := p.GetInput(outBuffer, inBuffer)
+
+			Expect(err1).To(BeNil())
+			Expect(err2).To(BeNil())
+			Expect(first).To(Equal("first"))
+			Expect(second).To(Equal("second"))
+		})
+
+		It("reads without echo from terminals", func() {
+			input.IsTerminal = func(fd int) bool { return true }
+			input.ReadPassword = func(fd int) ([]byte, error) { return []byte("secret"), nil }
+
+			str, err := p.GetInput(outBuffer, os.Stdin)
+
+			Expect(err).To(BeNil())
+			Expect(str).To(Equal("secret"))
+			verifyOutput(outBuffer, promptText+"\n")
+		})
+
+		It("returns errors from reading the terminal", func() {
+			readErr := errors.New("read err")
+			input.IsTerminal = func(fd int) bool { return true }
+			input.ReadPassword = func(fd int) ([]byte, error) { return nil, readErr }
+
+			_, err := p.GetInput(outBuffer, os.Stdin)
+
+			Expect(err).To(Equal(readErr))
+		})
+
+		It("rejects empty passwords", func() {
+			inBuffer.WriteString("\n")
+
+			_, err := p.GetInput(outBuffer, inBuffer)
+
+			Expect(err).ToNot(BeNil())
+		})
+	})
 })
 
 func verifyOutput(buffer *bytes.Buffer, expected string) {
@@ -3,7 +3,11 @@ package input
 import (
 	"mcmods/mc"
 	"net/url"
+	"path/filepath"
 	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
 )
 
 // Validator validates user input
@@ -54,6 +58,52 @@ func (v *URLValidator) Validate(input string) error {
 	return nil
 }
 
+// ModSourceValidator makes sure that a mod package source is either a valid URL
+// or an absolute path to a local file which exists (optionally as a file:// URL)
+type ModSourceValidator struct{}
+
+// Validate returns an error if the given input string is neither a URL nor a
+// path to an existing file
+func (v *ModSourceValidator) Validate(input string) error {
+	if !mc.IsLocalSource(input) {
+		return (&URLValidator{}).Validate(input)
+	}
+
+	p := mc.LocalSourcePath(input)
+	if !filepath.IsAbs(p) {
+		return &ValidationError{Message: "Invalid URL or file path: local files must use an absolute path"}
+	}
+	if exists, _ := afero.Exists(mc.LocalSourceFs, p); !exists {
+		return &ValidationError{Message: "File not found: " + p}
+	}
+	return nil
+}
+
+// URLListValidator makes sure that each URL in a comma-separated list is
+// valid. An empty list is allowed.
+type URLListValidator struct{}
+
+// Validate returns an error if any item in the list is not parsable to a URL
+func (v *URLListValidator) Validate(input string) error {
+	for _, u := range SplitList(input) {
+		if _, err := url.ParseRequestURI(u); err != nil {
+			return &ValidationError{Message: "Invalid URL: " + err.Error()}
+		}
+	}
+	return nil
+}
+
+// SplitList splits comma-separated input into its trimmed, non-empty items
+func SplitList(input string) []string {
+	items := []string{}
+	for _, item := range strings.Split(input, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // CliNameUniquenessValidator ensures that the given name for the CLI is not
 // already in use
 type CliNameUniquenessValidator struct {
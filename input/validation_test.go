@@ -10,6 +10,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
 )
 
 func TestInputs(t *testing.T) {
@@ -93,6 +94,71 @@ var _ = Describe("Validations", func() {
 		})
 	})
 
+	Describe("Mod Source Validator", func() {
+		var validator *input.ModSourceValidator
+		jarPath := "/builds/private-mod.jar"
+
+		BeforeEach(func() {
+			validator = &input.ModSourceValidator{}
+			mc.LocalSourceFs = afero.NewMemMapFs()
+			Expect(afero.WriteFile(mc.LocalSourceFs, jarPath, []byte("jar"), 0644)).To(BeNil())
+		})
+
+		It("should return no errors for HTTP URLs", func() {
+			Expect(validator.Validate("https://www.curseforge.com/minecraft/mc-mods/ducts/download/3571121/file")).To(BeNil())
+		})
+
+		It("should return no errors for existing files", func() {
+			Expect(validator.Validate(jarPath)).To(BeNil())
+			Expect(validator.Validate("file://" + jarPath)).To(BeNil())
+		})
+
+		It("should return errors for missing files", func() {
+			var asgnTest *input.ValidationError
+			err := validator.Validate("/builds/missing.jar")
+
+			Expect(err).ToNot(BeNil())
+			Expect(err).To(BeAssignableToTypeOf(asgnTest))
+		})
+
+		It("should return errors for relative paths", func() {
+			var asgnTest *input.ValidationError
+			err := validator.Validate("private-mod.jar")
+
+			Expect(err).ToNot(BeNil())
+			Expect(err).To(BeAssignableToTypeOf(asgnTest))
+		})
+	})
+
+	Describe("URL List Validator", func() {
+		var validator *input.URLListValidator
+
+		BeforeEach(func() {
+			validator = &input.URLListValidator{}
+		})
+
+		It("should allow an empty list", func() {
+			Expect(validator.Validate("")).To(BeNil())
+			Expect(validator.Validate(" ")).To(BeNil())
+		})
+
+		It("should return no errors for lists of valid URLs", func() {
+			Expect(validator.Validate("https://mirror_1/mod.jar, https://mirror_2/mod.jar")).To(BeNil())
+		})
+
+		It("should return errors if any URL is invalid", func() {
+			var asgnTest *input.ValidationError
+			err := validator.Validate("https://mirror_1/mod.jar,hello")
+
+			Expect(err).ToNot(BeNil())
+			Expect(err).To(BeAssignableToTypeOf(asgnTest))
+		})
+
+		It("splits the list into trimmed items", func() {
+			Expect(input.SplitList(" a, b ,,c ")).To(Equal([]string{"a", "b", "c"}))
+		})
+	})
+
 	Describe("No-op Validator", func() {
 		It("should only return nil", func() {
 			validator := &input.NoOpValidator{}
@@ -0,0 +1,338 @@
+package mc
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// BackupDirName is the name of the backups directory in the user's home
+	// directory. Each install target has its own directory inside it.
+	BackupDirName = ".mcmods-backups"
+
+	// BackupIDFormat is the format of the time in backup IDs
+	BackupIDFormat = "20060102-150405"
+
+	// AutoBackupKey - The key of the map of target names to whether the mods
+	// folder is backed up before installs. Backups are on by default.
+	AutoBackupKey = "autoBackup"
+)
+
+var (
+	// BackupFs is the file system backups are stored on
+	BackupFs = afero.NewOsFs()
+
+	// ErrNothingToBackUp is returned when the target has no mods folder
+	ErrNothingToBackUp = errors.New("there's no mods folder to back up")
+
+	backupTargetRegex = regexp.MustCompile(`[^a-z0-9_-]+`)
+)
+
+// Backup describes an archive of a target's mods folder
+type Backup struct {
+	ID      string
+	Created time.Time
+	Size    int64
+}
+
+// BackupRestore is the result of restoring a backup
+type BackupRestore struct {
+	// Config is the installation records from the backup. They aren't written
+	// to the target, so they can be saved with the history.
+	Config *UserModConfig
+
+	Written int
+	Removed int
+}
+
+// BackupStore keeps timestamped zip archives of the mods folder of one install
+// target, including its installation records
+type BackupStore struct {
+	Fs  afero.Fs
+	Dir string
+}
+
+// DefaultBackupStore returns the store for the target in the user's home
+// directory, or nil if there is no home directory
+func DefaultBackupStore(target string) *BackupStore {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return &BackupStore{Fs: BackupFs, Dir: filepath.Join(home, BackupDirName, BackupTargetName(target))}
+}
+
+// BackupTargetName makes a target name safe to use as a directory name or a
+// config key
+func BackupTargetName(target string) string {
+	return strings.Trim(backupTargetRegex.ReplaceAllString(strings.ToLower(target), "-"), "-")
+}
+
+// AutoBackupEnabled reads whether automatic backups are on for the target
+func AutoBackupEnabled(target string) bool {
+	key := AutoBackupKey + "." + BackupTargetName(target)
+	return !ViperInstance.IsSet(key) || ViperInstance.GetBool(key)
+}
+
+// SetAutoBackup turns automatic backups on or off for the target in Viper. The
+// config isn't written.
+func SetAutoBackup(target string, on bool) {
+	ViperInstance.Set(AutoBackupKey+"."+BackupTargetName(target), on)
+}
+
+// Create archives the mods folder on the file system, downloading each file
+// from remote file systems. Returns ErrNothingToBackUp if there is no mods
+// folder.
+func (s *BackupStore) Create(fs FileSystem, now time.Time) (Backup, error) {
+	files, err := listFiles(fs, ModFolderName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Backup{}, ErrNothingToBackUp
+		}
+		return Backup{}, err
+	}
+	files = removeLockFile(files)
+
+	id, err := s.newID(now)
+	if err != nil {
+		return Backup{}, err
+	}
+	fmt.Printf("Backing up %d file(s) from the mods folder as %s\n", len(files), id)
+
+	zfs, err := NewZipFileSystem(s.Fs, s.archivePath(id))
+	if err != nil {
+		return Backup{}, err
+	}
+	defer zfs.Close()
+
+	if err = zfs.MkDirAll(ModFolderName); err != nil {
+		return Backup{}, err
+	}
+	for _, relPath := range files {
+		b, err := fs.ReadFile(relPath)
+		if err != nil {
+			return Backup{}, fmt.Errorf("couldn't read %s: %w", relPath, err)
+		}
+		if err = zfs.WriteFile(bytes.NewReader(b), relPath); err != nil {
+			return Backup{}, err
+		}
+	}
+	if err = zfs.Finish(); err != nil {
+		return Backup{}, err
+	}
+
+	return s.stat(id)
+}
+
+// List returns the backups, oldest first
+func (s *BackupStore) List() ([]Backup, error) {
+	fis, err := afero.ReadDir(s.Fs, s.Dir)
+	if os.IsNotExist(err) {
+		return []Backup{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	backups := []Backup{}
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".zip") {
+			continue
+		}
+		backups = append(backups, Backup{
+			ID:      strings.TrimSuffix(fi.Name(), ".zip"),
+			Created: fi.ModTime(),
+			Size:    fi.Size(),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ID < backups[j].ID
+	})
+	return backups, nil
+}
+
+// Restore writes the files in the backup to the file system, and removes the
+// files in the mods folder which aren't in it. The history log is left as it
+// is, and the installation records are returned instead of written.
+func (s *BackupStore) Restore(fs FileSystem, id string) (BackupRestore, error) {
+	res := BackupRestore{}
+
+	b, err := afero.ReadFile(s.Fs, s.archivePath(id))
+	if os.IsNotExist(err) || filepath.Base(id) != id {
+		return res, fmt.Errorf("unknown backup: %s", id)
+	} else if err != nil {
+		return res, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return res, fmt.Errorf("backup %s is corrupt: %w", id, err)
+	}
+
+	historyPath := zipEntryName(filepath.Join(ModFolderName, HistoryFileName))
+	configPath := zipEntryName(relUserConfigPath())
+	lockPath := zipEntryName(relLockPath())
+
+	// every entry is read and the records are checked before the target is
+	// changed, so a bad backup doesn't leave the mods folder half restored
+	contents := map[string][]byte{}
+	names := []string{}
+	for _, f := range zr.File {
+		name := zipEntryName(f.Name)
+		if strings.HasSuffix(f.Name, "/") || name == historyPath || name == lockPath {
+			continue
+		}
+
+		content, err := readZipFile(f)
+		if err != nil {
+			return res, fmt.Errorf("backup %s is corrupt: %w", id, err)
+		}
+		contents[name] = content
+		if name != configPath {
+			names = append(names, name)
+		}
+	}
+
+	// the records are loaded like they are from a target, so older ones are
+	// migrated
+	records := LocalFileSystem{Fs: afero.NewMemMapFs(), Dir: "/"}
+	if content, ok := contents[configPath]; ok {
+		if err = records.MkDirAll(path.Dir(configPath)); err != nil {
+			return res, err
+		}
+		if err = records.WriteFile(bytes.NewReader(content), configPath); err != nil {
+			return res, err
+		}
+	}
+	if res.Config, err = NewUserModConfigIo(records).LoadOrNew(); err != nil {
+		return res, fmt.Errorf("the installation records in backup %s are invalid: %w", id, err)
+	}
+
+	kept := map[string]bool{historyPath: true, configPath: true, lockPath: true}
+	for _, name := range names {
+		if err = fs.MkDirAll(path.Dir(name)); err != nil {
+			return res, err
+		}
+		if err = fs.WriteFile(bytes.NewReader(contents[name]), name); err != nil {
+			return res, err
+		}
+		kept[name] = true
+		res.Written++
+	}
+
+	files, err := listFiles(fs, ModFolderName)
+	if err != nil {
+		return res, err
+	}
+	for _, relPath := range files {
+		if kept[zipEntryName(relPath)] {
+			continue
+		}
+		if err = fs.Remove(relPath); err != nil {
+			return res, err
+		}
+		res.Removed++
+	}
+	return res, nil
+}
+
+// Prune removes all but the newest backups, and returns the removed ones
+func (s *BackupStore) Prune(keep int) ([]Backup, error) {
+	backups, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if keep >= len(backups) {
+		return []Backup{}, nil
+	}
+
+	removed := backups[:len(backups)-keep]
+	for _, b := range removed {
+		if err = s.Fs.Remove(s.archivePath(b.ID)); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// newID returns an ID from the time, with a counter added if a backup was
+// already made that second
+func (s *BackupStore) newID(now time.Time) (string, error) {
+	base := now.Format(BackupIDFormat)
+	id := base
+	for n := 2; ; n++ {
+		exists, err := afero.Exists(s.Fs, s.archivePath(id))
+		if err != nil || !exists {
+			return id, err
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func (s *BackupStore) stat(id string) (Backup, error) {
+	fi, err := s.Fs.Stat(s.archivePath(id))
+	if err != nil {
+		return Backup{}, err
+	}
+	return Backup{ID: id, Created: fi.ModTime(), Size: fi.Size()}, nil
+}
+
+func (s *BackupStore) archivePath(id string) string {
+	return filepath.Join(s.Dir, id+".zip")
+}
+
+// listFiles returns the relative paths of the files in the directory and its
+// subdirectories
+func listFiles(fs FileSystem, relDir string) ([]string, error) {
+	infos, err := fs.ReadDir(relDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, fi := range infos {
+		relPath := filepath.Join(relDir, fi.Name)
+		if !fi.IsDir {
+			files = append(files, relPath)
+			continue
+		}
+
+		sub, err := listFiles(fs, relPath)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+// removeLockFile drops the install lock, which belongs to the run holding it,
+// from the files of the mods folder
+func removeLockFile(files []string) []string {
+	kept := make([]string, 0, len(files))
+	for _, f := range files {
+		if f != relLockPath() {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
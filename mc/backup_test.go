@@ -0,0 +1,139 @@
+package mc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mcmods/mc"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Backups", func() {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.Local)
+
+	var afs afero.Fs
+	var fs mc.FileSystem
+	var store *mc.BackupStore
+
+	modPath := func(name string) string {
+		return filepath.Join("/minecraft", mc.ModFolderName, name)
+	}
+	writeRecords := func(installations map[string]mc.ModInstallation) {
+		b, err := json.Marshal(mc.UserModConfig{SchemaVersion: mc.CurrentSchemaVersion, ModInstallations: installations, ClientMods: []*mc.Mod{}})
+		Expect(err).To(BeNil())
+		Expect(afero.WriteFile(afs, modPath(mc.ModConfigFileName), b, 0644)).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		afs = afero.NewMemMapFs()
+		fs = mc.LocalFileSystem{Fs: afs, Dir: "/minecraft"}
+		store = &mc.BackupStore{Fs: afs, Dir: "/backups/local"}
+
+		Expect(afero.WriteFile(afs, modPath("mod-a.jar"), []byte("a1"), 0644)).To(BeNil())
+		Expect(afero.WriteFile(afs, modPath("config/mod-a.toml"), []byte("setting=1"), 0644)).To(BeNil())
+		writeRecords(map[string]mc.ModInstallation{"mod-a": {DownloadURL: "https://a/1"}})
+	})
+
+	It("names targets safely", func() {
+		Expect(mc.BackupTargetName("server-mc.example.com:21")).To(Equal("server-mc-example-com-21"))
+		Expect(mc.BackupTargetName("remote-survival")).To(Equal("remote-survival"))
+	})
+
+	It("archives the mods folder with a timestamped ID", func() {
+		first, err := store.Create(fs, now)
+		Expect(err).To(BeNil())
+		second, err := store.Create(fs, now)
+		Expect(err).To(BeNil())
+
+		Expect(first.ID).To(Equal("20210601-120000"))
+		Expect(second.ID).To(Equal("20210601-120000-2"))
+		backups, err := store.List()
+		Expect(err).To(BeNil())
+		Expect(backups).To(HaveLen(2))
+		Expect(backups[0].ID).To(Equal(first.ID))
+		Expect(backups[0].Size).To(BeNumerically(">", 0))
+	})
+
+	It("has nothing to back up without a mods folder", func() {
+		_, err := store.Create(mc.LocalFileSystem{Fs: afs, Dir: "/empty"}, now)
+
+		Expect(err).To(Equal(mc.ErrNothingToBackUp))
+	})
+
+	It("restores the files and returns the records", func() {
+		backup, err := store.Create(fs, now)
+		Expect(err).To(BeNil())
+
+		// mod-a is updated and mod-b is installed since the backup
+		Expect(afero.WriteFile(afs, modPath("mod-a.jar"), []byte("a2"), 0644)).To(BeNil())
+		Expect(afero.WriteFile(afs, modPath("mod-b.jar"), []byte("b1"), 0644)).To(BeNil())
+		Expect(afero.WriteFile(afs, modPath(mc.HistoryFileName), []byte("{}\n"), 0644)).To(BeNil())
+		writeRecords(map[string]mc.ModInstallation{"mod-a": {DownloadURL: "https://a/2"}, "mod-b": {DownloadURL: "https://b/1"}})
+
+		res, err := store.Restore(fs, backup.ID)
+
+		Expect(err).To(BeNil())
+		Expect(res.Written).To(Equal(2))
+		Expect(res.Removed).To(Equal(1))
+		Expect(res.Config.ModInstallations).To(Equal(map[string]mc.ModInstallation{"mod-a": {DownloadURL: "https://a/1"}}))
+
+		b, _ := afero.ReadFile(afs, modPath("mod-a.jar"))
+		Expect(string(b)).To(Equal("a1"))
+		exists, _ := afero.Exists(afs, modPath("mod-b.jar"))
+		Expect(exists).To(BeFalse())
+		b, _ = afero.ReadFile(afs, modPath(mc.HistoryFileName))
+		Expect(b).To(Equal([]byte("{}\n")), "the history is kept")
+		b, _ = afero.ReadFile(afs, modPath(mc.ModConfigFileName))
+		Expect(bytes.Contains(b, []byte("https://b/1"))).To(BeTrue(), "the records aren't written")
+	})
+
+	It("doesn't change the target when the backed-up records are invalid", func() {
+		Expect(afero.WriteFile(afs, modPath(mc.ModConfigFileName), []byte("[]"), 0644)).To(BeNil())
+		backup, err := store.Create(fs, now)
+		Expect(err).To(BeNil())
+		Expect(afero.WriteFile(afs, modPath("mod-a.jar"), []byte("a2"), 0644)).To(BeNil())
+		Expect(afero.WriteFile(afs, modPath("mod-b.jar"), []byte("b1"), 0644)).To(BeNil())
+
+		_, err = store.Restore(fs, backup.ID)
+
+		Expect(err).ToNot(BeNil())
+		b, _ := afero.ReadFile(afs, modPath("mod-a.jar"))
+		Expect(string(b)).To(Equal("a2"))
+		exists, _ := afero.Exists(afs, modPath("mod-b.jar"))
+		Expect(exists).To(BeTrue())
+	})
+
+	It("returns an error for unknown backups", func() {
+		_, err := store.Restore(fs, "20210601-120000")
+
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("prunes all but the newest backups", func() {
+		for i := 0; i < 3; i++ {
+			_, err := store.Create(fs, now.Add(time.Duration(i)*time.Hour))
+			Expect(err).To(BeNil())
+		}
+
+		removed, err := store.Prune(1)
+
+		Expect(err).To(BeNil())
+		Expect(removed).To(HaveLen(2))
+		backups, _ := store.List()
+		Expect(backups).To(HaveLen(1))
+		Expect(backups[0].ID).To(Equal("20210601-140000"))
+	})
+
+	It("turns automatic backups on and off by target", func() {
+		defer mc.ViperInstance.Set(mc.AutoBackupKey, nil)
+
+		Expect(mc.AutoBackupEnabled("remote-survival")).To(BeTrue())
+		mc.SetAutoBackup("remote-survival", false)
+		Expect(mc.AutoBackupEnabled("remote-survival")).To(BeFalse())
+		Expect(mc.AutoBackupEnabled("local")).To(BeTrue())
+	})
+})
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/afero"
 )
@@ -13,11 +15,54 @@ import (
 const (
 	// ModConfigFileName is the default name of the user config file
 	ModConfigFileName = "mcmods-install.json"
+
+	// CurrentSchemaVersion is the version of the user config file written by
+	// this build. Files without a version are version 0.
+	CurrentSchemaVersion = 2
+
+	// ModConfigBackupSuffix is added to the user config file name for the copy
+	// of the last good file, which is replaced on every save
+	ModConfigBackupSuffix = ".bak"
+
+	// modConfigTmpSuffix is added to the user config file name while it's being
+	// written, before it replaces the file
+	modConfigTmpSuffix = ".tmp"
+)
+
+var (
+	// ErrNewerSchemaVersion is returned when changing a config loaded from a
+	// file written by a newer build, which may hold data this build would drop
+	ErrNewerSchemaVersion = errors.New("the install config was written by a newer version of the tool; update the tool to change it")
+
+	// configMigrations upgrade the parsed JSON of the user config file. The
+	// migration at index i upgrades version i to version i+1.
+	configMigrations = []func(raw map[string]interface{}){
+		migrateConfigV0,
+		migrateConfigV1,
+	}
 )
 
+// CorruptConfigError is returned when the user config file can't be parsed,
+// e.g. because a save was interrupted
+type CorruptConfigError struct {
+	Path string
+	Err  error
+}
+
+func (e *CorruptConfigError) Error() string {
+	return fmt.Sprintf("the install config %s is corrupt: %v; run config repair to rebuild it", e.Path, e.Err)
+}
+
+func (e *CorruptConfigError) Unwrap() error {
+	return e.Err
+}
+
 // UserModConfig contains data about the mods installed by the tool on the
 // system and custom client-only definitions of mods
 type UserModConfig struct {
+	// SchemaVersion is the version of the file the config was loaded from
+	SchemaVersion int `json:"schemaVersion"`
+
 	ModInstallations map[string]ModInstallation `json:"modInstallations"`
 	ClientMods       []*Mod                     `json:"clientMods"`
 }
@@ -25,25 +70,46 @@ type UserModConfig struct {
 // ModConfigIo interface for loading and saving the local installation config
 type ModConfigIo interface {
 	// LoadOrNew loads the JSON file and parses it, or returns a new config
-	// instance if not found
+	// instance if not found. Files written with an older schema are migrated,
+	// after backing up the original file. A CorruptConfigError is returned if
+	// the file can't be parsed.
 	LoadOrNew() (*UserModConfig, error)
 
-	// Save the config as JSON
+	// Save the config as JSON. Configs loaded from a newer schema version
+	// aren't saved. The file is written under a temporary name and then
+	// renamed, after copying the current file to the backup. The changes to
+	// the installations since the config was loaded are added to the history
+	// log.
 	Save(cfg *UserModConfig) error
 }
 
 type modConfigIo struct {
 	Fs FileSystem
+
+	// saved is a copy of the installations as last loaded or saved, to find
+	// the changes for the history. Nil until the config is loaded.
+	saved *map[string]ModInstallation
+
+	// migrated is the file the config was migrated from on load, backed up
+	// when the migrated config is saved
+	migrated *migrationBackup
+}
+
+// migrationBackup is a config file as it was before it was migrated
+type migrationBackup struct {
+	path string
+	b    []byte
 }
 
 // NewUserModConfigIo returns a new interface for reading/writing mod config
 func NewUserModConfigIo(fs FileSystem) ModConfigIo {
-	return modConfigIo{Fs: fs}
+	return modConfigIo{Fs: fs, saved: new(map[string]ModInstallation), migrated: &migrationBackup{}}
 }
 
 // NewUserModConfig returns a new config with fields initialized and empty
 func NewUserModConfig() UserModConfig {
 	return UserModConfig{
+		SchemaVersion:    CurrentSchemaVersion,
 		ModInstallations: map[string]ModInstallation{},
 		ClientMods:       []*Mod{},
 	}
@@ -52,30 +118,183 @@ func NewUserModConfig() UserModConfig {
 // LoadOrNew loads the JSON file and parses it, or returns a new config instance if not found
 func (m modConfigIo) LoadOrNew() (*UserModConfig, error) {
 	cfg := &UserModConfig{}
+	*m.migrated = migrationBackup{}
 	bytes, err := m.Fs.ReadFile(relUserConfigPath())
 
 	if err == nil {
-		err = json.Unmarshal(bytes, cfg)
+		bytes, err = m.migrate(bytes)
+		if err == nil {
+			if err = json.Unmarshal(bytes, cfg); err != nil {
+				err = &CorruptConfigError{Path: relUserConfigPath(), Err: err}
+			}
+		}
 		if err != nil {
 			cfg = nil
 		}
 	} else if os.IsNotExist(err) {
+		cfg.SchemaVersion = CurrentSchemaVersion
 		cfg.ClientMods = []*Mod{}
 		cfg.ModInstallations = map[string]ModInstallation{}
 		err = nil
 	}
 
+	if err == nil {
+		*m.saved = copyInstallations(cfg.ModInstallations)
+	}
 	return cfg, err
 }
 
 // Save the config as JSON
 func (m modConfigIo) Save(cfg *UserModConfig) error {
-	b, err := json.MarshalIndent(cfg, "", "\t")
+	if cfg.SchemaVersion > CurrentSchemaVersion {
+		return ErrNewerSchemaVersion
+	}
+
+	saved := *cfg
+	saved.SchemaVersion = CurrentSchemaVersion
+	b, err := json.MarshalIndent(saved, "", "\t")
 	if err != nil {
 		return err // not sure how to test :/
 	}
 
-	return m.Fs.WriteFile(bytes.NewReader(b), relUserConfigPath())
+	if err = m.backUpMigrated(); err != nil {
+		return err
+	}
+	if err = m.backUp(); err != nil {
+		return err
+	}
+	tmpPath := relUserConfigPath() + modConfigTmpSuffix
+	if err = m.Fs.WriteFile(bytes.NewReader(b), tmpPath); err != nil {
+		return err
+	}
+	if err = m.Fs.Rename(tmpPath, relUserConfigPath()); err != nil {
+		return err
+	}
+
+	if *m.saved == nil {
+		return nil
+	}
+	events := DiffInstallations(*m.saved, cfg.ModInstallations, time.Now())
+	if err = AppendHistory(m.Fs, events); err != nil {
+		return fmt.Errorf("the config was saved, but the history wasn't: %w", err)
+	}
+	*m.saved = copyInstallations(cfg.ModInstallations)
+	return nil
+}
+
+// backUp copies the current config file to the backup. Files which aren't
+// valid JSON aren't copied, so the backup stays the last good file.
+func (m modConfigIo) backUp() error {
+	b, err := m.Fs.ReadFile(relUserConfigPath())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !json.Valid(b) {
+		return nil
+	}
+	return m.Fs.WriteFile(bytes.NewReader(b), relUserConfigPath()+ModConfigBackupSuffix)
+}
+
+// backUpMigrated writes the file the config was migrated from, unless a backup
+// of the same version already exists
+func (m modConfigIo) backUpMigrated() error {
+	if m.migrated.b == nil {
+		return nil
+	}
+	if _, err := m.Fs.Stat(m.migrated.path); os.IsNotExist(err) {
+		if err = m.Fs.WriteFile(bytes.NewReader(m.migrated.b), m.migrated.path); err != nil {
+			return err
+		}
+	}
+	*m.migrated = migrationBackup{}
+	return nil
+}
+
+func copyInstallations(installations map[string]ModInstallation) map[string]ModInstallation {
+	c := make(map[string]ModInstallation, len(installations))
+	for cliName, installation := range installations {
+		c[cliName] = installation
+	}
+	return c
+}
+
+// migrate runs the migrations from the file's schema version to the current
+// one, and returns the migrated JSON. The original file is kept to be backed
+// up when the config is saved, so commands which only read it don't write.
+func (m modConfigIo) migrate(b []byte) ([]byte, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, &CorruptConfigError{Path: relUserConfigPath(), Err: err}
+	}
+	// a null file is a version 0 file with nothing in it
+	if raw == nil {
+		raw = map[string]interface{}{}
+	}
+
+	version := 0
+	if v, ok := raw["schemaVersion"].(float64); ok {
+		version = int(v)
+	}
+	if version >= CurrentSchemaVersion {
+		return b, nil
+	}
+
+	*m.migrated = migrationBackup{path: fmt.Sprintf("%s.v%d.bak", relUserConfigPath(), version), b: b}
+
+	for ; version < CurrentSchemaVersion; version++ {
+		configMigrations[version](raw)
+	}
+	raw["schemaVersion"] = CurrentSchemaVersion
+
+	return json.Marshal(raw)
+}
+
+// migrateConfigV0 fills in the sections which version 0 files could leave
+// null
+func migrateConfigV0(raw map[string]interface{}) {
+	if raw["modInstallations"] == nil {
+		raw["modInstallations"] = map[string]interface{}{}
+	}
+	if raw["clientMods"] == nil {
+		raw["clientMods"] = []interface{}{}
+	}
+}
+
+// migrateConfigV1 converts the install timestamps from time.UnixDate to RFC
+// 3339
+func migrateConfigV1(raw map[string]interface{}) {
+	installations, _ := raw["modInstallations"].(map[string]interface{})
+	for _, installation := range installations {
+		migrateInstallTimestamp(installation)
+	}
+}
+
+// migrateInstallTimestamp converts the timestamp of a parsed installation from
+// time.UnixDate to RFC 3339. The zone is looked up in the local time zone,
+// where the install was most likely made, and is UTC if it isn't found there.
+// Timestamps which can't be parsed are dropped.
+func migrateInstallTimestamp(installation interface{}) {
+	fields, ok := installation.(map[string]interface{})
+	if !ok {
+		return
+	}
+	s, ok := fields["timestamp"].(string)
+	if !ok {
+		return
+	}
+	if _, err := time.Parse(time.RFC3339, s); err == nil {
+		return
+	}
+
+	t, err := time.ParseInLocation(time.UnixDate, s, time.Local)
+	if err != nil {
+		delete(fields, "timestamp")
+		return
+	}
+	fields["timestamp"] = t.Format(time.RFC3339)
 }
 
 func relUserConfigPath() string {
@@ -2,10 +2,12 @@ package mc_test
 
 import (
 	"encoding/json"
+	"errors"
 	"mcmods/mc"
 	. "mcmods/testdata"
 	"os"
 	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -31,10 +33,12 @@ var _ = Describe("Config IO", func() {
 		BeforeEach(func() {
 			configPath = filepath.Join(mcPath, mc.ModFolderName, mc.ModConfigFileName)
 
-			b, _ := json.MarshalIndent(TestingConfig, "", "\t")
+			saved := *TestingConfig
+			saved.SchemaVersion = mc.CurrentSchemaVersion
+			b, _ := json.MarshalIndent(saved, "", "\t")
 			content = string(b)
 
-			b, _ = json.MarshalIndent(mc.UserModConfig{}, "", "\t")
+			b, _ = json.MarshalIndent(mc.UserModConfig{SchemaVersion: mc.CurrentSchemaVersion}, "", "\t")
 			emptyContent = string(b)
 
 			mcfs := &mc.LocalFileSystem{Fs: fs}
@@ -89,11 +93,99 @@ var _ = Describe("Config IO", func() {
 					cfg, err := configIo.LoadOrNew()
 
 					Expect(err).To(BeNil())
+					Expect(cfg.SchemaVersion).To(Equal(mc.CurrentSchemaVersion))
 					Expect(cfg.ClientMods).To(BeEmpty())
 					Expect(cfg.ModInstallations).To(BeEmpty())
 				})
 			})
 
+			When("the file has an older schema version", func() {
+				v0Content := `{"modInstallations": {"mod-a": {"downloadUrl": "https://a"}}, "clientMods": null}`
+				backupPath := func() string {
+					return configPath + ".v0.bak"
+				}
+
+				It("migrates the config, and backs up the file when it's saved", func() {
+					afero.WriteFile(fs, configPath, []byte(v0Content), 0644)
+
+					cfg, err := configIo.LoadOrNew()
+
+					Expect(err).To(BeNil())
+					Expect(cfg.SchemaVersion).To(Equal(mc.CurrentSchemaVersion))
+					Expect(cfg.ClientMods).ToNot(BeNil())
+					Expect(cfg.ModInstallations["mod-a"].DownloadURL).To(Equal("https://a"))
+					exists, _ := afero.Exists(fs, backupPath())
+					Expect(exists).To(BeFalse(), "loading doesn't write")
+
+					Expect(configIo.Save(cfg)).To(BeNil())
+					b, err := afero.ReadFile(fs, backupPath())
+					Expect(err).To(BeNil())
+					Expect(string(b)).To(Equal(v0Content))
+				})
+
+				It("converts the install timestamps to RFC 3339", func() {
+					v1Content := `{"schemaVersion": 1, "modInstallations": {` +
+						`"mod-a": {"downloadUrl": "https://a", "timestamp": "Tue Jun  1 12:00:00 UTC 2021"},` +
+						`"mod-b": {"downloadUrl": "https://b", "timestamp": "garbage"}}, "clientMods": []}`
+					afero.WriteFile(fs, configPath, []byte(v1Content), 0644)
+
+					cfg, err := configIo.LoadOrNew()
+
+					Expect(err).To(BeNil())
+					Expect(cfg.ModInstallations["mod-a"].Timestamp.Equal(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))).To(BeTrue())
+					Expect(cfg.ModInstallations["mod-b"].Timestamp.IsZero()).To(BeTrue())
+					Expect(cfg.ModInstallations["mod-b"].DownloadURL).To(Equal("https://b"))
+				})
+
+				It("loads null files as empty configs", func() {
+					afero.WriteFile(fs, configPath, []byte("null"), 0644)
+
+					cfg, err := configIo.LoadOrNew()
+
+					Expect(err).To(BeNil())
+					Expect(cfg.ModInstallations).To(BeEmpty())
+					Expect(cfg.ClientMods).ToNot(BeNil())
+				})
+
+				It("returns a corrupt config error for files which aren't objects", func() {
+					afero.WriteFile(fs, configPath, []byte("[]"), 0644)
+
+					_, err := configIo.LoadOrNew()
+
+					corrupt := &mc.CorruptConfigError{}
+					Expect(errors.As(err, &corrupt)).To(BeTrue())
+				})
+
+				It("keeps the first backup", func() {
+					afero.WriteFile(fs, configPath, []byte(v0Content), 0644)
+					afero.WriteFile(fs, backupPath(), []byte("first"), 0644)
+
+					cfg, err := configIo.LoadOrNew()
+
+					Expect(err).To(BeNil())
+					Expect(configIo.Save(cfg)).To(BeNil())
+					b, _ := afero.ReadFile(fs, backupPath())
+					Expect(string(b)).To(Equal("first"))
+				})
+			})
+
+			When("the file has a newer schema version", func() {
+				newerContent := `{"schemaVersion": 99, "modInstallations": {}, "clientMods": [], "newField": true}`
+
+				It("loads the config, but doesn't save over the file", func() {
+					afero.WriteFile(fs, configPath, []byte(newerContent), 0644)
+
+					cfg, err := configIo.LoadOrNew()
+
+					Expect(err).To(BeNil())
+					Expect(cfg.SchemaVersion).To(Equal(99))
+					Expect(configIo.Save(cfg)).To(Equal(mc.ErrNewerSchemaVersion))
+
+					b, _ := afero.ReadFile(fs, configPath)
+					Expect(string(b)).To(Equal(newerContent))
+				})
+			})
+
 			When("there are json unmarshalling errors", func() {
 				It("returns the error", func() {
 					afero.WriteFile(fs, configPath, []byte("{"), 0644)
@@ -103,6 +195,17 @@ var _ = Describe("Config IO", func() {
 					Expect(cfg).To(BeNil())
 					Expect(err).ToNot(BeNil())
 				})
+
+				It("returns a corrupt config error for truncated files", func() {
+					afero.WriteFile(fs, configPath, []byte(content[:len(content)/2]), 0644)
+
+					_, err := configIo.LoadOrNew()
+
+					corrupt := &mc.CorruptConfigError{}
+					Expect(errors.As(err, &corrupt)).To(BeTrue())
+					Expect(corrupt.Path).To(Equal(filepath.Join(mc.ModFolderName, mc.ModConfigFileName)))
+					Expect(err.Error()).To(ContainSubstring("config repair"))
+				})
 			})
 		})
 
@@ -126,6 +229,49 @@ var _ = Describe("Config IO", func() {
 
 				Expect(string(b)).To(Equal(content))
 			})
+
+			It("copies the previous file to the backup without leaving a temporary file", func() {
+				Expect(configIo.Save(&mc.UserModConfig{})).To(BeNil())
+				Expect(configIo.Save(TestingConfig)).To(BeNil())
+
+				b, _ := afero.ReadFile(fs, configPath)
+				Expect(string(b)).To(Equal(content))
+				b, _ = afero.ReadFile(fs, configPath+mc.ModConfigBackupSuffix)
+				Expect(string(b)).To(Equal(emptyContent))
+				infos, _ := afero.ReadDir(fs, filepath.Dir(configPath))
+				Expect(infos).To(HaveLen(2))
+			})
+
+			It("keeps the last good backup when the file is corrupt", func() {
+				afero.WriteFile(fs, configPath+mc.ModConfigBackupSuffix, []byte(emptyContent), 0644)
+				afero.WriteFile(fs, configPath, []byte("{"), 0644)
+
+				Expect(configIo.Save(TestingConfig)).To(BeNil())
+
+				b, _ := afero.ReadFile(fs, configPath+mc.ModConfigBackupSuffix)
+				Expect(string(b)).To(Equal(emptyContent))
+			})
+
+			It("adds the changes since the config was loaded to the history", func() {
+				historyPath := filepath.Join(mcPath, mc.ModFolderName, mc.HistoryFileName)
+				mcfs := &mc.LocalFileSystem{Fs: fs}
+
+				Expect(configIo.Save(TestingConfig)).To(BeNil())
+				exists, _ := afero.Exists(fs, historyPath)
+				Expect(exists).To(BeFalse())
+
+				cfg, err := configIo.LoadOrNew()
+				Expect(err).To(BeNil())
+				cfg.ModInstallations["new-mod"] = mc.ModInstallation{DownloadURL: "https://new"}
+				Expect(configIo.Save(cfg)).To(BeNil())
+				Expect(configIo.Save(cfg)).To(BeNil())
+
+				events, err := mc.ReadHistory(mcfs)
+				Expect(err).To(BeNil())
+				Expect(events).To(HaveLen(1))
+				Expect(events[0].Event).To(Equal(mc.HistoryInstall))
+				Expect(events[0].Mod).To(Equal("new-mod"))
+			})
 		})
 
 		Context("New func", func() {
@@ -133,6 +279,7 @@ var _ = Describe("Config IO", func() {
 				cfg := mc.NewUserModConfig()
 
 				Expect(cfg).ToNot(BeNil())
+				Expect(cfg.SchemaVersion).To(Equal(mc.CurrentSchemaVersion))
 				Expect(cfg.ClientMods).ToNot(BeNil())
 				Expect(cfg.ClientMods).To(BeEmpty())
 				Expect(cfg.ModInstallations).ToNot(BeNil())
@@ -0,0 +1,348 @@
+package mc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// CredentialsFileName is the name of the encrypted credentials file in the
+	// user's home directory
+	CredentialsFileName = ".mcmods-credentials"
+
+	// CredentialRemote is the kind of credential holding a remote's password
+	CredentialRemote = "remote"
+
+	// CredentialHost is the kind of credential holding a download host's token
+	CredentialHost = "host"
+
+	credentialsVersion = 1
+	credentialsKeyLen  = 32
+)
+
+var (
+	// CredentialsFs is the file system the credentials file is stored on
+	CredentialsFs = afero.NewOsFs()
+
+	// ErrWrongPassphrase is returned when the credentials can't be decrypted
+	ErrWrongPassphrase = errors.New("wrong passphrase, or the credentials file is corrupt")
+
+	// scrypt cost parameters for new credential files. Existing files keep the
+	// parameters they were created with.
+	scryptN, scryptR, scryptP = 1 << 15, 8, 1
+)
+
+// Credential is a secret stored in the credential store
+type Credential struct {
+	Kind   string
+	Name   string
+	Secret string
+}
+
+// CredentialStore holds the passwords for remotes and the tokens for download
+// hosts, encrypted with a key derived from a passphrase
+type CredentialStore struct {
+	Path    string
+	Remotes map[string]string
+	Hosts   map[string]string
+
+	file credentialsFile
+	key  []byte
+}
+
+// credentialsFile is the format of the file on disk. The salt and scrypt
+// parameters are authenticated along with the encrypted data.
+type credentialsFile struct {
+	Version int    `json:"version"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+
+	// HostNames are the hosts with tokens, kept unencrypted so the store is
+	// only opened for downloads from them. Host names aren't secret. Nil in
+	// files saved before they were kept.
+	HostNames []string `json:"hostNames"`
+}
+
+type credentialsData struct {
+	Remotes map[string]string `json:"remotes,omitempty"`
+	Hosts   map[string]string `json:"hosts,omitempty"`
+}
+
+// CredentialsPath returns the path of the credentials file
+func CredentialsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, CredentialsFileName), nil
+}
+
+// CredentialStoreExists returns true if the credentials file has been created
+func CredentialStoreExists(path string) (bool, error) {
+	return afero.Exists(CredentialsFs, path)
+}
+
+// CredentialStoreHosts returns the hosts with tokens in the store, without
+// decrypting it. Returns nil if the store was saved before they were kept, in
+// which case any host may have one.
+func CredentialStoreHosts(path string) ([]string, error) {
+	f, err := readCredentialsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.HostNames, nil
+}
+
+// NewCredentialStore creates an empty store protected by the passphrase. The
+// file isn't written until it's saved.
+func NewCredentialStore(path, passphrase string) (*CredentialStore, error) {
+	if passphrase == "" {
+		return nil, errors.New("the passphrase can't be empty")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	store := &CredentialStore{
+		Path:    path,
+		Remotes: map[string]string{},
+		Hosts:   map[string]string{},
+		file: credentialsFile{
+			Version: credentialsVersion,
+			N:       scryptN,
+			R:       scryptR,
+			P:       scryptP,
+			Salt:    salt,
+		},
+	}
+
+	var err error
+	store.key, err = deriveCredentialsKey(passphrase, store.file)
+	return store, err
+}
+
+// OpenCredentialStore reads the store and decrypts it with the passphrase
+func OpenCredentialStore(path, passphrase string) (*CredentialStore, error) {
+	f, err := readCredentialsFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := deriveCredentialsKey(passphrase, f)
+	if err != nil {
+		return nil, err
+	}
+	return decryptCredentialStore(path, f, key)
+}
+
+// OpenCredentialStoreWithKey reads the store and decrypts it with a session
+// key from SessionKey, skipping the slow key derivation
+func OpenCredentialStoreWithKey(path, sessionKey string) (*CredentialStore, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil || len(key) != credentialsKeyLen {
+		return nil, errors.New("invalid credentials session key")
+	}
+
+	f, err := readCredentialsFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decryptCredentialStore(path, f, key)
+}
+
+// SessionKey returns the derived key, which unlocks the store until it's
+// created again with a new passphrase
+func (s *CredentialStore) SessionKey() string {
+	return base64.StdEncoding.EncodeToString(s.key)
+}
+
+// Save encrypts the store and writes it, readable only by the user
+func (s *CredentialStore) Save() error {
+	plaintext, err := json.Marshal(credentialsData{Remotes: s.Remotes, Hosts: s.Hosts})
+	if err != nil {
+		return err
+	}
+
+	aead, err := newCredentialsAEAD(s.key)
+	if err != nil {
+		return err
+	}
+
+	f := s.file
+	f.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Data = aead.Seal(nil, f.Nonce, plaintext, f.additionalData())
+	f.HostNames = make([]string, 0, len(s.Hosts))
+	for host := range s.Hosts {
+		f.HostNames = append(f.HostNames, host)
+	}
+	sort.Strings(f.HostNames)
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := afero.WriteFile(CredentialsFs, s.Path, b, 0600); err != nil {
+		return err
+	}
+
+	s.file = f
+	return nil
+}
+
+// Set stores the secret, replacing any with the same kind and name
+func (s *CredentialStore) Set(kind, name, secret string) error {
+	m, err := s.credentialMap(kind)
+	if err != nil {
+		return err
+	}
+	m[name] = secret
+	return nil
+}
+
+// Get returns the secret with the kind and name, or false if there's none
+func (s *CredentialStore) Get(kind, name string) (string, bool) {
+	m, err := s.credentialMap(kind)
+	if err != nil {
+		return "", false
+	}
+	secret, ok := m[name]
+	return secret, ok
+}
+
+// Remove deletes the secret with the kind and name
+func (s *CredentialStore) Remove(kind, name string) error {
+	m, err := s.credentialMap(kind)
+	if err != nil {
+		return err
+	}
+	if _, ok := m[name]; !ok {
+		return fmt.Errorf("no %s credential for %s", kind, name)
+	}
+	delete(m, name)
+	return nil
+}
+
+// List returns the credentials sorted by kind and name
+func (s *CredentialStore) List() []Credential {
+	creds := []Credential{}
+	for name, secret := range s.Hosts {
+		creds = append(creds, Credential{Kind: CredentialHost, Name: name, Secret: secret})
+	}
+	for name, secret := range s.Remotes {
+		creds = append(creds, Credential{Kind: CredentialRemote, Name: name, Secret: secret})
+	}
+
+	sort.Slice(creds, func(i, j int) bool {
+		if creds[i].Kind != creds[j].Kind {
+			return creds[i].Kind < creds[j].Kind
+		}
+		return creds[i].Name < creds[j].Name
+	})
+	return creds
+}
+
+// HostCredentials returns the host tokens as HTTP credentials
+func (s *CredentialStore) HostCredentials() map[string]HostCredential {
+	creds := make(map[string]HostCredential, len(s.Hosts))
+	for host, token := range s.Hosts {
+		creds[host] = HostCredential{Token: token}
+	}
+	return creds
+}
+
+func (s *CredentialStore) credentialMap(kind string) (map[string]string, error) {
+	switch kind {
+	case CredentialRemote:
+		return s.Remotes, nil
+	case CredentialHost:
+		return s.Hosts, nil
+	default:
+		return nil, fmt.Errorf("unknown credential kind %q: use %s or %s", kind, CredentialRemote, CredentialHost)
+	}
+}
+
+func (f credentialsFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("mcmods-credentials:%d:%d:%d:%d:%x", f.Version, f.N, f.R, f.P, f.Salt))
+}
+
+func readCredentialsFile(path string) (credentialsFile, error) {
+	f := credentialsFile{}
+
+	b, err := afero.ReadFile(CredentialsFs, path)
+	if err != nil {
+		return f, err
+	}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return f, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	if f.Version != credentialsVersion {
+		return f, fmt.Errorf("unsupported credentials file version %d", f.Version)
+	}
+	return f, nil
+}
+
+func deriveCredentialsKey(passphrase string, f credentialsFile) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), f.Salt, f.N, f.R, f.P, credentialsKeyLen)
+}
+
+func newCredentialsAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func decryptCredentialStore(path string, f credentialsFile, key []byte) (*CredentialStore, error) {
+	aead, err := newCredentialsAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+
+	plaintext, err := aead.Open(nil, f.Nonce, f.Data, f.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	data := credentialsData{}
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, fmt.Errorf("invalid credentials data: %w", err)
+	}
+	if data.Remotes == nil {
+		data.Remotes = map[string]string{}
+	}
+	if data.Hosts == nil {
+		data.Hosts = map[string]string{}
+	}
+
+	return &CredentialStore{
+		Path:    path,
+		Remotes: data.Remotes,
+		Hosts:   data.Hosts,
+		file:    f,
+		key:     key,
+	}, nil
+}
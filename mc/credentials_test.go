@@ -0,0 +1,104 @@
+package mc_test
+
+import (
+	"mcmods/mc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Credential Store", func() {
+	path := "/home/me/.mcmods-credentials"
+	passphrase := "correct horse"
+
+	var store *mc.CredentialStore
+
+	BeforeEach(func() {
+		mc.CredentialsFs = afero.NewMemMapFs()
+
+		var err error
+		store, err = mc.NewCredentialStore(path, passphrase)
+		Expect(err).To(BeNil())
+		Expect(store.Set(mc.CredentialRemote, "survival", "ftp-pw")).To(BeNil())
+		Expect(store.Set(mc.CredentialHost, "builds.example.com", "token")).To(BeNil())
+		Expect(store.Save()).To(BeNil())
+	})
+
+	It("reads the saved credentials with the passphrase", func() {
+		opened, err := mc.OpenCredentialStore(path, passphrase)
+
+		Expect(err).To(BeNil())
+		pw, ok := opened.Get(mc.CredentialRemote, "survival")
+		Expect(ok).To(BeTrue())
+		Expect(pw).To(Equal("ftp-pw"))
+		Expect(opened.HostCredentials()).To(Equal(map[string]mc.HostCredential{
+			"builds.example.com": {Token: "token"},
+		}))
+	})
+
+	It("reads the hosts with tokens without the passphrase", func() {
+		Expect(store.Set(mc.CredentialHost, "artifacts.example.com", "token2")).To(BeNil())
+		Expect(store.Save()).To(BeNil())
+
+		hosts, err := mc.CredentialStoreHosts(path)
+
+		Expect(err).To(BeNil())
+		Expect(hosts).To(Equal([]string{"artifacts.example.com", "builds.example.com"}))
+	})
+
+	It("reads the saved credentials with the session key", func() {
+		opened, err := mc.OpenCredentialStoreWithKey(path, store.SessionKey())
+
+		Expect(err).To(BeNil())
+		Expect(opened.List()).To(Equal(store.List()))
+	})
+
+	It("doesn't store the secrets in plain text", func() {
+		b, err := afero.ReadFile(mc.CredentialsFs, path)
+
+		Expect(err).To(BeNil())
+		Expect(string(b)).ToNot(ContainSubstring("ftp-pw"))
+		Expect(string(b)).ToNot(ContainSubstring("survival"))
+	})
+
+	It("rejects the wrong passphrase", func() {
+		_, err := mc.OpenCredentialStore(path, "wrong")
+
+		Expect(err).To(Equal(mc.ErrWrongPassphrase))
+	})
+
+	It("rejects invalid session keys", func() {
+		_, err := mc.OpenCredentialStoreWithKey(path, "not a key")
+
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("lists the credentials sorted by kind and name", func() {
+		Expect(store.Set(mc.CredentialRemote, "creative", "pw2")).To(BeNil())
+
+		Expect(store.List()).To(Equal([]mc.Credential{
+			{Kind: mc.CredentialHost, Name: "builds.example.com", Secret: "token"},
+			{Kind: mc.CredentialRemote, Name: "creative", Secret: "pw2"},
+			{Kind: mc.CredentialRemote, Name: "survival", Secret: "ftp-pw"},
+		}))
+	})
+
+	It("removes credentials", func() {
+		Expect(store.Remove(mc.CredentialRemote, "survival")).To(BeNil())
+
+		_, ok := store.Get(mc.CredentialRemote, "survival")
+		Expect(ok).To(BeFalse())
+		Expect(store.Remove(mc.CredentialRemote, "survival")).ToNot(BeNil())
+	})
+
+	It("rejects unknown kinds", func() {
+		Expect(store.Set("other", "name", "secret")).ToNot(BeNil())
+	})
+
+	It("rejects empty passphrases", func() {
+		_, err := mc.NewCredentialStore(path, "")
+
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -1,46 +1,127 @@
 package mc
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"path/filepath"
 )
 
 // ModDownloader is the interface for downloading mods
 type ModDownloader interface {
 	// Download the mod to the specified file path
-	Download(mod *Mod, filePath string) error
+	Download(mod *Mod, filePath string) (*DownloadResult, error)
+}
+
+// DownloadResult describes the file saved by a download
+type DownloadResult struct {
+	// URL is the location the file was actually downloaded from
+	URL string
+
+	// SHA256 is the hex encoded digest of the file's content
+	SHA256 string
+
+	// Size is the number of bytes in the file
+	Size int64
 }
 
 // ModDownloaderImpl only exported for testing access. Use ModDownloader interface
 type ModDownloaderImpl struct {
 	Fs         FileSystem
 	HTTPClient *HTTPClient
+
+	// Cache keeps a copy of each downloaded jar. Nothing is cached if it's nil.
+	Cache *JarCache
 }
 
 // NewModDownloader creates a new instance of a struct which implements Downloader
-// over the given http client
-func NewModDownloader(hc *HTTPClient, fs FileSystem) ModDownloader {
+// over the given http client. Downloads are kept in the cache, if it isn't nil.
+func NewModDownloader(hc *HTTPClient, fs FileSystem, cache *JarCache) ModDownloader {
 	return &ModDownloaderImpl{
 		Fs:         fs,
 		HTTPClient: hc,
+		Cache:      cache,
 	}
 }
 
-// Download the specified mod from its LatestUrl and save it to the location specified
-func (d ModDownloaderImpl) Download(mod *Mod, relPath string) error {
+// Download the specified mod and save it to the location specified. The mod's
+// LatestURL is tried first, then each of its mirrors until one succeeds.
+func (d ModDownloaderImpl) Download(mod *Mod, relPath string) (*DownloadResult, error) {
 	err := d.Fs.MkDirAll(filepath.Dir(relPath))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fmt.Printf("  Downloading %s\n    to: %s\n", mod.LatestURL, relPath)
+	urls := mod.DownloadURLs()
+	for i, url := range urls {
+		if i > 0 {
+			fmt.Printf("    failed: %s\n  Trying mirror %d of %d\n", err, i, len(urls)-1)
+		}
+
+		if IsLocalSource(url) {
+			fmt.Printf("  Copying %s\n    to: %s\n", url, relPath)
+		} else {
+			fmt.Printf("  Downloading %s\n    to: %s\n", url, relPath)
+		}
+
+		var b []byte
+		if b, err = d.fetch(mod, url, i > 0); err == nil {
+			if d.Cache != nil {
+				if cacheErr := d.Cache.Put(b); cacheErr != nil {
+					fmt.Printf("    not cached: %s\n", cacheErr)
+				}
+			}
+
+			res := &DownloadResult{URL: url, SHA256: HashSHA256(b), Size: int64(len(b))}
+			return res, d.Fs.WriteFile(bytes.NewReader(b), relPath)
+		}
+	}
+
+	if len(urls) > 1 {
+		err = fmt.Errorf("all %d download URLs failed for %s, last error: %w", len(urls), mod.CliName, err)
+	}
+	return nil, err
+}
+
+// fetch reads the file at the URL and verifies it against the mod's hashes.
+// Mirrors can only be used when there are hashes to verify them with.
+func (d ModDownloaderImpl) fetch(mod *Mod, url string, mirror bool) ([]byte, error) {
+	var b []byte
+	var err error
 
-	resp, err := d.HTTPClient.Getter.Get(mod.LatestURL)
+	if IsLocalSource(url) {
+		b, err = ReadLocalSource(url)
+	} else {
+		b, err = d.get(url)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checked, err := VerifyHashes(b, mod.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	if mirror && checked == 0 {
+		return nil, errors.New("mirror can't be verified: the mod has no file hashes")
+	}
+
+	return b, nil
+}
+
+func (d ModDownloaderImpl) get(url string) ([]byte, error) {
+	resp, err := d.HTTPClient.Getter.Get(url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	defer resp.Body.Close()
 
-	return d.Fs.WriteFile(resp.Body, relPath)
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
 }
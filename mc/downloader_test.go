@@ -40,11 +40,11 @@ var _ = Describe("Downloader", func() {
 			mcfs = &mc.LocalFileSystem{Fs: fs}
 			eg = &emptyGetter{Res: &http.Response{Body: rc}}
 			hc = &mc.HTTPClient{Getter: eg}
-			dl = mc.NewModDownloader(hc, mcfs)
+			dl = mc.NewModDownloader(hc, mcfs, nil)
 		})
 
 		It("creates directories if not present, writes file contents", func() {
-			err := dl.Download(TestingClientMod1, relFilePath)
+			_, err := dl.Download(TestingClientMod1, relFilePath)
 
 			Expect(err).To(BeNil())
 
@@ -55,11 +55,23 @@ var _ = Describe("Downloader", func() {
 			Expect(string(b)).To(Equal(content))
 		})
 
+		It("keeps a copy of the download in the cache", func() {
+			cache := &mc.JarCache{Fs: fs, Dir: "/cache"}
+			dl = mc.NewModDownloader(hc, mcfs, cache)
+
+			res, err := dl.Download(TestingClientMod1, relFilePath)
+
+			Expect(err).To(BeNil())
+			b, err := cache.Get(res.SHA256)
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal(content))
+		})
+
 		It("doesn't download if dirs can't be created", func() {
 			eg.Err = errors.New("this error won't be returned")
 			mcfs.Fs = afero.NewReadOnlyFs(fs)
 
-			err := dl.Download(TestingClientMod1, relFilePath)
+			_, err := dl.Download(TestingClientMod1, relFilePath)
 
 			Expect(err).To(Not(BeNil()))
 			Expect(err).To(Not(Equal(eg.Err)))
@@ -71,7 +83,7 @@ var _ = Describe("Downloader", func() {
 		It("doesn't write the file if the download fails", func() {
 			eg.Err = errors.New("bad url, or something. idk")
 
-			err := dl.Download(TestingClientMod1, relFilePath)
+			_, err := dl.Download(TestingClientMod1, relFilePath)
 
 			Expect(err).To(Equal(eg.Err))
 
@@ -89,13 +101,122 @@ var _ = Describe("Downloader", func() {
 				},
 			}
 
-			err := dl.Download(TestingClientMod1, relFilePath)
+			_, err := dl.Download(TestingClientMod1, relFilePath)
 
 			Expect(err).To(Not(BeNil()))
 
 			exists, _ := afero.Exists(fs, fullPath)
 			Expect(exists).To(BeFalse())
 		})
+
+		It("returns an error for HTTP error statuses", func() {
+			eg.Res = &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: rc}
+			hc.Getter = eg
+
+			_, err := dl.Download(TestingClientMod1, relFilePath)
+
+			Expect(err).ToNot(BeNil())
+
+			exists, _ := afero.Exists(fs, fullPath)
+			Expect(exists).To(BeFalse())
+		})
+
+		Context("mirrors", func() {
+			var mod *mc.Mod
+			var ug *urlGetter
+			mirror1 := "https://mirror_1/mod.jar"
+			mirror2 := "https://mirror_2/mod.jar"
+			// sha1 of "test"
+			contentSha1 := "a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"
+
+			BeforeEach(func() {
+				mod = &mc.Mod{
+					CliName:    "mirrored",
+					LatestURL:  "https://primary/mod.jar",
+					MirrorURLs: []string{mirror1, mirror2},
+					Hashes:     map[string]string{"sha1": contentSha1},
+				}
+				ug = &urlGetter{Contents: map[string]string{}}
+				hc.Getter = ug
+			})
+
+			It("records the primary URL when it succeeds", func() {
+				ug.Contents[mod.LatestURL] = content
+
+				res, err := dl.Download(mod, relFilePath)
+
+				Expect(err).To(BeNil())
+				Expect(res.URL).To(Equal(mod.LatestURL))
+				Expect(ug.Requested).To(Equal([]string{mod.LatestURL}))
+			})
+
+			It("fails over to mirrors in order", func() {
+				ug.Contents[mirror2] = content
+
+				res, err := dl.Download(mod, relFilePath)
+
+				Expect(err).To(BeNil())
+				Expect(res.URL).To(Equal(mirror2))
+				Expect(ug.Requested).To(Equal([]string{mod.LatestURL, mirror1, mirror2}))
+
+				b, _ := afero.ReadFile(fs, fullPath)
+				Expect(string(b)).To(Equal(content))
+			})
+
+			It("skips mirrors serving different content", func() {
+				ug.Contents[mirror1] = "not the same file"
+				ug.Contents[mirror2] = content
+
+				res, err := dl.Download(mod, relFilePath)
+
+				Expect(err).To(BeNil())
+				Expect(res.URL).To(Equal(mirror2))
+			})
+
+			It("doesn't use mirrors when there are no hashes to verify them", func() {
+				mod.Hashes = nil
+				ug.Contents[mirror1] = content
+
+				_, err := dl.Download(mod, relFilePath)
+
+				Expect(err).ToNot(BeNil())
+
+				exists, _ := afero.Exists(fs, fullPath)
+				Expect(exists).To(BeFalse())
+			})
+
+			It("returns an error when every URL fails", func() {
+				_, err := dl.Download(mod, relFilePath)
+
+				Expect(err).ToNot(BeNil())
+				Expect(ug.Requested).To(HaveLen(3))
+			})
+		})
+	})
+
+	Context("VerifyHashes", func() {
+		It("ignores unsupported algorithms", func() {
+			checked, err := mc.VerifyHashes([]byte("test"), map[string]string{"md5": "whatever"})
+
+			Expect(err).To(BeNil())
+			Expect(checked).To(Equal(0))
+		})
+
+		It("returns an error for mismatches", func() {
+			_, err := mc.VerifyHashes([]byte("test"), map[string]string{"sha256": "abc"})
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("counts the matching hashes", func() {
+			checked, err := mc.VerifyHashes([]byte("test"), map[string]string{
+				"sha1":   "a94a8fe5ccb19ba61c4c0873d391e987982fbbd3",
+				"SHA256": "9F86D081884C7D659A2FEAA0C55AD015A3BF4F1B2B0B822CD15D6C15B0F00A08",
+			})
+
+			Expect(err).To(BeNil())
+			Expect(checked).To(Equal(2))
+		})
 	})
 })
 
@@ -108,8 +229,11 @@ type emptyDownloader struct {
 	Err error
 }
 
-func (e emptyDownloader) Download(mod *mc.Mod, modFolder string) error {
-	return e.Err
+func (e emptyDownloader) Download(mod *mc.Mod, modFolder string) (*mc.DownloadResult, error) {
+	if e.Err != nil {
+		return nil, e.Err
+	}
+	return &mc.DownloadResult{URL: mod.LatestURL}, nil
 }
 
 // verify Download func args
@@ -118,10 +242,10 @@ type verifyingDownloader struct {
 	ExpectedMod  *mc.Mod
 }
 
-func (v verifyingDownloader) Download(mod *mc.Mod, modFolder string) error {
+func (v verifyingDownloader) Download(mod *mc.Mod, modFolder string) (*mc.DownloadResult, error) {
 	Expect(mod).To(Equal(v.ExpectedMod))
 	Expect(modFolder).To(Equal(v.ExpectedPath))
-	return nil
+	return &mc.DownloadResult{URL: mod.LatestURL}, nil
 }
 
 // count calls to the Download func
@@ -129,9 +253,9 @@ type countingDownloader struct {
 	CallCount int
 }
 
-func (c *countingDownloader) Download(mod *mc.Mod, modFolder string) error {
+func (c *countingDownloader) Download(mod *mc.Mod, modFolder string) (*mc.DownloadResult, error) {
 	c.CallCount++
-	return nil
+	return &mc.DownloadResult{URL: mod.LatestURL}, nil
 }
 
 // -----
@@ -160,6 +284,20 @@ func (v getURLVerifier) Get(url string) (*http.Response, error) {
 	return v.Res, v.Err
 }
 
+// serves content by URL, 404 for anything else, and records requested URLs
+type urlGetter struct {
+	Contents  map[string]string
+	Requested []string
+}
+
+func (g *urlGetter) Get(url string) (*http.Response, error) {
+	g.Requested = append(g.Requested, url)
+	if c, ok := g.Contents[url]; ok {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(c))}, nil
+	}
+	return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
 // runs a task when the download should happen to change some state during a test
 type emptyGetterWithTask struct {
 	emptyGetter
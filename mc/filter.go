@@ -64,12 +64,28 @@ func latestInstalled(mod *Mod, cfg *UserModConfig) bool {
 	installation, exists := cfg.ModInstallations[mod.CliName]
 
 	if exists {
-		latestInstalled = mod.LatestURL == installation.DownloadURL
+		latestInstalled = IsUpToDate(mod, installation)
 	}
 
 	return latestInstalled
 }
 
+// IsUpToDate returns true if the installation is the mod's latest package.
+// Local sources never change their path, so their content hash is compared
+// instead of the URL.
+func IsUpToDate(mod *Mod, installation ModInstallation) bool {
+	if !IsLocalSource(mod.LatestURL) {
+		return mod.LatestURL == installation.DownloadURL
+	}
+
+	if installation.SHA256 == "" {
+		return false
+	}
+
+	b, err := ReadLocalSource(mod.LatestURL)
+	return err == nil && HashSHA256(b) == installation.SHA256
+}
+
 func toSet(s []string) map[string]bool {
 	set := map[string]bool{}
 
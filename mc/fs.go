@@ -1,14 +1,22 @@
 package mc
 
 import (
+	"fmt"
 	"io"
+	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/spf13/afero"
 )
 
-// GetInstallPath reads the install path set in Viper.
+// GetInstallPath returns the install path of the profile in use, or else
+// reads the install path set in Viper.
 func GetInstallPath() string {
+	if currentProfile != nil {
+		return currentProfile.InstallPath
+	}
 	return ViperInstance.GetString(InstallPathKey)
 }
 
@@ -18,46 +26,155 @@ type FileSystem interface {
 	WriteFile(r io.Reader, relPath string) error
 	ReadFile(relPath string) ([]byte, error)
 	MkDirAll(relPath string) error
+	ReadDir(relPath string) ([]FileInfo, error)
+	Stat(relPath string) (FileInfo, error)
+	Remove(relPath string) error
+
+	// Rename moves the file at the old path to the new path, replacing any
+	// file there
+	Rename(oldRelPath, newRelPath string) error
 	Close()
 }
 
+// FileInfo describes a file or directory on a FileSystem
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+func newFileInfo(fi os.FileInfo) FileInfo {
+	return FileInfo{
+		Name:    fi.Name(),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		IsDir:   fi.IsDir(),
+	}
+}
+
+func sortFileInfos(infos []FileInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+}
+
+// canReplace returns true if a failed rename can be retried by deleting the
+// file at the new path first: the file to move and the one in its way both
+// exist. Any other failure is returned, so nothing is deleted for nothing.
+func canReplace(fs FileSystem, oldRelPath, newRelPath string) bool {
+	if _, err := fs.Stat(oldRelPath); err != nil {
+		return false
+	}
+	_, err := fs.Stat(newRelPath)
+	return err == nil
+}
+
 // LocalFileSystem reads and writes to the file system using afero.
 type LocalFileSystem struct {
 	Fs afero.Fs
+
+	// Dir is the Minecraft directory paths are relative to. Defaults to the
+	// install path if it's empty.
+	Dir string
 }
 
 // WriteFile writes the bytes to the relative path under the install directory.
 // The file is given 0644 perms.
 func (l LocalFileSystem) WriteFile(r io.Reader, relPath string) error {
-	return afero.WriteReader(l.Fs, filepath.Join(GetInstallPath(), relPath), r)
+	return afero.WriteReader(l.Fs, l.localPath(relPath), r)
 }
 
 // ReadFile reads the given relative path under the install directory.
 func (l LocalFileSystem) ReadFile(relPath string) ([]byte, error) {
-	return afero.ReadFile(l.Fs, filepath.Join(GetInstallPath(), relPath))
+	return afero.ReadFile(l.Fs, l.localPath(relPath))
 }
 
 // MkDirAll creates all non-existant folders in the given path with 0755 perms
 func (l LocalFileSystem) MkDirAll(relPath string) error {
-	return l.Fs.MkdirAll(filepath.Join(GetInstallPath(), relPath), 0755)
+	return l.Fs.MkdirAll(l.localPath(relPath), 0755)
+}
+
+// ReadDir lists the directory at the relative path under the install
+// directory, sorted by name.
+func (l LocalFileSystem) ReadDir(relPath string) ([]FileInfo, error) {
+	fis, err := afero.ReadDir(l.Fs, l.localPath(relPath))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]FileInfo, 0, len(fis))
+	for _, fi := range fis {
+		infos = append(infos, newFileInfo(fi))
+	}
+	sortFileInfos(infos)
+	return infos, nil
+}
+
+// Stat describes the file at the relative path under the install directory.
+func (l LocalFileSystem) Stat(relPath string) (FileInfo, error) {
+	fi, err := l.Fs.Stat(l.localPath(relPath))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return newFileInfo(fi), nil
+}
+
+// Remove deletes the file at the relative path under the install directory.
+func (l LocalFileSystem) Remove(relPath string) error {
+	return l.Fs.Remove(l.localPath(relPath))
+}
+
+// Rename moves the file at the old relative path under the install directory
+// to the new one.
+func (l LocalFileSystem) Rename(oldRelPath, newRelPath string) error {
+	return l.Fs.Rename(l.localPath(oldRelPath), l.localPath(newRelPath))
 }
 
 // Close is a no-op for the local file system
 func (l LocalFileSystem) Close() {}
 
-// NewFs creates an FTPFileSystem if the args indicate FTP, or else
-// a LocalFileSystem
+func (l LocalFileSystem) localPath(relPath string) string {
+	dir := l.Dir
+	if dir == "" {
+		dir = GetInstallPath()
+	}
+	return filepath.Join(dir, relPath)
+}
+
+// NewFs creates an FTPFileSystem, SFTPFileSystem or PterodactylFileSystem if
+// there are args for the server, or else a LocalFileSystem
 func NewFs(ftpArgs *FTPArgs) (FileSystem, error) {
-	var fs FileSystem
-	if ftpArgs != nil {
-		conn, err := openFTPToServer(ftpArgs)
-		if err != nil {
-			return nil, err
-		}
-		fs = &FTPFileSystem{Connection: conn}
-	} else {
-		fs = &LocalFileSystem{Fs: afero.NewOsFs()}
-	}
-
-	return fs, nil
+	if ftpArgs == nil {
+		return &LocalFileSystem{Fs: afero.NewOsFs()}, nil
+	}
+
+	switch ftpArgs.GetProtocol() {
+	case FTPProtocol:
+		return openFTPFileSystem(ftpArgs)
+	case SFTPProtocol:
+		return openSFTPToServer(ftpArgs)
+	case PterodactylProtocol:
+		return openPterodactylToServer(ftpArgs)
+	}
+	return nil, fmt.Errorf("unknown protocol: %s (use %s, %s or %s)", ftpArgs.Protocol, FTPProtocol, SFTPProtocol, PterodactylProtocol)
+}
+
+// openFTPFileSystem connects to the server over FTP, reconnecting with the
+// same args when the connection is lost
+func openFTPFileSystem(ftpArgs *FTPArgs) (FileSystem, error) {
+	conn, err := openFTPToServer(ftpArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	ftpFs := &FTPFileSystem{
+		Connection: conn,
+		BaseDir:    ftpArgs.BaseDir,
+		Reconnect: func() (FTPConnection, error) {
+			return openFTPToServer(ftpArgs)
+		},
+	}
+	ftpFs.StartKeepAlive(ftpArgs.KeepAlive)
+	return ftpFs, nil
 }
@@ -2,7 +2,9 @@ package mc_test
 
 import (
 	"bytes"
+	"errors"
 	"mcmods/mc"
+	"os"
 	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
@@ -72,6 +74,57 @@ var _ = Describe("Local File System", func() {
 		})
 	})
 
+	Context("ReadDir", func() {
+		It("lists the directory sorted by name", func() {
+			Expect(aferoMemMap.MkdirAll(filepath.Join(mcInstallLoc, mc.ModFolderName, "sub"), 0755)).To(BeNil())
+			Expect(afero.WriteFile(aferoMemMap, fullPath, expectedBytes, 0644)).To(BeNil())
+
+			infos, err := fs.ReadDir(mc.ModFolderName)
+
+			Expect(err).To(BeNil())
+			Expect(infos).To(HaveLen(2))
+			Expect(infos[0].Name).To(Equal("sub"))
+			Expect(infos[0].IsDir).To(BeTrue())
+			Expect(infos[1].Name).To(Equal("test.txt"))
+			Expect(infos[1].Size).To(Equal(int64(len(expectedBytes))))
+		})
+	})
+
+	Context("Stat", func() {
+		It("describes the file", func() {
+			Expect(afero.WriteFile(aferoMemMap, fullPath, expectedBytes, 0644)).To(BeNil())
+
+			fi, err := fs.Stat(relPath)
+
+			Expect(err).To(BeNil())
+			Expect(fi.Name).To(Equal("test.txt"))
+			Expect(fi.Size).To(Equal(int64(len(expectedBytes))))
+			Expect(fi.IsDir).To(BeFalse())
+			Expect(fi.ModTime.IsZero()).To(BeFalse())
+		})
+
+		It("returns os.ErrNotExist for missing files", func() {
+			_, err := fs.Stat(relPath)
+
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+	})
+
+	Context("Rename", func() {
+		It("replaces the file at the new path", func() {
+			newPath := mc.ModFolderName + "/renamed.txt"
+			Expect(afero.WriteFile(aferoMemMap, fullPath, expectedBytes, 0644)).To(BeNil())
+			Expect(afero.WriteFile(aferoMemMap, mcInstallLoc+"/"+newPath, []byte("old"), 0644)).To(BeNil())
+
+			Expect(fs.Rename(relPath, newPath)).To(BeNil())
+
+			readBytes, _ := afero.ReadFile(aferoMemMap, mcInstallLoc+"/"+newPath)
+			Expect(string(readBytes)).To(Equal(fileContent))
+			exists, _ := afero.Exists(aferoMemMap, fullPath)
+			Expect(exists).To(BeFalse())
+		})
+	})
+
 	Context("Close", func() {
 		It("does nothing", func() {
 			fs.Close()
@@ -1,19 +1,55 @@
 package mc
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/textproto"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/jlaffaye/ftp"
 )
 
+const (
+	// FTPProtocol is the protocol name for plain FTP
+	FTPProtocol = "ftp"
+
+	// SFTPProtocol is the protocol name for SFTP (over SSH)
+	SFTPProtocol = "sftp"
+
+	// SFTPScheme is the URL scheme which selects SFTP for a server
+	SFTPScheme = "sftp://"
+
+	// FTPModeKey - The key of the FTP data connection mode
+	FTPModeKey = "ftpMode"
+
+	// FTPKeepAliveKey - The key of the interval between FTP keepalives
+	FTPKeepAliveKey = "ftpKeepAlive"
+
+	// FTPModeEPSV opens data connections with EPSV, falling back to PASV if
+	// the server doesn't support it
+	FTPModeEPSV = "epsv"
+
+	// FTPModePASV opens data connections with PASV only
+	FTPModePASV = "pasv"
+
+	// FTPModeActive has the server open data connections to this machine,
+	// with PORT or EPRT. Not available with TLS.
+	FTPModeActive = "active"
+
+	// DefaultFTPKeepAlive is the interval between keepalives when none is set
+	DefaultFTPKeepAlive = 30 * time.Second
+)
+
 var (
 	// FTPDial is the function called when
 	FTPDial func(server string, opts ...ftp.DialOption) (FTPConnection, error) = liveFTPDial
@@ -25,40 +61,72 @@ type FTPConnection interface {
 	Stor(path string, r io.Reader) error
 	Retr(path string) (*ftp.Response, error)
 	MakeDir(dir string) error
+	List(path string) ([]*ftp.Entry, error)
+	Delete(path string) error
+	Rename(from, to string) error
+	NoOp() error
 	Quit() error
 }
 
 // FTPFileSystem is used to interact with Minecraft FTP servers to maintain mod
-// installations.
+// installations. Operations are serialized, since an FTP connection can only
+// run one command at a time.
 type FTPFileSystem struct {
 	Connection FTPConnection
+
+	// BaseDir is the Minecraft directory on the server. Relative to the login
+	// directory unless absolute. Paths are relative to the FTP root if it's
+	// empty.
+	BaseDir string
+
+	// Reconnect dials and logs in again when the connection is lost, after
+	// which the interrupted operation is retried once. Nil disables it.
+	Reconnect func() (FTPConnection, error)
+
+	mu       sync.Mutex
+	lastUsed time.Time
+	stop     chan struct{}
 }
 
 // WriteFile writes the bytes over FTP to the given path on the server.
-func (f FTPFileSystem) WriteFile(r io.Reader, relPath string) error {
-	return f.Connection.Stor(fixPathForFTP(relPath), r)
+func (f *FTPFileSystem) WriteFile(r io.Reader, relPath string) error {
+	// buffered so the file can be sent again after reconnecting
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return f.do(func(c FTPConnection) error {
+		return c.Stor(f.ftpPath(relPath), bytes.NewReader(b))
+	})
 }
 
 // ReadFile reads the bytes of the given path over FTP.
-func (f FTPFileSystem) ReadFile(relPath string) ([]byte, error) {
-	protoErr := &textproto.Error{}
-	r, err := f.Connection.Retr(fixPathForFTP(relPath))
-	if err == nil {
-		defer r.Close()
-		return ioutil.ReadAll(r) // hard to unit test the happy path due to the library's architecture :(
-	} else if errors.As(err, &protoErr) {
-		if protoErr.Code == ftp.StatusFileUnavailable {
-			return nil, os.ErrNotExist
+func (f *FTPFileSystem) ReadFile(relPath string) ([]byte, error) {
+	var b []byte
+	err := f.do(func(c FTPConnection) error {
+		r, err := c.Retr(f.ftpPath(relPath))
+		if err != nil {
+			return err
 		}
+		defer r.Close()
+		b, err = ioutil.ReadAll(r) // hard to unit test the happy path due to the library's architecture :(
+		return err
+	})
+	if err != nil {
+		return nil, ftpNotExist(err)
 	}
-	return nil, err
+	return b, nil
 }
 
 // MkDirAll creates all non-existant folders in the given path.
-func (f FTPFileSystem) MkDirAll(relPath string) error {
+func (f *FTPFileSystem) MkDirAll(relPath string) error {
 	protoErr := &textproto.Error{}
-	for _, dir := range GetRecursiveDirs(fixPathForFTP(relPath)) {
-		if err := f.Connection.MakeDir(dir); err != nil {
+	for _, dir := range GetRecursiveDirs(f.ftpPath(relPath)) {
+		err := f.do(func(c FTPConnection) error {
+			return c.MakeDir(dir)
+		})
+		if err != nil {
 			if errors.As(err, &protoErr) {
 				if protoErr.Code == ftp.StatusFileUnavailable {
 					continue
@@ -70,9 +138,156 @@ func (f FTPFileSystem) MkDirAll(relPath string) error {
 	return nil
 }
 
-// Close calls Quit on the ftp connection
-func (f FTPFileSystem) Close() {
+// ReadDir lists the directory at the given path over FTP, sorted by name. The
+// listing uses MLSD if the server supports it, or else parses LIST output.
+func (f *FTPFileSystem) ReadDir(relPath string) ([]FileInfo, error) {
+	return f.list(f.ftpPath(relPath))
+}
+
+func (f *FTPFileSystem) list(ftpPath string) ([]FileInfo, error) {
+	var entries []*ftp.Entry
+	err := f.do(func(c FTPConnection) (err error) {
+		entries, err = c.List(ftpPath)
+		return err
+	})
+	if err != nil {
+		return nil, ftpNotExist(err)
+	}
+
+	infos := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.Name == "." || e.Name == ".." {
+			continue
+		}
+		infos = append(infos, FileInfo{
+			Name:    e.Name,
+			Size:    int64(e.Size),
+			ModTime: e.Time,
+			IsDir:   e.Type == ftp.EntryTypeFolder,
+		})
+	}
+	sortFileInfos(infos)
+	return infos, nil
+}
+
+// Stat describes the file at the given path by finding it in the listing of
+// its parent directory, since not all servers support MLST, SIZE and MDTM.
+func (f *FTPFileSystem) Stat(relPath string) (FileInfo, error) {
+	p := f.ftpPath(relPath)
+	if p == "/" || p == "/." {
+		return FileInfo{Name: "/", IsDir: true}, nil
+	}
+
+	infos, err := f.list(path.Dir(p))
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	name := path.Base(p)
+	for _, fi := range infos {
+		if fi.Name == name {
+			return fi, nil
+		}
+	}
+	return FileInfo{}, os.ErrNotExist
+}
+
+// Remove deletes the file at the given path over FTP.
+func (f *FTPFileSystem) Remove(relPath string) error {
+	err := f.do(func(c FTPConnection) error {
+		return c.Delete(f.ftpPath(relPath))
+	})
+	return ftpNotExist(err)
+}
+
+// Rename moves the file at the given path over FTP. Servers which won't
+// replace an existing file have it deleted first.
+func (f *FTPFileSystem) Rename(oldRelPath, newRelPath string) error {
+	from, to := f.ftpPath(oldRelPath), f.ftpPath(newRelPath)
+	err := f.do(func(c FTPConnection) error {
+		return c.Rename(from, to)
+	})
+	if err == nil || !canReplace(f, oldRelPath, newRelPath) {
+		return ftpNotExist(err)
+	}
+
+	err = f.do(func(c FTPConnection) error {
+		if err := c.Delete(to); err != nil {
+			return err
+		}
+		return c.Rename(from, to)
+	})
+	return ftpNotExist(err)
+}
+
+// Close stops the keepalives and calls Quit on the ftp connection
+func (f *FTPFileSystem) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.stop != nil {
+		close(f.stop)
+		f.stop = nil
+	}
+	f.Connection.Quit()
+}
+
+// StartKeepAlive sends a NOOP whenever the connection has been idle for the
+// interval, e.g. while mods are downloaded, so the server doesn't drop it.
+// Stopped by Close.
+func (f *FTPFileSystem) StartKeepAlive(interval time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if interval <= 0 || f.stop != nil {
+		return
+	}
+	f.lastUsed = time.Now()
+	f.stop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				f.mu.Lock()
+				if f.stop == stop && time.Since(f.lastUsed) >= interval {
+					// errors are left for the next operation, which reconnects
+					f.Connection.NoOp()
+					f.lastUsed = time.Now()
+				}
+				f.mu.Unlock()
+			}
+		}
+	}(f.stop)
+}
+
+// do runs the operation on the connection. If the connection was lost, it
+// reconnects and runs the operation again.
+func (f *FTPFileSystem) do(op func(c FTPConnection) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	defer func() { f.lastUsed = time.Now() }()
+
+	err := op(f.Connection)
+	if err == nil || f.Reconnect == nil || !ftpConnectionLost(err) {
+		return err
+	}
+
+	fmt.Printf("FTP connection lost (%v), reconnecting\n", err)
 	f.Connection.Quit()
+
+	conn, reconnectErr := f.Reconnect()
+	if reconnectErr != nil {
+		return fmt.Errorf("reconnecting after %v: %w", err, reconnectErr)
+	}
+	f.Connection = conn
+
+	return op(conn)
 }
 
 // GetRecursiveDirs returns a slice of paths to each subdirectory in the dir
@@ -96,12 +311,63 @@ func GetRecursiveDirs(dir string) []string {
 	return dirs
 }
 
-// FTPArgs represents the information necessary to connect to FTP servers
+// FTPArgs represents the information necessary to connect to FTP and SFTP
+// servers
 type FTPArgs struct {
+	// Remote is the name of the remote the args are for, if any
+	Remote string
+
 	Server    string
 	User      string
 	Pw        string
 	TimeoutMs uint
+
+	// Protocol is FTPProtocol, SFTPProtocol or PterodactylProtocol. If empty,
+	// it's determined by the server's URL scheme, defaulting to FTP. For the
+	// panel, the server is the panel's URL, the user is the server identifier
+	// and the password is an API key.
+	Protocol string
+
+	// KeyFile is the path to a private key used to log in over SFTP
+	KeyFile string
+
+	// KnownHostsFile is used to verify SFTP servers. Defaults to the user's
+	// ~/.ssh/known_hosts
+	KnownHostsFile string
+
+	// BaseDir is the Minecraft directory on the server, which contains the mods
+	// folder. Paths are relative to the login directory if it's empty.
+	BaseDir string
+
+	// TLSMode is FTPTLSNone, FTPTLSExplicit or FTPTLSImplicit. If empty, it's
+	// determined by the server's URL scheme, defaulting to none.
+	TLSMode string
+
+	// TLSFingerprint pins the SHA-256 fingerprint of the FTP server's
+	// certificate, which is trusted instead of verifying it against the CAs
+	TLSFingerprint string
+
+	// TLSSkipVerify accepts any FTP server certificate. Insecure.
+	TLSSkipVerify bool
+
+	// Mode is the FTP data connection mode, FTPModeEPSV (default),
+	// FTPModePASV or FTPModeActive
+	Mode string
+
+	// KeepAlive is the interval between NOOPs sent on an idle FTP connection.
+	// Zero disables them.
+	KeepAlive time.Duration
+}
+
+// GetProtocol returns the protocol to connect to the server with
+func (a FTPArgs) GetProtocol() string {
+	if a.Protocol != "" {
+		return strings.ToLower(a.Protocol)
+	}
+	if strings.HasPrefix(strings.ToLower(a.Server), SFTPScheme) {
+		return SFTPProtocol
+	}
+	return FTPProtocol
 }
 
 func openFTPToServer(args *FTPArgs) (FTPConnection, error) {
@@ -109,11 +375,28 @@ func openFTPToServer(args *FTPArgs) (FTPConnection, error) {
 		return nil, errors.New("FTP access requires a username, password, and server")
 	}
 
-	fmt.Printf("Connecting FTP to %s\n", args.Server)
+	opts := []ftp.DialOption{ftp.DialWithTimeout(time.Duration(args.TimeoutMs) * time.Millisecond)}
 
-	timeoutOpt := ftp.DialWithTimeout(time.Duration(args.TimeoutMs) * time.Millisecond)
+	tlsOpts, err := ftpTLSDialOptions(args)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, tlsOpts...)
 
-	ftpConnection, err := FTPDial(args.Server, timeoutOpt)
+	modeOpts, err := ftpModeDialOptions(args)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, modeOpts...)
+
+	server := ftpDialAddress(args)
+	if len(tlsOpts) > 0 {
+		fmt.Printf("Connecting FTP to %s (%s TLS)\n", server, args.GetFTPTLSMode())
+	} else {
+		fmt.Printf("Connecting FTP to %s\n", server)
+	}
+
+	ftpConnection, err := FTPDial(server, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -128,6 +411,60 @@ func openFTPToServer(args *FTPArgs) (FTPConnection, error) {
 	return ftpConnection, nil
 }
 
+// ftpModeDialOptions returns the dial options for the data connection mode
+func ftpModeDialOptions(args *FTPArgs) ([]ftp.DialOption, error) {
+	switch strings.ToLower(args.Mode) {
+	case "", FTPModeEPSV:
+		return nil, nil
+	case FTPModePASV:
+		return []ftp.DialOption{ftp.DialWithDisabledEPSV(true)}, nil
+	case FTPModeActive:
+		// the dialer sees the commands on the control connection, which it
+		// can't with TLS
+		if args.GetFTPTLSMode() != FTPTLSNone {
+			return nil, errors.New("the active FTP mode can't be used with TLS")
+		}
+		d := newActiveDialer(time.Duration(args.TimeoutMs) * time.Millisecond)
+		return []ftp.DialOption{ftp.DialWithDisabledEPSV(true), ftp.DialWithDialFunc(d.dial)}, nil
+	}
+	return nil, fmt.Errorf("unknown FTP mode: %s (use %s, %s or %s)", args.Mode, FTPModeEPSV, FTPModePASV, FTPModeActive)
+}
+
+// ftpConnectionLost returns true if the error means the control connection was
+// closed, as opposed to the server rejecting the command
+func ftpConnectionLost(err error) bool {
+	protoErr := &textproto.Error{}
+	if errors.As(err, &protoErr) {
+		return protoErr.Code == ftp.StatusNotAvailable
+	}
+
+	netErr := (net.Error)(nil)
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr)
+}
+
+// ftpNotExist converts the FTP file unavailable error to os.ErrNotExist
+func ftpNotExist(err error) error {
+	protoErr := &textproto.Error{}
+	if errors.As(err, &protoErr) && protoErr.Code == ftp.StatusFileUnavailable {
+		return os.ErrNotExist
+	}
+	return err
+}
+
+// ftpPath returns the path on the server. Relative base dirs are left
+// relative, so the server resolves them against the login directory.
+func (f *FTPFileSystem) ftpPath(relPath string) string {
+	if f.BaseDir == "" {
+		return path.Clean(fixPathForFTP(relPath))
+	}
+	return path.Join(filepath.ToSlash(f.BaseDir), fixPathForFTP(relPath))
+}
+
 func fixPathForFTP(path string) string {
 	ps := string(os.PathSeparator)
 	return "/" + strings.ReplaceAll(path, ps, "/")
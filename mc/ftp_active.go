@@ -0,0 +1,227 @@
+package mc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// activeDialer gives the FTP client an active (PORT) mode, which it doesn't
+// have. The client's PASV commands are swapped on the control connection for
+// PORT, or EPRT over IPv6, naming a listener opened for the transfer, and the
+// client is told to connect to an address which stands for the listener. Its
+// connection to that address is the one the server opens to the listener.
+type activeDialer struct {
+	timeout time.Duration
+
+	mu sync.Mutex
+	// pending are the data connections not yet dialed by the client, by the
+	// address it was told to connect to
+	pending map[string]*activeDataConn
+}
+
+func newActiveDialer(timeout time.Duration) *activeDialer {
+	return &activeDialer{timeout: timeout, pending: map[string]*activeDataConn{}}
+}
+
+// dial opens the control connection, or returns the data connection for the
+// address given in a PASV reply
+func (d *activeDialer) dial(network, address string) (net.Conn, error) {
+	d.mu.Lock()
+	data, ok := d.pending[address]
+	delete(d.pending, address)
+	d.mu.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	dialer := net.Dialer{Timeout: d.timeout}
+	conn, err := dialer.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return &activeControlConn{Conn: conn, d: d}, nil
+}
+
+// activeControlConn is the control connection, which swaps PASV for PORT
+type activeControlConn struct {
+	net.Conn
+	d *activeDialer
+
+	// reply is read by the client before anything else from the server
+	reply []byte
+}
+
+func (c *activeControlConn) Read(b []byte) (int, error) {
+	if len(c.reply) > 0 {
+		n := copy(b, c.reply)
+		c.reply = c.reply[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+// Write sends the commands of the client. They're written a line at a time.
+func (c *activeControlConn) Write(b []byte) (int, error) {
+	if !strings.EqualFold(strings.TrimSpace(string(b)), "PASV") {
+		return c.Conn.Write(b)
+	}
+
+	reply, err := c.port()
+	if err != nil {
+		return 0, err
+	}
+	c.reply = reply
+	return len(b), nil
+}
+
+// port opens a listener for the next transfer and sends its address to the
+// server. Returns the reply for the client's PASV: the server's reply if it
+// refused the address, or one with the address standing for the listener.
+func (c *activeControlConn) port() ([]byte, error) {
+	local := c.LocalAddr().(*net.TCPAddr)
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: local.IP})
+	if err != nil {
+		return nil, err
+	}
+	addr := l.Addr().(*net.TCPAddr)
+
+	var cmd string
+	if ip := addr.IP.To4(); ip != nil {
+		cmd = fmt.Sprintf("PORT %d,%d,%d,%d,%d,%d\r\n", ip[0], ip[1], ip[2], ip[3], addr.Port/256, addr.Port%256)
+	} else {
+		cmd = fmt.Sprintf("EPRT |2|%s|%d|\r\n", addr.IP, addr.Port)
+	}
+	if _, err = c.Conn.Write([]byte(cmd)); err != nil {
+		l.Close()
+		return nil, err
+	}
+	reply, err := readFTPReply(c.Conn)
+	if err != nil || !bytes.HasPrefix(reply, []byte("200")) {
+		l.Close()
+		return reply, err
+	}
+
+	// the address only has to find the listener, and PASV replies can't hold
+	// IPv6 addresses, so it's a loopback one with the listener's port
+	c.d.mu.Lock()
+	c.d.pending[net.JoinHostPort("127.0.0.1", strconv.Itoa(addr.Port))] = &activeDataConn{l: l, timeout: c.d.timeout}
+	c.d.mu.Unlock()
+	return []byte(fmt.Sprintf("227 Entering Passive Mode (127,0,0,1,%d,%d).\r\n", addr.Port/256, addr.Port%256)), nil
+}
+
+// readFTPReply reads one reply, which may span lines, from the server. It's
+// read a byte at a time so nothing after it is taken from the client.
+func readFTPReply(r io.Reader) ([]byte, error) {
+	var reply, line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		line = append(line, b[0])
+		if b[0] != '\n' {
+			continue
+		}
+
+		reply = append(reply, line...)
+		if len(line) < 4 || bytes.Equal(line[:3], reply[:3]) && line[3] != '-' {
+			return reply, nil
+		}
+		line = line[:0]
+	}
+}
+
+// activeDataConn is the connection the server opens to the listener. It's
+// accepted when the client first uses it, after sending the transfer command.
+type activeDataConn struct {
+	l       *net.TCPListener
+	timeout time.Duration
+
+	once sync.Once
+	conn net.Conn
+	err  error
+}
+
+func (c *activeDataConn) accept() error {
+	c.once.Do(func() {
+		if c.timeout > 0 {
+			c.l.SetDeadline(time.Now().Add(c.timeout))
+		}
+		c.conn, c.err = c.l.Accept()
+		c.l.Close()
+	})
+	return c.err
+}
+
+// Handshake is called by the client when nothing is written for an upload, so
+// the server's connection is still accepted for empty files
+func (c *activeDataConn) Handshake() error {
+	return c.accept()
+}
+
+func (c *activeDataConn) Read(b []byte) (int, error) {
+	if err := c.accept(); err != nil {
+		return 0, err
+	}
+	return c.conn.Read(b)
+}
+
+func (c *activeDataConn) Write(b []byte) (int, error) {
+	if err := c.accept(); err != nil {
+		return 0, err
+	}
+	return c.conn.Write(b)
+}
+
+// Close closes the listener, and the connection if the server opened it
+func (c *activeDataConn) Close() error {
+	c.l.Close()
+	c.once.Do(func() {
+		c.err = net.ErrClosed
+	})
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+func (c *activeDataConn) LocalAddr() net.Addr {
+	if c.conn != nil {
+		return c.conn.LocalAddr()
+	}
+	return c.l.Addr()
+}
+
+func (c *activeDataConn) RemoteAddr() net.Addr {
+	if c.conn != nil {
+		return c.conn.RemoteAddr()
+	}
+	return c.l.Addr()
+}
+
+func (c *activeDataConn) SetDeadline(t time.Time) error {
+	if err := c.accept(); err != nil {
+		return err
+	}
+	return c.conn.SetDeadline(t)
+}
+
+func (c *activeDataConn) SetReadDeadline(t time.Time) error {
+	if err := c.accept(); err != nil {
+		return err
+	}
+	return c.conn.SetReadDeadline(t)
+}
+
+func (c *activeDataConn) SetWriteDeadline(t time.Time) error {
+	if err := c.accept(); err != nil {
+		return err
+	}
+	return c.conn.SetWriteDeadline(t)
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"io/ioutil"
 	"mcmods/mc"
 	"net/textproto"
 	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"github.com/jlaffaye/ftp"
 	. "github.com/onsi/ginkgo"
@@ -131,6 +135,26 @@ var _ = Describe("FTP File System", func() {
 				Expect(called).To(BeTrue())
 			})
 
+			It("prefixes the path with the base dir, relative to the login directory", func() {
+				ftpFs.BaseDir = "servers/survival"
+				mock.StorFunc = func(path string, r io.Reader) error {
+					Expect(path).To(Equal("servers/survival/mods/a.jar"))
+					return nil
+				}
+
+				Expect(ftpFs.WriteFile(r, filepath.Join(mc.ModFolderName, "a.jar"))).To(BeNil())
+			})
+
+			It("keeps absolute base dirs absolute", func() {
+				ftpFs.BaseDir = "/srv/survival"
+				mock.StorFunc = func(path string, r io.Reader) error {
+					Expect(path).To(Equal("/srv/survival/mods/a.jar"))
+					return nil
+				}
+
+				Expect(ftpFs.WriteFile(r, filepath.Join(mc.ModFolderName, "a.jar"))).To(BeNil())
+			})
+
 			It("returns errors from stor", func() {
 				storErr := errors.New("stor error")
 				relPath := "path/to/file.txt"
@@ -226,6 +250,134 @@ var _ = Describe("FTP File System", func() {
 			})
 		})
 
+		Context("ReadDir", func() {
+			modTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			It("lists the directory, sorted by name, without . and ..", func() {
+				mock.ListFunc = func(path string) ([]*ftp.Entry, error) {
+					Expect(path).To(Equal("/mods"))
+					return []*ftp.Entry{
+						{Name: ".", Type: ftp.EntryTypeFolder},
+						{Name: "..", Type: ftp.EntryTypeFolder},
+						{Name: "b.jar", Size: 12, Time: modTime},
+						{Name: "a", Type: ftp.EntryTypeFolder},
+					}, nil
+				}
+
+				infos, err := ftpFs.ReadDir(mc.ModFolderName)
+
+				Expect(err).To(BeNil())
+				Expect(infos).To(Equal([]mc.FileInfo{
+					{Name: "a", IsDir: true},
+					{Name: "b.jar", Size: 12, ModTime: modTime},
+				}))
+			})
+
+			It("returns os.ErrNotExist for missing directories", func() {
+				mock.ListFunc = func(path string) ([]*ftp.Entry, error) {
+					return nil, &textproto.Error{Code: ftp.StatusFileUnavailable}
+				}
+
+				_, err := ftpFs.ReadDir("missing")
+
+				Expect(err).To(Equal(os.ErrNotExist))
+			})
+
+			It("returns other errors", func() {
+				listErr := errors.New("list err")
+				mock.ListFunc = func(path string) ([]*ftp.Entry, error) {
+					return nil, listErr
+				}
+
+				_, err := ftpFs.ReadDir(mc.ModFolderName)
+
+				Expect(err).To(Equal(listErr))
+			})
+		})
+
+		Context("Stat", func() {
+			BeforeEach(func() {
+				mock.ListFunc = func(path string) ([]*ftp.Entry, error) {
+					Expect(path).To(Equal("/mods"))
+					return []*ftp.Entry{{Name: "a.jar", Size: 34}}, nil
+				}
+			})
+
+			It("finds the file in its parent directory", func() {
+				fi, err := ftpFs.Stat(filepath.Join(mc.ModFolderName, "a.jar"))
+
+				Expect(err).To(BeNil())
+				Expect(fi).To(Equal(mc.FileInfo{Name: "a.jar", Size: 34}))
+			})
+
+			It("returns os.ErrNotExist for missing files", func() {
+				_, err := ftpFs.Stat(filepath.Join(mc.ModFolderName, "b.jar"))
+
+				Expect(err).To(Equal(os.ErrNotExist))
+			})
+
+			It("describes the root as a directory without listing", func() {
+				fi, err := ftpFs.Stat("")
+
+				Expect(err).To(BeNil())
+				Expect(fi.IsDir).To(BeTrue())
+			})
+		})
+
+		Context("Rename", func() {
+			It("renames the file", func() {
+				mock.RenameFunc = func(from, to string) error {
+					Expect(from).To(Equal("/mods/a.tmp"))
+					Expect(to).To(Equal("/mods/a.json"))
+					return nil
+				}
+				mock.DeleteFunc = func(path string) error {
+					Fail("nothing should be deleted")
+					return nil
+				}
+
+				Expect(ftpFs.Rename(filepath.Join(mc.ModFolderName, "a.tmp"), filepath.Join(mc.ModFolderName, "a.json"))).To(BeNil())
+			})
+
+			It("deletes the file at the new path when the server won't replace it", func() {
+				deleted := false
+				mock.RenameFunc = func(from, to string) error {
+					if !deleted {
+						return &textproto.Error{Code: ftp.StatusFileUnavailable}
+					}
+					return nil
+				}
+				mock.ListFunc = func(path string) ([]*ftp.Entry, error) {
+					return []*ftp.Entry{{Name: "a.json"}, {Name: "a.tmp"}}, nil
+				}
+				mock.DeleteFunc = func(path string) error {
+					Expect(path).To(Equal("/mods/a.json"))
+					deleted = true
+					return nil
+				}
+
+				Expect(ftpFs.Rename(filepath.Join(mc.ModFolderName, "a.tmp"), filepath.Join(mc.ModFolderName, "a.json"))).To(BeNil())
+				Expect(deleted).To(BeTrue())
+			})
+
+			It("doesn't delete the file at the new path when the file to move is missing", func() {
+				mock.RenameFunc = func(from, to string) error {
+					return &textproto.Error{Code: ftp.StatusFileUnavailable}
+				}
+				mock.ListFunc = func(path string) ([]*ftp.Entry, error) {
+					return []*ftp.Entry{{Name: "a.json"}}, nil
+				}
+				mock.DeleteFunc = func(path string) error {
+					Fail("nothing should be deleted")
+					return nil
+				}
+
+				err := ftpFs.Rename(filepath.Join(mc.ModFolderName, "a.tmp"), filepath.Join(mc.ModFolderName, "a.json"))
+
+				Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+			})
+		})
+
 		Context("Close", func() {
 			It("calls Quit", func() {
 				called := false
@@ -239,6 +391,96 @@ var _ = Describe("FTP File System", func() {
 				Expect(called).To(BeTrue())
 			})
 		})
+
+		Context("reconnecting", func() {
+			var reconnected *mockFTP
+			var reconnects int
+
+			BeforeEach(func() {
+				reconnected = emptyMock()
+				reconnects = 0
+				ftpFs.Reconnect = func() (mc.FTPConnection, error) {
+					reconnects++
+					return reconnected, nil
+				}
+			})
+
+			It("reconnects and retries stor with the same content when the connection is lost", func() {
+				mock.StorFunc = func(path string, r io.Reader) error {
+					ioutil.ReadAll(r)
+					return io.EOF
+				}
+				var stored []byte
+				reconnected.StorFunc = func(path string, r io.Reader) error {
+					stored, _ = ioutil.ReadAll(r)
+					return nil
+				}
+
+				err := ftpFs.WriteFile(r, "a.jar")
+
+				Expect(err).To(BeNil())
+				Expect(reconnects).To(Equal(1))
+				Expect(string(stored)).To(Equal("test file content"))
+				Expect(ftpFs.Connection).To(Equal(reconnected))
+			})
+
+			It("reconnects when the server closes the control connection", func() {
+				mock.ListFunc = func(path string) ([]*ftp.Entry, error) {
+					return nil, &textproto.Error{Code: ftp.StatusNotAvailable}
+				}
+				reconnected.ListFunc = func(path string) ([]*ftp.Entry, error) {
+					return []*ftp.Entry{{Name: "a.jar"}}, nil
+				}
+
+				infos, err := ftpFs.ReadDir("")
+
+				Expect(err).To(BeNil())
+				Expect(infos).To(HaveLen(1))
+			})
+
+			It("doesn't reconnect when the server rejects the command", func() {
+				mock.RetrFunc = func(path string) (*ftp.Response, error) {
+					return nil, &textproto.Error{Code: ftp.StatusFileUnavailable}
+				}
+
+				_, err := ftpFs.ReadFile("a.jar")
+
+				Expect(err).To(Equal(os.ErrNotExist))
+				Expect(reconnects).To(Equal(0))
+			})
+
+			It("returns errors from reconnecting", func() {
+				dialErr := errors.New("dial err")
+				mock.MakeDirFunc = func(dir string) error {
+					return io.ErrUnexpectedEOF
+				}
+				ftpFs.Reconnect = func() (mc.FTPConnection, error) {
+					return nil, dialErr
+				}
+
+				err := ftpFs.MkDirAll("mods")
+
+				Expect(errors.Is(err, dialErr)).To(BeTrue())
+			})
+		})
+
+		Context("keepalive", func() {
+			It("sends NOOPs while idle until closed", func() {
+				var noops int32
+				mock.NoOpFunc = func() error {
+					atomic.AddInt32(&noops, 1)
+					return nil
+				}
+
+				ftpFs.StartKeepAlive(10 * time.Millisecond)
+
+				Eventually(func() int32 { return atomic.LoadInt32(&noops) }).Should(BeNumerically(">=", 2))
+
+				ftpFs.Close()
+				closedAt := atomic.LoadInt32(&noops)
+				Consistently(func() int32 { return atomic.LoadInt32(&noops) }, 50*time.Millisecond).Should(Equal(closedAt))
+			})
+		})
 	})
 })
 
@@ -247,6 +489,10 @@ type mockFTP struct {
 	StorFunc    func(path string, r io.Reader) error
 	RetrFunc    func(path string) (*ftp.Response, error)
 	MakeDirFunc func(dir string) error
+	ListFunc    func(path string) ([]*ftp.Entry, error)
+	DeleteFunc  func(path string) error
+	RenameFunc  func(from, to string) error
+	NoOpFunc    func() error
 	QuitFunc    func() error
 }
 
@@ -256,6 +502,10 @@ func emptyMock() *mockFTP {
 		StorFunc:    func(path string, r io.Reader) error { return nil },
 		RetrFunc:    func(path string) (*ftp.Response, error) { return &ftp.Response{}, nil },
 		MakeDirFunc: func(dir string) error { return nil },
+		ListFunc:    func(path string) ([]*ftp.Entry, error) { return nil, nil },
+		DeleteFunc:  func(path string) error { return nil },
+		RenameFunc:  func(from, to string) error { return nil },
+		NoOpFunc:    func() error { return nil },
 		QuitFunc:    func() error { return nil },
 	}
 }
@@ -276,6 +526,22 @@ func (ftp mockFTP) MakeDir(dir string) error {
 	return ftp.MakeDirFunc(dir)
 }
 
+func (ftp mockFTP) List(path string) ([]*ftp.Entry, error) {
+	return ftp.ListFunc(path)
+}
+
+func (ftp mockFTP) Delete(path string) error {
+	return ftp.DeleteFunc(path)
+}
+
+func (ftp mockFTP) Rename(from, to string) error {
+	return ftp.RenameFunc(from, to)
+}
+
+func (ftp mockFTP) NoOp() error {
+	return ftp.NoOpFunc()
+}
+
 func (ftp mockFTP) Quit() error {
 	return ftp.QuitFunc()
 }
@@ -0,0 +1,168 @@
+package mc
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jlaffaye/ftp"
+)
+
+const (
+	// FTPTLSKey - The key of the TLS mode used for FTP connections
+	FTPTLSKey = "ftpTls"
+
+	// FTPTLSFingerprintKey - The key of the pinned FTP server certificate fingerprint
+	FTPTLSFingerprintKey = "ftpTlsFingerprint"
+
+	// FTPTLSSkipVerifyKey - The key of the toggle which skips FTP certificate verification
+	FTPTLSSkipVerifyKey = "ftpTlsSkipVerify"
+
+	// FTPTLSNone connects over plain FTP
+	FTPTLSNone = "none"
+
+	// FTPTLSExplicit upgrades the connection to TLS with AUTH TLS
+	FTPTLSExplicit = "explicit"
+
+	// FTPTLSImplicit connects over TLS from the start, usually on port 990
+	FTPTLSImplicit = "implicit"
+
+	// FTPSScheme is the URL scheme which selects implicit TLS for a server
+	FTPSScheme = "ftps://"
+
+	// FTPDefaultPort is used when the server address doesn't include a port
+	FTPDefaultPort = "21"
+
+	// FTPSDefaultPort is used for implicit TLS when the server address
+	// doesn't include a port
+	FTPSDefaultPort = "990"
+)
+
+// GetFTPTLSMode returns the TLS mode to connect to the FTP server with. If the
+// mode isn't set, ftps:// servers use implicit TLS and others plain FTP.
+func (a FTPArgs) GetFTPTLSMode() string {
+	if a.TLSMode != "" {
+		return strings.ToLower(a.TLSMode)
+	}
+	if strings.HasPrefix(strings.ToLower(a.Server), FTPSScheme) {
+		return FTPTLSImplicit
+	}
+	return FTPTLSNone
+}
+
+// ftpTLSDialOptions returns the dial options for the TLS mode, which are empty
+// for plain FTP
+func ftpTLSDialOptions(args *FTPArgs) ([]ftp.DialOption, error) {
+	mode := args.GetFTPTLSMode()
+	if mode == FTPTLSNone {
+		if args.TLSFingerprint != "" || args.TLSSkipVerify {
+			return nil, errors.New("the FTP TLS fingerprint and skip verify options require a TLS mode")
+		}
+		return nil, nil
+	}
+
+	cfg, err := NewFTPTLSConfig(args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch mode {
+	case FTPTLSExplicit:
+		return []ftp.DialOption{ftp.DialWithExplicitTLS(cfg)}, nil
+	case FTPTLSImplicit:
+		return []ftp.DialOption{ftp.DialWithTLS(cfg)}, nil
+	}
+	return nil, fmt.Errorf("unknown FTP TLS mode: %s (use %s, %s, or %s)", args.TLSMode, FTPTLSNone, FTPTLSExplicit, FTPTLSImplicit)
+}
+
+// NewFTPTLSConfig creates the TLS config for connecting to the FTP server. The
+// server's certificate is verified against the system CAs, unless it's pinned
+// by fingerprint or verification is skipped. Exported for testing.
+func NewFTPTLSConfig(args *FTPArgs) (*tls.Config, error) {
+	host := ftpAddress(args.Server)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	cfg := &tls.Config{
+		ServerName: host,
+		// data connections must resume the control connection's TLS session
+		// on many servers
+		ClientSessionCache: tls.NewLRUClientSessionCache(0),
+	}
+
+	if args.TLSFingerprint != "" {
+		pin, err := parseFingerprint(args.TLSFingerprint)
+		if err != nil {
+			return nil, err
+		}
+		// the pinned certificate replaces CA verification, so self-signed
+		// certificates can be used
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errors.New("the FTP server didn't send a certificate")
+			}
+			actual := sha256.Sum256(rawCerts[0])
+			if hex.EncodeToString(actual[:]) != pin {
+				return fmt.Errorf("the FTP server's certificate fingerprint %s doesn't match the pinned fingerprint", FormatFingerprint(actual[:]))
+			}
+			return nil
+		}
+	} else if args.TLSSkipVerify {
+		fmt.Println("WARNING: the FTP server's certificate isn't being verified")
+		cfg.InsecureSkipVerify = true
+	}
+
+	return cfg, nil
+}
+
+// FormatFingerprint formats a certificate fingerprint as colon-separated hex,
+// like openssl does
+func FormatFingerprint(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02X", v)
+	}
+	return strings.Join(parts, ":")
+}
+
+// parseFingerprint normalizes a SHA-256 fingerprint, with or without colons,
+// to lower case hex
+func parseFingerprint(fp string) (string, error) {
+	fp = strings.ReplaceAll(strings.TrimPrefix(strings.ToLower(fp), "sha256:"), ":", "")
+	if b, err := hex.DecodeString(fp); err != nil || len(b) != sha256.Size {
+		return "", errors.New("the FTP TLS fingerprint must be a SHA-256 hash in hex, e.g. from openssl x509 -fingerprint -sha256")
+	}
+	return fp, nil
+}
+
+// ftpDialAddress returns the address to dial the server on, adding the
+// default port of the TLS mode if there isn't one
+func ftpDialAddress(args *FTPArgs) string {
+	server := ftpAddress(args.Server)
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	if args.GetFTPTLSMode() == FTPTLSImplicit {
+		return net.JoinHostPort(server, FTPSDefaultPort)
+	}
+	return net.JoinHostPort(server, FTPDefaultPort)
+}
+
+// ftpAddress strips the ftp:// or ftps:// scheme from the server
+func ftpAddress(server string) string {
+	lower := strings.ToLower(server)
+	for _, scheme := range []string{FTPSScheme, "ftp://"} {
+		if strings.HasPrefix(lower, scheme) {
+			server = server[len(scheme):]
+			break
+		}
+	}
+	return strings.TrimSuffix(server, "/")
+}
@@ -0,0 +1,322 @@
+package mc_test
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"mcmods/mc"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	"github.com/jlaffaye/ftp"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FTPS", func() {
+	Describe("GetFTPTLSMode", func() {
+		It("defaults to none", func() {
+			Expect(mc.FTPArgs{Server: "host:21"}.GetFTPTLSMode()).To(Equal(mc.FTPTLSNone))
+		})
+
+		It("uses implicit TLS for ftps:// servers", func() {
+			Expect(mc.FTPArgs{Server: "FTPS://host:990"}.GetFTPTLSMode()).To(Equal(mc.FTPTLSImplicit))
+		})
+
+		It("prefers the configured mode", func() {
+			args := mc.FTPArgs{Server: "ftps://host:990", TLSMode: "Explicit"}
+
+			Expect(args.GetFTPTLSMode()).To(Equal(mc.FTPTLSExplicit))
+		})
+	})
+
+	Describe("dialing", func() {
+		var dialedServer string
+		var dialedOpts []ftp.DialOption
+		var args *mc.FTPArgs
+
+		BeforeEach(func() {
+			mc.FTPDial = func(server string, opts ...ftp.DialOption) (mc.FTPConnection, error) {
+				dialedServer = server
+				dialedOpts = opts
+				return emptyMock(), nil
+			}
+			args = &mc.FTPArgs{Server: "host:21", User: "usr", Pw: "pw"}
+		})
+
+		AfterEach(func() {
+			mc.FTPDial = fakeFTPDial
+		})
+
+		It("disables EPSV in PASV mode", func() {
+			args.Mode = mc.FTPModePASV
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).To(BeNil())
+			Expect(dialedOpts).To(HaveLen(2))
+		})
+
+		It("rejects unknown modes, and active mode with TLS", func() {
+			args.Mode = "other"
+			_, err := mc.NewFs(args)
+			Expect(err).ToNot(BeNil())
+
+			args.Mode = mc.FTPModeActive
+			args.TLSMode = mc.FTPTLSExplicit
+			_, err = mc.NewFs(args)
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("has the server open the data connections in active mode", func() {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).To(BeNil())
+			defer l.Close()
+			server := &activeFTPServer{files: map[string]string{"/mods/a.jar": "jar"}}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				server.serve(l)
+			}()
+			args.Server = l.Addr().String()
+			args.Mode = mc.FTPModeActive
+			_, err = mc.NewFs(args)
+			Expect(err).To(BeNil())
+
+			conn, err := ftp.Dial(args.Server, dialedOpts...)
+			Expect(err).To(BeNil())
+			Expect(conn.Login("usr", "pw")).To(BeNil())
+			r, err := conn.Retr("/mods/a.jar")
+			Expect(err).To(BeNil())
+			b, err := io.ReadAll(r)
+			Expect(err).To(BeNil())
+			Expect(r.Close()).To(BeNil())
+			Expect(conn.Stor("/mods/b.jar", strings.NewReader("new"))).To(BeNil())
+			Expect(conn.Stor("/mods/empty.txt", strings.NewReader(""))).To(BeNil())
+			Expect(conn.Quit()).To(BeNil())
+			<-done
+
+			Expect(string(b)).To(Equal("jar"))
+			Expect(server.files["/mods/b.jar"]).To(Equal("new"))
+			Expect(server.files).To(HaveKeyWithValue("/mods/empty.txt", ""))
+			Expect(server.cmds).ToNot(ContainElement("PASV"))
+			Expect(server.cmds).To(ContainElement(HavePrefix("PORT ")))
+		})
+
+		It("selects FTP from the protocol", func() {
+			args.Protocol = "FTP"
+
+			fs, err := mc.NewFs(args)
+
+			Expect(err).To(BeNil())
+			Expect(fs).To(BeAssignableToTypeOf(&mc.FTPFileSystem{}))
+		})
+
+		It("dials plain FTP without a TLS option", func() {
+			_, err := mc.NewFs(args)
+
+			Expect(err).To(BeNil())
+			Expect(dialedOpts).To(HaveLen(1))
+		})
+
+		It("adds a TLS option for explicit TLS", func() {
+			args.TLSMode = mc.FTPTLSExplicit
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).To(BeNil())
+			Expect(dialedOpts).To(HaveLen(2))
+		})
+
+		It("strips the scheme from ftps:// servers", func() {
+			args.Server = "ftps://host:990"
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).To(BeNil())
+			Expect(dialedServer).To(Equal("host:990"))
+			Expect(dialedOpts).To(HaveLen(2))
+		})
+
+		It("adds the default port of the TLS mode", func() {
+			args.Server = "ftps://host"
+			_, err := mc.NewFs(args)
+			Expect(err).To(BeNil())
+			Expect(dialedServer).To(Equal("host:990"))
+
+			args.Server = "FTP://host/"
+			_, err = mc.NewFs(args)
+			Expect(err).To(BeNil())
+			Expect(dialedServer).To(Equal("host:21"))
+		})
+
+		It("returns an error for unknown TLS modes", func() {
+			args.TLSMode = "sometimes"
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("returns an error for TLS options without a TLS mode", func() {
+			args.TLSSkipVerify = true
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("returns an error for invalid fingerprints", func() {
+			args.TLSMode = mc.FTPTLSImplicit
+			args.TLSFingerprint = "abc"
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Describe("NewFTPTLSConfig", func() {
+		var server *httptest.Server
+		var args *mc.FTPArgs
+		var fingerprint string
+
+		BeforeEach(func() {
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			sum := sha256.Sum256(server.Certificate().Raw)
+			fingerprint = mc.FormatFingerprint(sum[:])
+
+			args = &mc.FTPArgs{
+				Server:  "ftps://" + server.Listener.Addr().String(),
+				TLSMode: mc.FTPTLSImplicit,
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		handshake := func() error {
+			cfg, err := mc.NewFTPTLSConfig(args)
+			Expect(err).To(BeNil())
+
+			conn, err := tls.Dial("tcp", server.Listener.Addr().String(), cfg)
+			if err == nil {
+				conn.Close()
+			}
+			return err
+		}
+
+		It("uses the host as the server name", func() {
+			cfg, err := mc.NewFTPTLSConfig(args)
+
+			Expect(err).To(BeNil())
+			Expect(cfg.ServerName).To(Equal("127.0.0.1"))
+			Expect(cfg.ClientSessionCache).ToNot(BeNil())
+		})
+
+		It("verifies the certificate against the CAs by default", func() {
+			Expect(handshake()).ToNot(BeNil())
+		})
+
+		It("accepts the certificate with a matching fingerprint", func() {
+			args.TLSFingerprint = fingerprint
+
+			Expect(handshake()).To(BeNil())
+		})
+
+		It("accepts fingerprints without colons in any case", func() {
+			args.TLSFingerprint = strings.ToLower(strings.ReplaceAll(fingerprint, ":", ""))
+
+			Expect(handshake()).To(BeNil())
+		})
+
+		It("rejects the certificate with a different fingerprint", func() {
+			args.TLSFingerprint = strings.Repeat("AB:", 31) + "AB"
+
+			err := handshake()
+
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring(fingerprint))
+		})
+
+		It("accepts any certificate when skipping verification", func() {
+			args.TLSSkipVerify = true
+
+			Expect(handshake()).To(BeNil())
+		})
+	})
+})
+
+// activeFTPServer answers one client with just enough of FTP for logins and
+// transfers, opening the data connections to the address given with PORT
+type activeFTPServer struct {
+	files map[string]string
+	cmds  []string
+}
+
+func (s *activeFTPServer) serve(l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) {
+		fmt.Fprintf(conn, "%s\r\n", line)
+	}
+	reply("220 Ready")
+
+	var dataAddr string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		s.cmds = append(s.cmds, line)
+		cmd, arg := line, ""
+		if i := strings.Index(line, " "); i >= 0 {
+			cmd, arg = line[:i], line[i+1:]
+		}
+
+		switch cmd {
+		case "USER":
+			reply("230 Logged in")
+		case "TYPE":
+			reply("200 OK")
+		case "PORT":
+			p := strings.Split(arg, ",")
+			hi, _ := strconv.Atoi(p[4])
+			lo, _ := strconv.Atoi(p[5])
+			dataAddr = net.JoinHostPort(strings.Join(p[:4], "."), strconv.Itoa(hi*256+lo))
+			reply("200 PORT OK")
+		case "RETR", "STOR":
+			reply("150 Opening data connection")
+			data, err := net.Dial("tcp", dataAddr)
+			if err != nil {
+				reply("425 Can't open data connection")
+				continue
+			}
+			if cmd == "RETR" {
+				io.WriteString(data, s.files[arg])
+			} else {
+				b, _ := io.ReadAll(data)
+				s.files[arg] = string(b)
+			}
+			data.Close()
+			reply("226 Transfer complete")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
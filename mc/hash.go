@@ -0,0 +1,44 @@
+package mc
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+var hashFuncs = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// HashSHA256 returns the hex encoded SHA-256 digest of the content
+func HashSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyHashes checks the content against each of the expected hashes with a
+// supported algorithm. Returns the number of hashes which were checked, or an
+// error for the first mismatch.
+func VerifyHashes(b []byte, expected map[string]string) (int, error) {
+	checked := 0
+	for algo, want := range expected {
+		newHash, ok := hashFuncs[strings.ToLower(algo)]
+		if !ok {
+			continue
+		}
+
+		h := newHash()
+		h.Write(b)
+		if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+			return checked, fmt.Errorf("%s mismatch: expected %s, got %s", algo, want, got)
+		}
+		checked++
+	}
+	return checked, nil
+}
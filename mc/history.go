@@ -0,0 +1,245 @@
+package mc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// HistoryFileName is the name of the install history log, which is kept
+	// next to the user config file
+	HistoryFileName = "mcmods-history.jsonl"
+
+	// HistoryInstall is the event of a mod installed for the first time
+	HistoryInstall = "install"
+
+	// HistoryUpdate is the event of an installed mod replaced by another file
+	HistoryUpdate = "update"
+
+	// HistoryUninstall is the event of a mod removed from the installations
+	HistoryUninstall = "uninstall"
+)
+
+var (
+	// HistoryActor returns the user and machine recorded as running the
+	// changes in the history
+	HistoryActor func() (username, hostname string) = currentActor
+)
+
+// HistoryEvent is a change to the installation of a mod
+type HistoryEvent struct {
+	// Revision numbers the saves of the installations, starting at 1. The
+	// events of one save share a revision.
+	Revision int `json:"revision"`
+
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Mod       string    `json:"mod"`
+	OldURL    string    `json:"oldUrl,omitempty"`
+	NewURL    string    `json:"newUrl,omitempty"`
+	OldSHA256 string    `json:"oldSha256,omitempty"`
+	NewSHA256 string    `json:"newSha256,omitempty"`
+	User      string    `json:"user"`
+	Host      string    `json:"host"`
+
+	// Before and After are the complete installation records, which are nil
+	// while the mod isn't installed
+	Before *ModInstallation `json:"before,omitempty"`
+	After  *ModInstallation `json:"after,omitempty"`
+}
+
+// HistoryFilter selects history events. Empty fields match every event.
+type HistoryFilter struct {
+	Mod   string
+	Since time.Time
+	Until time.Time
+}
+
+// Match returns true if the event is for the mod and in the time range. Since
+// is inclusive, and Until is exclusive.
+func (f HistoryFilter) Match(e HistoryEvent) bool {
+	if f.Mod != "" && f.Mod != e.Mod {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// DiffInstallations returns the events which change the installations from
+// before to after, sorted by mod. Mods reinstalled with the same URL and hash
+// aren't changed.
+func DiffInstallations(before, after map[string]ModInstallation, now time.Time) []HistoryEvent {
+	username, hostname := HistoryActor()
+	newEvent := func(event, cliName string) HistoryEvent {
+		return HistoryEvent{Time: now, Event: event, Mod: cliName, User: username, Host: hostname}
+	}
+
+	events := []HistoryEvent{}
+	for cliName, installation := range after {
+		old, existed := before[cliName]
+		if existed && sameInstallation(old, installation) {
+			continue
+		}
+
+		e := newEvent(HistoryInstall, cliName)
+		if existed {
+			e.Event = HistoryUpdate
+			e.OldURL = old.DownloadURL
+			e.OldSHA256 = old.SHA256
+			e.Before = &old
+		}
+		installation := installation
+		e.NewURL = installation.DownloadURL
+		e.NewSHA256 = installation.SHA256
+		e.After = &installation
+		events = append(events, e)
+	}
+
+	for cliName, old := range before {
+		if _, installed := after[cliName]; installed {
+			continue
+		}
+
+		old := old
+		e := newEvent(HistoryUninstall, cliName)
+		e.OldURL = old.DownloadURL
+		e.OldSHA256 = old.SHA256
+		e.Before = &old
+		events = append(events, e)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Mod < events[j].Mod
+	})
+	return events
+}
+
+// AppendHistory adds the events to the end of the history log on the file
+// system, as the next revision. The log is rewritten, since not every file
+// system can append.
+func AppendHistory(fs FileSystem, events []HistoryEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	b, err := fs.ReadFile(relHistoryPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	past, err := parseHistory(b)
+	if err != nil {
+		return err
+	}
+	revision := LatestRevision(past) + 1
+
+	buf := bytes.NewBuffer(b)
+	if buf.Len() > 0 && !bytes.HasSuffix(b, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	for _, e := range events {
+		e.Revision = revision
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	return fs.WriteFile(buf, relHistoryPath())
+}
+
+// ReadHistory reads the history log on the file system, oldest first. An
+// empty history is returned if there's no log.
+func ReadHistory(fs FileSystem) ([]HistoryEvent, error) {
+	b, err := fs.ReadFile(relHistoryPath())
+	if os.IsNotExist(err) {
+		return []HistoryEvent{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return parseHistory(b)
+}
+
+// LatestRevision returns the revision of the last events, or 0 if there are
+// none
+func LatestRevision(events []HistoryEvent) int {
+	latest := 0
+	for _, e := range events {
+		if e.Revision > latest {
+			latest = e.Revision
+		}
+	}
+	return latest
+}
+
+func parseHistory(b []byte) ([]HistoryEvent, error) {
+	events := []HistoryEvent{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		e, err := parseHistoryEvent(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("line %d of %s: %w", line, HistoryFileName, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// parseHistoryEvent parses a line of the history. Lines written before the
+// install timestamps were RFC 3339 have their records migrated first.
+func parseHistoryEvent(b []byte) (HistoryEvent, error) {
+	e := HistoryEvent{}
+	err := json.Unmarshal(b, &e)
+	if _, ok := err.(*time.ParseError); !ok {
+		return e, err
+	}
+
+	raw := map[string]interface{}{}
+	if err = json.Unmarshal(b, &raw); err != nil {
+		return e, err
+	}
+	migrateInstallTimestamp(raw["before"])
+	migrateInstallTimestamp(raw["after"])
+	if b, err = json.Marshal(raw); err != nil {
+		return e, err
+	}
+
+	e = HistoryEvent{}
+	return e, json.Unmarshal(b, &e)
+}
+
+// sameInstallation returns true if the records are for the same file
+func sameInstallation(a, b ModInstallation) bool {
+	return a.DownloadURL == b.DownloadURL && strings.EqualFold(a.SHA256, b.SHA256)
+}
+
+func relHistoryPath() string {
+	return filepath.Join(ModFolderName, HistoryFileName)
+}
+
+func currentActor() (username, hostname string) {
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+	hostname, _ = os.Hostname()
+	return username, hostname
+}
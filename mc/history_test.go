@@ -0,0 +1,122 @@
+package mc_test
+
+import (
+	"mcmods/mc"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("History", func() {
+	var fs mc.FileSystem
+	var afs afero.Fs
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	BeforeEach(func() {
+		afs = afero.NewMemMapFs()
+		fs = mc.LocalFileSystem{Fs: afs, Dir: "/minecraft"}
+		mc.HistoryActor = func() (string, string) {
+			return "alice", "build-box"
+		}
+	})
+
+	Context("diff", func() {
+		It("returns install, update and uninstall events sorted by mod", func() {
+			before := map[string]mc.ModInstallation{
+				"kept":     {DownloadURL: "https://kept", SHA256: "aa"},
+				"updated":  {DownloadURL: "https://old", SHA256: "bb"},
+				"removed":  {DownloadURL: "https://removed", SHA256: "cc"},
+				"reloaded": {DownloadURL: "https://reloaded", SHA256: "dd", Timestamp: now.Add(-time.Hour)},
+			}
+			after := map[string]mc.ModInstallation{
+				"kept":     {DownloadURL: "https://kept", SHA256: "aa"},
+				"updated":  {DownloadURL: "https://new", SHA256: "ee"},
+				"added":    {DownloadURL: "https://added", SHA256: "ff"},
+				"reloaded": {DownloadURL: "https://reloaded", SHA256: "DD", Timestamp: now},
+			}
+
+			events := mc.DiffInstallations(before, after, now)
+
+			added, removed, updated, oldUpdated := after["added"], before["removed"], after["updated"], before["updated"]
+			Expect(events).To(Equal([]mc.HistoryEvent{
+				{Time: now, Event: mc.HistoryInstall, Mod: "added", NewURL: "https://added", NewSHA256: "ff", User: "alice", Host: "build-box", After: &added},
+				{Time: now, Event: mc.HistoryUninstall, Mod: "removed", OldURL: "https://removed", OldSHA256: "cc", User: "alice", Host: "build-box", Before: &removed},
+				{Time: now, Event: mc.HistoryUpdate, Mod: "updated", OldURL: "https://old", NewURL: "https://new", OldSHA256: "bb", NewSHA256: "ee", User: "alice", Host: "build-box", Before: &oldUpdated, After: &updated},
+			}))
+		})
+	})
+
+	Context("log", func() {
+		It("returns an empty history when there's no log", func() {
+			events, err := mc.ReadHistory(fs)
+
+			Expect(err).To(BeNil())
+			Expect(events).To(BeEmpty())
+		})
+
+		It("appends events to the log as new revisions", func() {
+			first := mc.DiffInstallations(nil, map[string]mc.ModInstallation{"mod-a": {DownloadURL: "https://a"}}, now)
+			second := mc.DiffInstallations(map[string]mc.ModInstallation{"mod-a": {DownloadURL: "https://a"}}, nil, now.Add(time.Hour))
+
+			Expect(mc.AppendHistory(fs, first)).To(BeNil())
+			Expect(mc.AppendHistory(fs, second)).To(BeNil())
+
+			events, err := mc.ReadHistory(fs)
+			Expect(err).To(BeNil())
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Event).To(Equal(mc.HistoryInstall))
+			Expect(events[0].Revision).To(Equal(1))
+			Expect(events[1].Event).To(Equal(mc.HistoryUninstall))
+			Expect(events[1].Revision).To(Equal(2))
+			Expect(mc.LatestRevision(events)).To(Equal(2))
+			Expect(events[1].Time.Equal(now.Add(time.Hour))).To(BeTrue())
+		})
+
+		It("reads records with the install timestamps of older versions", func() {
+			line := `{"revision":1,"time":"2021-06-01T12:00:00Z","event":"update","mod":"mod-a",` +
+				`"before":{"downloadUrl":"https://a/1","timestamp":"Tue Jun  1 11:00:00 UTC 2021"},` +
+				`"after":{"downloadUrl":"https://a/2","timestamp":"Tue Jun  1 12:00:00 UTC 2021"}}`
+			Expect(afero.WriteFile(afs, filepath.Join("/minecraft", mc.ModFolderName, mc.HistoryFileName), []byte(line+"\n"), 0644)).To(BeNil())
+
+			events, err := mc.ReadHistory(fs)
+
+			Expect(err).To(BeNil())
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Before.Timestamp.Equal(now.Add(-time.Hour))).To(BeTrue())
+			Expect(events[0].After.Timestamp.Equal(now)).To(BeTrue())
+		})
+
+		It("returns an error for malformed lines", func() {
+			Expect(afero.WriteFile(afs, filepath.Join("/minecraft", mc.ModFolderName, mc.HistoryFileName), []byte("{}\n{"), 0644)).To(BeNil())
+
+			_, err := mc.ReadHistory(fs)
+
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("line 2"))
+		})
+	})
+
+	Context("filter", func() {
+		event := mc.HistoryEvent{Time: now, Mod: "mod-a"}
+
+		It("matches every event when empty", func() {
+			Expect(mc.HistoryFilter{}.Match(event)).To(BeTrue())
+		})
+
+		It("matches the mod", func() {
+			Expect(mc.HistoryFilter{Mod: "mod-a"}.Match(event)).To(BeTrue())
+			Expect(mc.HistoryFilter{Mod: "mod-b"}.Match(event)).To(BeFalse())
+		})
+
+		It("includes the start and excludes the end of the range", func() {
+			Expect(mc.HistoryFilter{Since: now}.Match(event)).To(BeTrue())
+			Expect(mc.HistoryFilter{Since: now.Add(time.Second)}.Match(event)).To(BeFalse())
+			Expect(mc.HistoryFilter{Until: now}.Match(event)).To(BeFalse())
+			Expect(mc.HistoryFilter{Until: now.Add(time.Second)}.Match(event)).To(BeTrue())
+		})
+	})
+})
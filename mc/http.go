@@ -1,12 +1,50 @@
 package mc
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	cloudflarebp "github.com/DaRealFreak/cloudflare-bp-go"
 )
 
+const (
+	// HTTPProxyKey - The key of the proxy URL for HTTP downloads
+	HTTPProxyKey = "httpProxy"
+
+	// HTTPConnectTimeoutKey - The key of the timeout for establishing HTTP connections
+	HTTPConnectTimeoutKey = "httpConnectTimeout"
+
+	// HTTPReadTimeoutKey - The key of the timeout for waiting on HTTP data
+	HTTPReadTimeoutKey = "httpReadTimeout"
+
+	// HTTPUserAgentKey - The key of the user agent sent with HTTP requests
+	HTTPUserAgentKey = "httpUserAgent"
+
+	// HTTPCABundleKey - The key of the path to extra trusted CA certificates
+	HTTPCABundleKey = "httpCaBundle"
+
+	// HTTPCloudflareBypassKey - The key of the toggle for the Cloudflare bypass
+	HTTPCloudflareBypassKey = "httpCloudflareBypass"
+
+	// HTTPAuthKey - The key of the credentials for download hosts, keyed by host
+	HTTPAuthKey = "httpAuth"
+
+	// EnvironmentProxy is the proxy setting which uses the proxy from the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+	EnvironmentProxy = "env"
+)
+
 // HTTPGet is the interface for making HTTP GET requests abstractly
 type HTTPGet interface {
 	Get(url string) (*http.Response, error)
@@ -17,15 +55,142 @@ type HTTPClient struct {
 	Getter HTTPGet
 }
 
-// NewHTTPClient uses a live http.Client to make connections
+// HTTPOptions configures the connections made by the HTTP client
+type HTTPOptions struct {
+	// Proxy is a proxy URL, EnvironmentProxy, or empty for direct connections
+	Proxy string
+
+	// ConnectTimeout limits dialing and the TLS handshake. Zero means no limit.
+	ConnectTimeout time.Duration
+
+	// ReadTimeout limits how long to wait for any data from the server. Zero
+	// means no limit.
+	ReadTimeout time.Duration
+
+	// UserAgent replaces the default user agent if not empty
+	UserAgent string
+
+	// CABundle is the path to a PEM file of CAs to trust in addition to the
+	// system CAs
+	CABundle string
+
+	// CloudflareBypass makes requests look like they came from a browser
+	CloudflareBypass bool
+
+	// Credentials are sent only to the host they're keyed by. Keys are either
+	// host names, or host:port to match a single port.
+	Credentials map[string]HostCredential
+
+	// StoredCredentials loads credentials kept outside the config, keyed like
+	// Credentials, which are used for hosts without credentials there.
+	// Loading them can ask for a passphrase, so it's only done once a request
+	// is made to one of StoredCredentialHosts, or to any host if that's nil.
+	StoredCredentials     func() (map[string]HostCredential, error)
+	StoredCredentialHosts []string
+}
+
+// HostCredential authenticates requests to a private download host
+type HostCredential struct {
+	// Username and Password are sent with basic auth
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+
+	// Token is sent as a bearer token, taking precedence over basic auth
+	Token string `mapstructure:"token"`
+
+	// Headers are custom headers added to each request
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// String describes the credential without revealing any secrets
+func (c HostCredential) String() string {
+	kinds := []string{}
+	if c.Token != "" {
+		kinds = append(kinds, "bearer token")
+	} else if c.Username != "" {
+		kinds = append(kinds, "basic auth ("+c.Username+")")
+	}
+	for name := range c.Headers {
+		kinds = append(kinds, "header "+http.CanonicalHeaderKey(name))
+	}
+	if len(kinds) == 0 {
+		return "no credentials"
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ", ")
+}
+
+// DefaultHTTPOptions returns the options used when none are configured
+func DefaultHTTPOptions() HTTPOptions {
+	return HTTPOptions{
+		ConnectTimeout:   30 * time.Second,
+		ReadTimeout:      60 * time.Second,
+		CloudflareBypass: true,
+	}
+}
+
+// GetHTTPOptions reads the HTTP options set in Viper, using the defaults for
+// any that aren't set.
+func GetHTTPOptions() (HTTPOptions, error) {
+	opts := DefaultHTTPOptions()
+
+	opts.Proxy = ViperInstance.GetString(HTTPProxyKey)
+	opts.UserAgent = ViperInstance.GetString(HTTPUserAgentKey)
+	opts.CABundle = ViperInstance.GetString(HTTPCABundleKey)
+
+	if ViperInstance.IsSet(HTTPConnectTimeoutKey) {
+		opts.ConnectTimeout = ViperInstance.GetDuration(HTTPConnectTimeoutKey)
+	}
+	if ViperInstance.IsSet(HTTPReadTimeoutKey) {
+		opts.ReadTimeout = ViperInstance.GetDuration(HTTPReadTimeoutKey)
+	}
+	if ViperInstance.IsSet(HTTPCloudflareBypassKey) {
+		opts.CloudflareBypass = ViperInstance.GetBool(HTTPCloudflareBypassKey)
+	}
+
+	if err := ViperInstance.UnmarshalKey(HTTPAuthKey, &opts.Credentials); err != nil {
+		return opts, fmt.Errorf("invalid %s config: %w", HTTPAuthKey, err)
+	}
+
+	return opts, nil
+}
+
+// NewHTTPClient uses a live http.Client with the default options to make
+// connections
 func NewHTTPClient() *HTTPClient {
+	hc, _ := NewConfiguredHTTPClient(DefaultHTTPOptions()) // only CA bundles can fail
+	return hc
+}
+
+// NewConfiguredHTTPClient uses a live http.Client with the given options to
+// make connections
+func NewConfiguredHTTPClient(opts HTTPOptions) (*HTTPClient, error) {
+	transport, err := newHTTPTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var rt http.RoundTripper = transport
+	if opts.CloudflareBypass {
+		tlsCfg := transport.TLSClientConfig
+		rt = cloudflarebp.AddCloudFlareByPass(transport)
+		// the bypass replaces the TLS config, so keep the trusted CAs
+		transport.TLSClientConfig.RootCAs = tlsCfg.RootCAs
+	}
+	if opts.UserAgent != "" {
+		rt = userAgentTransport{Inner: rt, UserAgent: opts.UserAgent}
+	}
+	if len(opts.Credentials) > 0 || opts.StoredCredentials != nil {
+		rt = newAuthTransport(rt, opts)
+	}
+
 	client := &http.Client{
 		CheckRedirect: CheckRedirect,
+		Transport:     rt,
 	}
-	client.Transport = cloudflarebp.AddCloudFlareByPass(client.Transport)
 	return &HTTPClient{
 		Getter: client,
-	}
+	}, nil
 }
 
 // CheckRedirect makes redirects are followed. Only exported for testing
@@ -33,3 +198,198 @@ func CheckRedirect(r *http.Request, _ []*http.Request) error {
 	r.URL.Opaque = strings.ReplaceAll(r.URL.Path, "+", "%2B")
 	return nil
 }
+
+func newHTTPTransport(opts HTTPOptions) (*http.Transport, error) {
+	proxy, err := proxyFunc(opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{}
+	if opts.CABundle != "" {
+		if tlsCfg.RootCAs, err = loadCABundle(opts.CABundle); err != nil {
+			return nil, err
+		}
+	}
+
+	dialer := &timeoutDialer{
+		Dialer:      net.Dialer{Timeout: opts.ConnectTimeout, KeepAlive: 30 * time.Second},
+		ReadTimeout: opts.ReadTimeout,
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsCfg,
+		TLSHandshakeTimeout:   opts.ConnectTimeout,
+		ResponseHeaderTimeout: opts.ReadTimeout,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}, nil
+}
+
+func proxyFunc(proxy string) (func(*http.Request) (*url.URL, error), error) {
+	switch proxy {
+	case "":
+		return nil, nil
+	case EnvironmentProxy:
+		return http.ProxyFromEnvironment, nil
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: %s", proxy)
+	}
+	return http.ProxyURL(u), nil
+}
+
+func loadCABundle(path string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in CA bundle: " + path)
+	}
+	return pool, nil
+}
+
+// timeoutDialer dials connections which fail reads that wait on the server
+// longer than the read timeout
+type timeoutDialer struct {
+	net.Dialer
+	ReadTimeout time.Duration
+}
+
+func (d *timeoutDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, addr)
+	if err != nil || d.ReadTimeout == 0 {
+		return conn, err
+	}
+	return &timeoutConn{Conn: conn, ReadTimeout: d.ReadTimeout}, nil
+}
+
+type timeoutConn struct {
+	net.Conn
+	ReadTimeout time.Duration
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// userAgentTransport sets the user agent on each request
+type userAgentTransport struct {
+	Inner     http.RoundTripper
+	UserAgent string
+}
+
+func (t userAgentTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set("User-Agent", t.UserAgent)
+	return t.Inner.RoundTrip(r)
+}
+
+// authTransport adds credentials to requests for the matching host. Redirects
+// are separate requests, so each hop only gets its own host's credentials.
+type authTransport struct {
+	Inner       http.RoundTripper
+	Credentials map[string]HostCredential
+
+	stored *storedCredentials
+}
+
+// storedCredentials loads the stored credentials the first time they're needed
+type storedCredentials struct {
+	load  func() (map[string]HostCredential, error)
+	hosts map[string]bool
+
+	once  sync.Once
+	creds map[string]HostCredential
+	err   error
+}
+
+func newAuthTransport(inner http.RoundTripper, opts HTTPOptions) authTransport {
+	t := authTransport{Inner: inner, Credentials: lowerHostKeys(opts.Credentials)}
+	if opts.StoredCredentials != nil {
+		t.stored = &storedCredentials{load: opts.StoredCredentials}
+		if opts.StoredCredentialHosts != nil {
+			t.stored.hosts = map[string]bool{}
+			for _, host := range opts.StoredCredentialHosts {
+				t.stored.hosts[strings.ToLower(host)] = true
+			}
+		}
+	}
+	return t
+}
+
+func (t authTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c, ok := findHostCredential(t.Credentials, r.URL)
+	if !ok && t.stored != nil {
+		var err error
+		if c, ok, err = t.stored.find(r.URL); err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		return t.Inner.RoundTrip(r)
+	}
+
+	r = r.Clone(r.Context())
+	for name, value := range c.Headers {
+		r.Header.Set(name, value)
+	}
+	if c.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.Username != "" {
+		r.SetBasicAuth(c.Username, c.Password)
+	}
+	return t.Inner.RoundTrip(r)
+}
+
+// find returns the stored credential for the URL's host, loading the stored
+// credentials if the host may have one
+func (s *storedCredentials) find(u *url.URL) (HostCredential, bool, error) {
+	if s.hosts != nil && !s.hosts[strings.ToLower(u.Host)] && !s.hosts[strings.ToLower(u.Hostname())] {
+		return HostCredential{}, false, nil
+	}
+
+	s.once.Do(func() {
+		var creds map[string]HostCredential
+		creds, s.err = s.load()
+		s.creds = lowerHostKeys(creds)
+	})
+	if s.err != nil {
+		return HostCredential{}, false, s.err
+	}
+
+	c, ok := findHostCredential(s.creds, u)
+	return c, ok, nil
+}
+
+// findHostCredential returns the credential for the URL's host:port, or else
+// its host name. The keys must be lower case.
+func findHostCredential(creds map[string]HostCredential, u *url.URL) (HostCredential, bool) {
+	c, ok := creds[strings.ToLower(u.Host)]
+	if !ok {
+		c, ok = creds[strings.ToLower(u.Hostname())]
+	}
+	return c, ok
+}
+
+func lowerHostKeys(creds map[string]HostCredential) map[string]HostCredential {
+	lowerCreds := make(map[string]HostCredential, len(creds))
+	for host, c := range creds {
+		lowerCreds[strings.ToLower(host)] = c
+	}
+	return lowerCreds
+}
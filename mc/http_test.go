@@ -1,9 +1,17 @@
 package mc_test
 
 import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"mcmods/mc"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -48,4 +56,319 @@ var _ = Describe("HTTP", func() {
 			Expect(req.URL.Opaque).To(Equal(expectedPath))
 		})
 	})
+
+	Context("options", func() {
+		AfterEach(func() {
+			for _, key := range []string{mc.HTTPProxyKey, mc.HTTPConnectTimeoutKey, mc.HTTPReadTimeoutKey,
+				mc.HTTPUserAgentKey, mc.HTTPCABundleKey, mc.HTTPCloudflareBypassKey, mc.HTTPAuthKey} {
+				mc.ViperInstance.Set(key, nil)
+			}
+		})
+
+		It("uses the defaults when nothing is configured", func() {
+			opts, err := mc.GetHTTPOptions()
+
+			Expect(err).To(BeNil())
+			Expect(opts).To(Equal(mc.DefaultHTTPOptions()))
+		})
+
+		It("reads the options from viper", func() {
+			mc.ViperInstance.Set(mc.HTTPProxyKey, mc.EnvironmentProxy)
+			mc.ViperInstance.Set(mc.HTTPConnectTimeoutKey, "5s")
+			mc.ViperInstance.Set(mc.HTTPReadTimeoutKey, "2m")
+			mc.ViperInstance.Set(mc.HTTPUserAgentKey, "mcmods")
+			mc.ViperInstance.Set(mc.HTTPCABundleKey, "/path/to/ca.pem")
+			mc.ViperInstance.Set(mc.HTTPCloudflareBypassKey, false)
+			mc.ViperInstance.Set(mc.HTTPAuthKey, map[string]interface{}{
+				"builds.example.com": map[string]interface{}{"username": "me", "password": "secret"},
+				"files.example.com":  map[string]interface{}{"token": "abc", "headers": map[string]interface{}{"x-api-key": "key"}},
+			})
+
+			opts, err := mc.GetHTTPOptions()
+
+			Expect(err).To(BeNil())
+			Expect(opts).To(Equal(mc.HTTPOptions{
+				Proxy:            mc.EnvironmentProxy,
+				ConnectTimeout:   5 * time.Second,
+				ReadTimeout:      2 * time.Minute,
+				UserAgent:        "mcmods",
+				CABundle:         "/path/to/ca.pem",
+				CloudflareBypass: false,
+				Credentials: map[string]mc.HostCredential{
+					"builds.example.com": {Username: "me", Password: "secret"},
+					"files.example.com":  {Token: "abc", Headers: map[string]string{"x-api-key": "key"}},
+				},
+			}))
+		})
+
+		It("returns an error for invalid credentials", func() {
+			mc.ViperInstance.Set(mc.HTTPAuthKey, "not a map")
+
+			_, err := mc.GetHTTPOptions()
+
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("configured http client", func() {
+		var opts mc.HTTPOptions
+		var server *httptest.Server
+		var userAgent string
+
+		BeforeEach(func() {
+			opts = mc.DefaultHTTPOptions()
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userAgent = r.Header.Get("User-Agent")
+				if r.URL.Path == "/slow" {
+					time.Sleep(200 * time.Millisecond)
+				}
+				w.Write([]byte("ok"))
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("sends the custom user agent", func() {
+			opts.UserAgent = "mcmods-test"
+			hc, err := mc.NewConfiguredHTTPClient(opts)
+			Expect(err).To(BeNil())
+
+			resp, err := hc.Getter.Get(server.URL)
+
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(userAgent).To(Equal("mcmods-test"))
+		})
+
+		It("makes plain requests without the cloudflare bypass", func() {
+			opts.CloudflareBypass = false
+			hc, err := mc.NewConfiguredHTTPClient(opts)
+			Expect(err).To(BeNil())
+
+			client := hc.Getter.(*http.Client)
+			Expect(client.Transport).To(BeAssignableToTypeOf(&http.Transport{}))
+
+			resp, err := hc.Getter.Get(server.URL)
+
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(userAgent).To(HavePrefix("Go-http-client"))
+		})
+
+		It("fails reads that exceed the read timeout", func() {
+			opts.ReadTimeout = 50 * time.Millisecond
+			hc, err := mc.NewConfiguredHTTPClient(opts)
+			Expect(err).To(BeNil())
+
+			_, err = hc.Getter.Get(server.URL + "/slow")
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("uses the proxy URL", func() {
+			opts.Proxy = "http://proxy.local:3128"
+			opts.CloudflareBypass = false
+			hc, err := mc.NewConfiguredHTTPClient(opts)
+			Expect(err).To(BeNil())
+			transport := hc.Getter.(*http.Client).Transport.(*http.Transport)
+
+			proxyURL, err := transport.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "mod_site"}})
+
+			Expect(err).To(BeNil())
+			Expect(proxyURL.String()).To(Equal(opts.Proxy))
+		})
+
+		It("returns an error for invalid proxy URLs", func() {
+			opts.Proxy = "proxy.local"
+
+			_, err := mc.NewConfiguredHTTPClient(opts)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("returns an error for missing CA bundles", func() {
+			opts.CABundle = "/does/not/exist.pem"
+
+			_, err := mc.NewConfiguredHTTPClient(opts)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("trusts the certificates in the CA bundle", func() {
+			tlsServer := httptest.NewTLSServer(server.Config.Handler)
+			defer tlsServer.Close()
+			dir, err := ioutil.TempDir("", "mcmods")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+			opts.CABundle = filepath.Join(dir, "ca.pem")
+			certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+			Expect(ioutil.WriteFile(opts.CABundle, certPem, 0644)).To(BeNil())
+			hc, err := mc.NewConfiguredHTTPClient(opts)
+			Expect(err).To(BeNil())
+
+			resp, err := hc.Getter.Get(tlsServer.URL)
+
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+		})
+
+		It("returns an error for CA bundles without certificates", func() {
+			dir, err := ioutil.TempDir("", "mcmods")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+			opts.CABundle = filepath.Join(dir, "ca.pem")
+			Expect(ioutil.WriteFile(opts.CABundle, []byte("not a cert"), 0644)).To(BeNil())
+
+			_, err = mc.NewConfiguredHTTPClient(opts)
+
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("host credentials", func() {
+		var private, public *httptest.Server
+		var privateReq, publicReq *http.Request
+		var opts mc.HTTPOptions
+
+		BeforeEach(func() {
+			privateReq, publicReq = nil, nil
+			public = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				publicReq = r
+				w.Write([]byte("public"))
+			}))
+			private = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				privateReq = r
+				if r.URL.Path == "/redirect" {
+					http.Redirect(w, r, public.URL+"/file.jar", http.StatusFound)
+					return
+				}
+				w.Write([]byte("private"))
+			}))
+			opts = mc.DefaultHTTPOptions()
+			opts.CloudflareBypass = false
+		})
+
+		AfterEach(func() {
+			private.Close()
+			public.Close()
+		})
+
+		get := func(url string) {
+			hc, err := mc.NewConfiguredHTTPClient(opts)
+			Expect(err).To(BeNil())
+			resp, err := hc.Getter.Get(url)
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+		}
+
+		It("sends basic auth to the matching host", func() {
+			opts.Credentials = map[string]mc.HostCredential{
+				private.Listener.Addr().String(): {Username: "me", Password: "secret"},
+			}
+
+			get(private.URL + "/file.jar")
+
+			user, pw, ok := privateReq.BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(user).To(Equal("me"))
+			Expect(pw).To(Equal("secret"))
+		})
+
+		It("sends bearer tokens and custom headers", func() {
+			opts.Credentials = map[string]mc.HostCredential{
+				private.Listener.Addr().String(): {Token: "abc", Headers: map[string]string{"x-api-key": "key"}},
+			}
+
+			get(private.URL + "/file.jar")
+
+			Expect(privateReq.Header.Get("Authorization")).To(Equal("Bearer abc"))
+			Expect(privateReq.Header.Get("X-Api-Key")).To(Equal("key"))
+		})
+
+		It("doesn't send credentials to other hosts after redirects", func() {
+			opts.Credentials = map[string]mc.HostCredential{
+				private.Listener.Addr().String(): {Token: "abc", Headers: map[string]string{"x-api-key": "key"}},
+			}
+
+			get(private.URL + "/redirect")
+
+			Expect(privateReq.Header.Get("Authorization")).To(Equal("Bearer abc"))
+			Expect(publicReq).ToNot(BeNil())
+			Expect(publicReq.Header.Get("Authorization")).To(BeEmpty())
+			Expect(publicReq.Header.Get("X-Api-Key")).To(BeEmpty())
+		})
+
+		It("sends credentials to the host a redirect leads to", func() {
+			opts.Credentials = map[string]mc.HostCredential{
+				public.Listener.Addr().String(): {Token: "abc"},
+			}
+
+			get(private.URL + "/redirect")
+
+			Expect(privateReq.Header.Get("Authorization")).To(BeEmpty())
+			Expect(publicReq.Header.Get("Authorization")).To(Equal("Bearer abc"))
+		})
+
+		It("loads stored credentials only for requests to their hosts", func() {
+			loads := 0
+			opts.StoredCredentialHosts = []string{private.Listener.Addr().String()}
+			opts.StoredCredentials = func() (map[string]mc.HostCredential, error) {
+				loads++
+				return map[string]mc.HostCredential{private.Listener.Addr().String(): {Token: "stored"}}, nil
+			}
+			hc, err := mc.NewConfiguredHTTPClient(opts)
+			Expect(err).To(BeNil())
+
+			resp, err := hc.Getter.Get(public.URL + "/file.jar")
+			Expect(err).To(BeNil())
+			resp.Body.Close()
+			Expect(loads).To(Equal(0))
+
+			for i := 0; i < 2; i++ {
+				resp, err = hc.Getter.Get(private.URL + "/file.jar")
+				Expect(err).To(BeNil())
+				resp.Body.Close()
+			}
+			Expect(loads).To(Equal(1))
+			Expect(privateReq.Header.Get("Authorization")).To(Equal("Bearer stored"))
+		})
+
+		It("prefers the configured credentials over the stored ones", func() {
+			opts.Credentials = map[string]mc.HostCredential{
+				private.Listener.Addr().String(): {Token: "configured"},
+			}
+			opts.StoredCredentials = func() (map[string]mc.HostCredential, error) {
+				Fail("the stored credentials shouldn't be loaded")
+				return nil, nil
+			}
+
+			get(private.URL + "/file.jar")
+
+			Expect(privateReq.Header.Get("Authorization")).To(Equal("Bearer configured"))
+		})
+
+		It("returns errors from loading the stored credentials", func() {
+			opts.StoredCredentials = func() (map[string]mc.HostCredential, error) {
+				return nil, errors.New("wrong passphrase")
+			}
+			hc, err := mc.NewConfiguredHTTPClient(opts)
+			Expect(err).To(BeNil())
+
+			_, err = hc.Getter.Get(private.URL + "/file.jar")
+
+			Expect(err).ToNot(BeNil())
+			Expect(privateReq).To(BeNil())
+		})
+
+		It("doesn't reveal secrets when printed", func() {
+			c := mc.HostCredential{Username: "me", Password: "secret", Headers: map[string]string{"x-api-key": "key"}}
+
+			str := fmt.Sprint(c)
+
+			Expect(str).To(Equal("basic auth (me), header X-Api-Key"))
+			Expect(mc.HostCredential{Token: "abc"}.String()).To(Equal("bearer token"))
+		})
+	})
 })
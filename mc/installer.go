@@ -2,14 +2,25 @@ package mc
 
 import (
 	"fmt"
-	"path/filepath"
 	"time"
 )
 
 // ModInstallation captures the URL and filename for a mod that gets installed on the system
 type ModInstallation struct {
 	DownloadURL string `json:"downloadUrl"`
-	Timestamp   string `json:"timestamp"`
+
+	// Timestamp is when the mod was installed. Zero if the time is unknown.
+	Timestamp time.Time `json:"timestamp"`
+
+	// SourceURL is the URL the file was actually downloaded from, which is a
+	// mirror if DownloadURL couldn't be used
+	SourceURL string `json:"sourceUrl,omitempty"`
+
+	// SHA256 is the hex encoded digest of the installed file
+	SHA256 string `json:"sha256,omitempty"`
+
+	// Size is the number of bytes in the installed file
+	Size int64 `json:"size,omitempty"`
 }
 
 // ModInstaller is an interface for for installing mods
@@ -28,11 +39,10 @@ func NewModInstaller() ModInstaller {
 // InstallMods downloads and installs the mods in the given slice
 func (i modInstaller) InstallMods(downloader ModDownloader, mods []*Mod, cfg *UserModConfig) error {
 	for _, m := range mods {
-		fileName := fmt.Sprintf("%s.jar", m.CliName)
-		modPath := filepath.Join(ModFolderName, fileName)
+		modPath := ModInstallPath(m.CliName)
 
 		fmt.Printf("Installing %s\n", m.FriendlyName)
-		err := downloader.Download(m, modPath)
+		res, err := downloader.Download(m, modPath)
 
 		if err != nil {
 			return err
@@ -40,7 +50,10 @@ func (i modInstaller) InstallMods(downloader ModDownloader, mods []*Mod, cfg *Us
 
 		cfg.ModInstallations[m.CliName] = ModInstallation{
 			DownloadURL: m.LatestURL,
-			Timestamp:   fmt.Sprint(time.Now().Format(time.UnixDate)),
+			Timestamp:   time.Now().Truncate(time.Second),
+			SourceURL:   res.URL,
+			SHA256:      res.SHA256,
+			Size:        res.Size,
 		}
 	}
 
@@ -2,7 +2,6 @@ package mc_test
 
 import (
 	"errors"
-	"fmt"
 	"mcmods/mc"
 	. "mcmods/testdata"
 	"path/filepath"
@@ -64,20 +63,22 @@ var _ = Describe("Installer", func() {
 	It("adds install items to the config", func() {
 		mods := []*mc.Mod{TestingClientMod1, TestingClientMod2}
 		dl := &emptyDownloader{}
-		nowText := fmt.Sprint(time.Now().Format(time.UnixDate))
+		start := time.Now().Truncate(time.Second)
 
 		err := installer.InstallMods(dl, mods, cfg)
 
 		Expect(err).To(BeNil())
 		Expect(cfg.ModInstallations).To(HaveLen(len(mods)))
 
-		verifyInstall(TestingClientMod1, cfg, nowText)
-		verifyInstall(TestingClientMod2, cfg, nowText)
+		verifyInstall(TestingClientMod1, cfg, start)
+		verifyInstall(TestingClientMod2, cfg, start)
 	})
 })
 
-func verifyInstall(mod *mc.Mod, cfg *mc.UserModConfig, nowText string) {
+func verifyInstall(mod *mc.Mod, cfg *mc.UserModConfig, start time.Time) {
 	install := cfg.ModInstallations[mod.CliName]
 	Expect(install.DownloadURL).To(Equal(mod.LatestURL))
-	Expect(install.Timestamp).To(Equal(nowText))
+	Expect(install.Timestamp).To(BeTemporally(">=", start))
+	Expect(install.Timestamp).To(BeTemporally("<=", time.Now()))
+	Expect(install.SourceURL).To(Equal(mod.LatestURL))
 }
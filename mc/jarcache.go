@@ -0,0 +1,76 @@
+package mc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// JarCacheDirName is the name of the jar cache directory in the user's
+	// home directory
+	JarCacheDirName = ".mcmods-cache"
+)
+
+var (
+	// JarCacheFs is the file system the jar cache is stored on
+	JarCacheFs = afero.NewOsFs()
+)
+
+// JarCache keeps a local copy of each downloaded jar, by content hash, so
+// earlier installs can be restored without downloading them again
+type JarCache struct {
+	Fs  afero.Fs
+	Dir string
+}
+
+// DefaultJarCache returns the cache in the user's home directory, or nil if
+// there is no home directory
+func DefaultJarCache() *JarCache {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return &JarCache{Fs: JarCacheFs, Dir: filepath.Join(home, JarCacheDirName)}
+}
+
+// Put stores the jar, unless the cache already has it
+func (c *JarCache) Put(b []byte) error {
+	p := c.jarPath(HashSHA256(b))
+	if exists, err := afero.Exists(c.Fs, p); err != nil || exists {
+		return err
+	}
+
+	if err := c.Fs.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+
+	// written to a temporary file first so a partial jar is never cached
+	tmp := p + ".tmp"
+	if err := afero.WriteFile(c.Fs, tmp, b, 0644); err != nil {
+		return err
+	}
+	return c.Fs.Rename(tmp, p)
+}
+
+// Get returns the jar with the SHA-256 hash, or os.ErrNotExist if it isn't
+// cached. Jars which no longer match their hash are treated as missing.
+func (c *JarCache) Get(sha256 string) ([]byte, error) {
+	b, err := afero.ReadFile(c.Fs, c.jarPath(sha256))
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(HashSHA256(b), sha256) {
+		c.Fs.Remove(c.jarPath(sha256))
+		return nil, fmt.Errorf("cached jar %s was corrupt: %w", sha256, os.ErrNotExist)
+	}
+	return b, nil
+}
+
+func (c *JarCache) jarPath(sha256 string) string {
+	return filepath.Join(c.Dir, strings.ToLower(sha256)+".jar")
+}
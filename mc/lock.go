@@ -0,0 +1,231 @@
+package mc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// LockFileName is the name of the install lock file in the mods folder
+	LockFileName = "mcmods-install.lock"
+
+	// LockStaleAfterKey - The key of how old a lock is before it's taken to be
+	// left behind by an interrupted run
+	LockStaleAfterKey = "lockStaleAfter"
+
+	// DefaultLockStaleAfter is how old a lock is before it's stale when no
+	// age is set
+	DefaultLockStaleAfter = time.Hour
+)
+
+// InstallLock is written to the mods folder while a run changes the
+// installation records, so other runs don't change them at the same time. The
+// lock is advisory: it's only respected by this tool.
+type InstallLock struct {
+	Owner    string    `json:"owner"`
+	Host     string    `json:"host"`
+	PID      int       `json:"pid"`
+	Acquired time.Time `json:"acquired"`
+
+	// Refreshed is when the run last showed it's still going. The lock goes
+	// stale from then, so long runs keep it.
+	Refreshed time.Time `json:"refreshed"`
+
+	// Token identifies the run which wrote the lock
+	Token string `json:"token"`
+}
+
+func (l InstallLock) String() string {
+	by := l.Owner
+	if l.Host != "" {
+		by += "@" + l.Host
+	}
+	return fmt.Sprintf("%s (PID %d) since %s", by, l.PID, l.Acquired.Local().Format("2006-01-02 15:04:05"))
+}
+
+// lastSeen returns when the run holding the lock last showed it's still going.
+// Locks written before they were refreshed only have the time they were
+// acquired.
+func (l InstallLock) lastSeen() time.Time {
+	if l.Refreshed.After(l.Acquired) {
+		return l.Refreshed
+	}
+	return l.Acquired
+}
+
+// LockedError is returned when another run holds the install lock
+type LockedError struct {
+	Lock InstallLock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("the install is locked by %s; wait for it to finish, or use --break-lock if it was interrupted", e.Lock)
+}
+
+// AcquireLock writes the lock file, unless another run holds the lock. Locks
+// which weren't refreshed for staleAfter are taken over, and so is any lock if breakLock is
+// set. Since the file systems can't create files exclusively, the lock is read
+// back after it's written to check that no other run wrote it at the same
+// time.
+func AcquireLock(fs FileSystem, now time.Time, staleAfter time.Duration, breakLock bool) (InstallLock, error) {
+	held, err := ReadLock(fs)
+	if err != nil && !breakLock {
+		return InstallLock{}, err
+	}
+	if held != nil && !breakLock {
+		if now.Sub(held.lastSeen()) < staleAfter {
+			return InstallLock{}, &LockedError{Lock: *held}
+		}
+		fmt.Printf("Taking over the stale install lock held by %s\n", held)
+	}
+
+	token := make([]byte, 16)
+	if _, err = rand.Read(token); err != nil {
+		return InstallLock{}, err
+	}
+
+	username, hostname := HistoryActor()
+	lock := InstallLock{
+		Owner:     username,
+		Host:      hostname,
+		PID:       os.Getpid(),
+		Acquired:  now,
+		Refreshed: now,
+		Token:     hex.EncodeToString(token),
+	}
+	if err = writeLock(fs, lock); err != nil {
+		return InstallLock{}, err
+	}
+
+	written, err := ReadLock(fs)
+	if err != nil {
+		return InstallLock{}, err
+	}
+	if written == nil || written.Token != lock.Token {
+		if written == nil {
+			written = &InstallLock{}
+		}
+		return InstallLock{}, &LockedError{Lock: *written}
+	}
+	return lock, nil
+}
+
+// RefreshLock writes the lock again with the time given, so it doesn't go
+// stale while the run holding it is still going. Returns a LockedError if
+// another run has taken it over.
+func RefreshLock(fs FileSystem, lock InstallLock, now time.Time) (InstallLock, error) {
+	held, err := ReadLock(fs)
+	if err != nil {
+		return lock, err
+	}
+	if held == nil || held.Token != lock.Token {
+		if held == nil {
+			held = &InstallLock{}
+		}
+		return lock, &LockedError{Lock: *held}
+	}
+
+	lock.Refreshed = now
+	return lock, writeLock(fs, lock)
+}
+
+// KeepLockFresh refreshes the lock in the background, a few times within
+// staleAfter, until the returned function is called. It's called before the
+// lock is released, so the lock isn't written again after. Refreshing stops
+// if another run takes the lock over.
+func KeepLockFresh(fs FileSystem, lock InstallLock, staleAfter time.Duration) (stop func()) {
+	if staleAfter <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(staleAfter / 4)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				refreshed, err := RefreshLock(fs, lock, time.Now())
+				var locked *LockedError
+				if errors.As(err, &locked) {
+					fmt.Printf("The install lock was taken over by %s\n", locked.Lock)
+					return
+				} else if err != nil {
+					// tried again on the next tick
+					fmt.Printf("The install lock couldn't be refreshed: %s\n", err)
+					continue
+				}
+				lock = refreshed
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// ReleaseLock removes the lock file, unless another run has taken it over
+func ReleaseLock(fs FileSystem, lock InstallLock) error {
+	held, err := ReadLock(fs)
+	if err != nil || held == nil || held.Token != lock.Token {
+		return err
+	}
+	return fs.Remove(relLockPath())
+}
+
+// ReadLock returns the lock held on the install, or nil if there is none
+func ReadLock(fs FileSystem) (*InstallLock, error) {
+	b, err := fs.ReadFile(relLockPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	lock := &InstallLock{}
+	if err = json.Unmarshal(b, lock); err != nil {
+		return nil, fmt.Errorf("invalid install lock %s: %w; remove it with --break-lock", relLockPath(), err)
+	}
+	return lock, nil
+}
+
+// GetLockStaleAfter reads how old a lock is before it's stale from Viper
+func GetLockStaleAfter() time.Duration {
+	if ViperInstance.IsSet(LockStaleAfterKey) {
+		return ViperInstance.GetDuration(LockStaleAfterKey)
+	}
+	return DefaultLockStaleAfter
+}
+
+func writeLock(fs FileSystem, lock InstallLock) error {
+	b, err := json.MarshalIndent(lock, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err = fs.MkDirAll(ModFolderName); err != nil {
+		return err
+	}
+	return fs.WriteFile(bytes.NewReader(b), relLockPath())
+}
+
+func relLockPath() string {
+	return filepath.Join(ModFolderName, LockFileName)
+}
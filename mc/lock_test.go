@@ -0,0 +1,149 @@
+package mc_test
+
+import (
+	"errors"
+	"mcmods/mc"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Install Lock", func() {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var afs afero.Fs
+	var fs mc.FileSystem
+
+	BeforeEach(func() {
+		afs = afero.NewMemMapFs()
+		fs = mc.LocalFileSystem{Fs: afs, Dir: "/minecraft"}
+		mc.HistoryActor = func() (string, string) {
+			return "alice", "build-box"
+		}
+	})
+
+	It("writes the owner of the lock, and removes it on release", func() {
+		lock, err := mc.AcquireLock(fs, now, time.Hour, false)
+		Expect(err).To(BeNil())
+
+		held, err := mc.ReadLock(fs)
+		Expect(err).To(BeNil())
+		Expect(*held).To(Equal(lock))
+		Expect(held.Owner).To(Equal("alice"))
+		Expect(held.Host).To(Equal("build-box"))
+		Expect(held.PID).ToNot(BeZero())
+
+		Expect(mc.ReleaseLock(fs, lock)).To(BeNil())
+		held, err = mc.ReadLock(fs)
+		Expect(err).To(BeNil())
+		Expect(held).To(BeNil())
+	})
+
+	It("refuses a lock held by another run", func() {
+		first, err := mc.AcquireLock(fs, now, time.Hour, false)
+		Expect(err).To(BeNil())
+
+		_, err = mc.AcquireLock(fs, now.Add(time.Minute), time.Hour, false)
+
+		var locked *mc.LockedError
+		Expect(errors.As(err, &locked)).To(BeTrue())
+		Expect(locked.Lock).To(Equal(first))
+		Expect(err.Error()).To(ContainSubstring("alice@build-box"))
+	})
+
+	It("takes over stale locks", func() {
+		_, err := mc.AcquireLock(fs, now, time.Hour, false)
+		Expect(err).To(BeNil())
+
+		lock, err := mc.AcquireLock(fs, now.Add(2*time.Hour), time.Hour, false)
+
+		Expect(err).To(BeNil())
+		held, _ := mc.ReadLock(fs)
+		Expect(*held).To(Equal(lock))
+	})
+
+	It("keeps refreshed locks however long ago they were taken", func() {
+		lock, err := mc.AcquireLock(fs, now, time.Hour, false)
+		Expect(err).To(BeNil())
+		_, err = mc.RefreshLock(fs, lock, now.Add(50*time.Minute))
+		Expect(err).To(BeNil())
+
+		_, err = mc.AcquireLock(fs, now.Add(90*time.Minute), time.Hour, false)
+
+		var locked *mc.LockedError
+		Expect(errors.As(err, &locked)).To(BeTrue())
+	})
+
+	It("doesn't refresh a lock another run took over", func() {
+		first, err := mc.AcquireLock(fs, now, time.Hour, false)
+		Expect(err).To(BeNil())
+		second, err := mc.AcquireLock(fs, now, time.Hour, true)
+		Expect(err).To(BeNil())
+
+		_, err = mc.RefreshLock(fs, first, now.Add(time.Minute))
+
+		var locked *mc.LockedError
+		Expect(errors.As(err, &locked)).To(BeTrue())
+		held, _ := mc.ReadLock(fs)
+		Expect(*held).To(Equal(second))
+	})
+
+	It("refreshes the lock in the background until stopped", func() {
+		lock, err := mc.AcquireLock(fs, now, 40*time.Millisecond, false)
+		Expect(err).To(BeNil())
+
+		stop := mc.KeepLockFresh(fs, lock, 40*time.Millisecond)
+		Eventually(func() time.Time {
+			held, _ := mc.ReadLock(fs)
+			return held.Refreshed
+		}).Should(BeTemporally(">", now))
+		stop()
+		Expect(mc.ReleaseLock(fs, lock)).To(BeNil())
+
+		Consistently(func() bool {
+			exists, _ := afero.Exists(afs, filepath.Join("/minecraft", mc.ModFolderName, mc.LockFileName))
+			return exists
+		}, 50*time.Millisecond).Should(BeFalse())
+	})
+
+	It("breaks held and unreadable locks when asked to", func() {
+		Expect(afero.WriteFile(afs, filepath.Join("/minecraft", mc.ModFolderName, mc.LockFileName), []byte("garbage"), 0644)).To(BeNil())
+		_, err := mc.AcquireLock(fs, now, time.Hour, false)
+		Expect(err).ToNot(BeNil())
+
+		_, err = mc.AcquireLock(fs, now, time.Hour, true)
+
+		Expect(err).To(BeNil())
+	})
+
+	It("doesn't release a lock another run took over", func() {
+		first, err := mc.AcquireLock(fs, now, time.Hour, false)
+		Expect(err).To(BeNil())
+		second, err := mc.AcquireLock(fs, now, time.Hour, true)
+		Expect(err).To(BeNil())
+
+		Expect(mc.ReleaseLock(fs, first)).To(BeNil())
+
+		held, _ := mc.ReadLock(fs)
+		Expect(*held).To(Equal(second))
+	})
+
+	It("leaves the lock out of backups", func() {
+		Expect(afero.WriteFile(afs, filepath.Join("/minecraft", mc.ModInstallPath("mod-a")), []byte("a1"), 0644)).To(BeNil())
+		lock, err := mc.AcquireLock(fs, now, time.Hour, false)
+		Expect(err).To(BeNil())
+		store := &mc.BackupStore{Fs: afs, Dir: "/backups"}
+		backup, err := store.Create(fs, now)
+		Expect(err).To(BeNil())
+
+		res, err := store.Restore(fs, backup.ID)
+
+		Expect(err).To(BeNil())
+		Expect(res.Written).To(Equal(1))
+		held, _ := mc.ReadLock(fs)
+		Expect(*held).To(Equal(lock))
+	})
+})
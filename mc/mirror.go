@@ -0,0 +1,151 @@
+package mc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// MirrorResult lists the mods changed by mirroring, by CLI name
+type MirrorResult struct {
+	Copied    []string
+	Deleted   []string
+	Unchanged int
+}
+
+// InstallMirrorer copies installations from one file system to another
+type InstallMirrorer interface {
+	// Mirror makes the managed jars and installation records of the
+	// destination match the source. The destination config is updated, but
+	// not saved.
+	Mirror(src FileSystem, srcCfg *UserModConfig, dst FileSystem, dstCfg *UserModConfig) (MirrorResult, error)
+}
+
+type installMirrorer struct{}
+
+// NewInstallMirrorer returns a new struct which implements InstallMirrorer
+func NewInstallMirrorer() InstallMirrorer {
+	return installMirrorer{}
+}
+
+// Mirror copies each installed jar which differs on the destination, and
+// deletes the jars installed on the destination which aren't installed on the
+// source. Jars the tool didn't install are left alone.
+func (m installMirrorer) Mirror(src FileSystem, srcCfg *UserModConfig, dst FileSystem, dstCfg *UserModConfig) (MirrorResult, error) {
+	res := MirrorResult{Copied: []string{}, Deleted: []string{}}
+	if dstCfg.ModInstallations == nil {
+		dstCfg.ModInstallations = map[string]ModInstallation{}
+	}
+
+	for _, cliName := range sortedInstallations(srcCfg) {
+		srcInstallation := srcCfg.ModInstallations[cliName]
+		dstInstallation, installed := dstCfg.ModInstallations[cliName]
+		relPath := ModInstallPath(cliName)
+
+		upToDate := false
+		if installed {
+			var err error
+			if upToDate, err = mirrorUpToDate(src, dst, relPath, srcInstallation, dstInstallation); err != nil {
+				return res, err
+			}
+		}
+		if upToDate {
+			res.Unchanged++
+			continue
+		}
+
+		fmt.Printf("Copying %s\n", relPath)
+		installation, err := mirrorFile(src, dst, relPath, srcInstallation)
+		if err != nil {
+			return res, err
+		}
+		dstCfg.ModInstallations[cliName] = installation
+		res.Copied = append(res.Copied, cliName)
+	}
+
+	for _, cliName := range sortedInstallations(dstCfg) {
+		if _, ok := srcCfg.ModInstallations[cliName]; ok {
+			continue
+		}
+
+		relPath := ModInstallPath(cliName)
+		fmt.Printf("Deleting %s\n", relPath)
+		if err := dst.Remove(relPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return res, err
+		}
+		delete(dstCfg.ModInstallations, cliName)
+		res.Deleted = append(res.Deleted, cliName)
+	}
+
+	return res, nil
+}
+
+// mirrorUpToDate compares the records' hashes, or download URLs for mods
+// installed before hashes were recorded, and the sizes of the files. Neither
+// file is read.
+func mirrorUpToDate(src, dst FileSystem, relPath string, srcInstallation, dstInstallation ModInstallation) (bool, error) {
+	if srcInstallation.SHA256 != "" || dstInstallation.SHA256 != "" {
+		if !strings.EqualFold(srcInstallation.SHA256, dstInstallation.SHA256) {
+			return false, nil
+		}
+	} else if srcInstallation.DownloadURL != dstInstallation.DownloadURL {
+		return false, nil
+	}
+
+	dstInfo, err := dst.Stat(relPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	srcInfo, err := src.Stat(relPath)
+	if err != nil {
+		return false, mirrorSourceErr(relPath, err)
+	}
+	return srcInfo.Size == dstInfo.Size, nil
+}
+
+// mirrorFile copies the file, and returns the installation record for the
+// destination. The source file must match its record's hash.
+func mirrorFile(src, dst FileSystem, relPath string, installation ModInstallation) (ModInstallation, error) {
+	b, err := src.ReadFile(relPath)
+	if err != nil {
+		return installation, mirrorSourceErr(relPath, err)
+	}
+
+	hash := HashSHA256(b)
+	if installation.SHA256 != "" && !strings.EqualFold(hash, installation.SHA256) {
+		return installation, fmt.Errorf("%s on the source doesn't match its install record; run verify --repair on the source first", relPath)
+	}
+
+	if err = dst.MkDirAll(ModFolderName); err != nil {
+		return installation, err
+	}
+	if err = dst.WriteFile(bytes.NewReader(b), relPath); err != nil {
+		return installation, err
+	}
+
+	installation.SHA256 = hash
+	installation.Size = int64(len(b))
+	return installation, nil
+}
+
+func mirrorSourceErr(relPath string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s is missing on the source; run verify --repair on the source first", relPath)
+	}
+	return err
+}
+
+func sortedInstallations(cfg *UserModConfig) []string {
+	names := make([]string, 0, len(cfg.ModInstallations))
+	for cliName := range cfg.ModInstallations {
+		names = append(names, cliName)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,114 @@
+package mc_test
+
+import (
+	"bytes"
+	"mcmods/mc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Install Mirrorer", func() {
+	var aferoFs afero.Fs
+	var src, dst mc.FileSystem
+	var srcCfg, dstCfg *mc.UserModConfig
+	var mirrorer mc.InstallMirrorer
+
+	content := []byte("jar content")
+	installation := mc.ModInstallation{DownloadURL: "https://a", SHA256: mc.HashSHA256(content), Size: int64(len(content))}
+
+	writeJar := func(fs mc.FileSystem, cliName string, b []byte) {
+		Expect(fs.MkDirAll(mc.ModFolderName)).To(BeNil())
+		Expect(fs.WriteFile(bytes.NewReader(b), mc.ModInstallPath(cliName))).To(BeNil())
+	}
+
+	exists := func(fs mc.FileSystem, cliName string) bool {
+		_, err := fs.Stat(mc.ModInstallPath(cliName))
+		return err == nil
+	}
+
+	BeforeEach(func() {
+		aferoFs = afero.NewMemMapFs()
+		src = mc.LocalFileSystem{Fs: aferoFs, Dir: "/src"}
+		dst = mc.LocalFileSystem{Fs: aferoFs, Dir: "/dst"}
+		mirrorer = mc.NewInstallMirrorer()
+
+		writeJar(src, "mod-a", content)
+		srcCfg = &mc.UserModConfig{ModInstallations: map[string]mc.ModInstallation{"mod-a": installation}}
+		dstCfg = &mc.UserModConfig{ModInstallations: map[string]mc.ModInstallation{}}
+	})
+
+	It("copies the jars and records missing on the destination", func() {
+		res, err := mirrorer.Mirror(src, srcCfg, dst, dstCfg)
+
+		Expect(err).To(BeNil())
+		Expect(res.Copied).To(Equal([]string{"mod-a"}))
+		b, err := dst.ReadFile(mc.ModInstallPath("mod-a"))
+		Expect(err).To(BeNil())
+		Expect(b).To(Equal(content))
+		Expect(dstCfg.ModInstallations["mod-a"]).To(Equal(installation))
+	})
+
+	It("skips jars which match", func() {
+		writeJar(dst, "mod-a", content)
+		dstCfg.ModInstallations["mod-a"] = installation
+
+		res, err := mirrorer.Mirror(src, srcCfg, dst, dstCfg)
+
+		Expect(err).To(BeNil())
+		Expect(res.Copied).To(BeEmpty())
+		Expect(res.Unchanged).To(Equal(1))
+	})
+
+	It("copies jars which differ in hash or size", func() {
+		writeJar(dst, "mod-a", []byte("old"))
+		dstCfg.ModInstallations["mod-a"] = installation
+
+		res, err := mirrorer.Mirror(src, srcCfg, dst, dstCfg)
+
+		Expect(err).To(BeNil())
+		Expect(res.Copied).To(Equal([]string{"mod-a"}))
+		b, _ := dst.ReadFile(mc.ModInstallPath("mod-a"))
+		Expect(b).To(Equal(content))
+	})
+
+	It("compares download URLs for records without hashes", func() {
+		srcCfg.ModInstallations["mod-a"] = mc.ModInstallation{DownloadURL: "https://new"}
+		writeJar(dst, "mod-a", content)
+		dstCfg.ModInstallations["mod-a"] = mc.ModInstallation{DownloadURL: "https://old"}
+
+		res, err := mirrorer.Mirror(src, srcCfg, dst, dstCfg)
+
+		Expect(err).To(BeNil())
+		Expect(res.Copied).To(Equal([]string{"mod-a"}))
+		Expect(dstCfg.ModInstallations["mod-a"].SHA256).To(Equal(installation.SHA256))
+	})
+
+	It("deletes jars managed on the destination but not the source", func() {
+		writeJar(dst, "mod-b", content)
+		writeJar(dst, "unmanaged", content)
+		dstCfg.ModInstallations["mod-b"] = installation
+
+		res, err := mirrorer.Mirror(src, srcCfg, dst, dstCfg)
+
+		Expect(err).To(BeNil())
+		Expect(res.Deleted).To(Equal([]string{"mod-b"}))
+		Expect(exists(dst, "mod-b")).To(BeFalse())
+		Expect(exists(dst, "unmanaged")).To(BeTrue())
+		Expect(dstCfg.ModInstallations).ToNot(HaveKey("mod-b"))
+	})
+
+	It("returns an error when the source jar is missing or changed", func() {
+		srcCfg.ModInstallations["mod-b"] = installation
+
+		_, err := mirrorer.Mirror(src, srcCfg, dst, dstCfg)
+		Expect(err).ToNot(BeNil())
+
+		delete(srcCfg.ModInstallations, "mod-b")
+		writeJar(src, "mod-a", []byte("changed"))
+
+		_, err = mirrorer.Mirror(src, srcCfg, dst, dstCfg)
+		Expect(err).ToNot(BeNil())
+	})
+})
@@ -22,6 +22,21 @@ const (
 
 	// FTPServerKey - The key of the FTP username
 	FTPServerKey = "ftpServer"
+
+	// ServerProtocolKey - The key of the protocol used to connect to the server
+	ServerProtocolKey = "serverProtocol"
+
+	// SFTPKnownHostsKey - The key of the known_hosts file used to verify SFTP servers
+	SFTPKnownHostsKey = "sftpKnownHosts"
+
+	// GameVersionKey - The key of the Minecraft version mods are resolved for
+	GameVersionKey = "gameVersion"
+
+	// ModLoaderKey - The key of the mod loader mods are resolved for
+	ModLoaderKey = "modLoader"
+
+	// DefaultModLoader is the mod loader used when none is configured
+	DefaultModLoader = "fabric"
 )
 
 var (
@@ -64,6 +79,22 @@ type Mod struct {
 	Description  string `json:"description"`
 	DetailsURL   string `json:"detailsUrl"`
 	LatestURL    string `json:"latestUrl"`
+
+	// ProjectID is the upstream project ID or slug used to find new versions
+	ProjectID string `json:"projectId,omitempty"`
+
+	// Hashes of the file at LatestURL, keyed by algorithm (sha1, sha512)
+	Hashes map[string]string `json:"hashes,omitempty"`
+
+	// MirrorURLs are tried in order when LatestURL can't be downloaded. They
+	// must all serve the same file as LatestURL.
+	MirrorURLs []string `json:"mirrorUrls,omitempty"`
+}
+
+// DownloadURLs returns LatestURL followed by the mirror URLs, in the order
+// they should be tried
+func (m Mod) DownloadURLs() []string {
+	return append([]string{m.LatestURL}, m.MirrorURLs...)
 }
 
 // ServerGroup is a logical grouping of Mods on the Server
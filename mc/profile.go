@@ -0,0 +1,189 @@
+package mc
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// ProfilesKey - The key of the named Minecraft instance profiles
+	ProfilesKey = "profiles"
+
+	// ActiveProfileKey - The key of the name of the profile used when none is
+	// given
+	ActiveProfileKey = "activeProfile"
+)
+
+// currentProfile is the profile whose settings override the global ones
+var currentProfile *Profile
+
+// Profile is a named Minecraft instance on this machine, with its own install
+// path, target version and mod selection. Profiles are stored as a list, like
+// remotes.
+type Profile struct {
+	Name        string `mapstructure:"name"`
+	InstallPath string `mapstructure:"installPath"`
+
+	// GameVersion and ModLoader are the versions mods are resolved for. The
+	// global ones are used if they're empty.
+	GameVersion string `mapstructure:"gameVersion"`
+	ModLoader   string `mapstructure:"modLoader"`
+
+	// Groups are the server mod groups installed in the instance. Every group
+	// is installed if it's empty.
+	Groups []string `mapstructure:"groups"`
+
+	// ExcludedMods are the mods which aren't installed in the instance
+	ExcludedMods []string `mapstructure:"excludedMods"`
+}
+
+// ExcludedGroups returns the server mod groups which aren't selected in the
+// profile, sorted by name
+func (p Profile) ExcludedGroups() []string {
+	if len(p.Groups) == 0 {
+		return []string{}
+	}
+
+	selected := toSet(p.Groups)
+	excluded := []string{}
+	for name := range ServerGroups {
+		if !selected[name] {
+			excluded = append(excluded, name)
+		}
+	}
+	sort.Strings(excluded)
+	return excluded
+}
+
+func (p Profile) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"name":         p.Name,
+		"installPath":  p.InstallPath,
+		"gameVersion":  p.GameVersion,
+		"modLoader":    p.ModLoader,
+		"groups":       p.Groups,
+		"excludedMods": p.ExcludedMods,
+	}
+}
+
+// ValidateProfileName returns an error if the name can't be used for a
+// profile
+func ValidateProfileName(name string) error {
+	if !remoteNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use lower case letters, numbers, - and _", name)
+	}
+	return nil
+}
+
+// UseProfile makes the profile's settings override the global ones, or goes
+// back to the global ones if it's nil
+func UseProfile(p *Profile) {
+	currentProfile = p
+}
+
+// CurrentProfile returns the profile in use, or nil if the global settings are
+// used
+func CurrentProfile() *Profile {
+	return currentProfile
+}
+
+// GetProfiles reads the profiles set in Viper, sorted by name
+func GetProfiles() ([]Profile, error) {
+	profiles := []Profile{}
+	if err := ViperInstance.UnmarshalKey(ProfilesKey, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", ProfilesKey, err)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// GetProfile returns the profile with the given name
+func GetProfile(name string) (Profile, error) {
+	profiles, err := GetProfiles()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile: %s", name)
+}
+
+// SetProfile adds the profile to Viper, replacing any profile with the same
+// name. Each profile keeps its installation records in its own mods folder,
+// so profiles can't share an install path. The config isn't written.
+func SetProfile(profile Profile) error {
+	if err := ValidateProfileName(profile.Name); err != nil {
+		return err
+	}
+	if !filepath.IsAbs(profile.InstallPath) {
+		return fmt.Errorf("the install path of profile %s must be absolute: %q", profile.Name, profile.InstallPath)
+	}
+	for _, group := range profile.Groups {
+		if _, ok := ServerGroups[group]; !ok {
+			return NewUnknownGroupError(group)
+		}
+	}
+
+	profiles, err := GetProfiles()
+	if err != nil {
+		return err
+	}
+
+	kept := []Profile{profile}
+	for _, p := range profiles {
+		if p.Name == profile.Name {
+			continue
+		}
+		if filepath.Clean(p.InstallPath) == filepath.Clean(profile.InstallPath) {
+			return fmt.Errorf("profile %s already uses %s; each profile needs its own Minecraft directory", p.Name, p.InstallPath)
+		}
+		kept = append(kept, p)
+	}
+	setProfiles(kept)
+	return nil
+}
+
+// RemoveProfile removes the profile from Viper, and unsets it as the active
+// one. The config isn't written.
+func RemoveProfile(name string) error {
+	profiles, err := GetProfiles()
+	if err != nil {
+		return err
+	}
+
+	kept := []Profile{}
+	for _, p := range profiles {
+		if p.Name != name {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == len(profiles) {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+
+	setProfiles(kept)
+	if ViperInstance.GetString(ActiveProfileKey) == name {
+		ViperInstance.Set(ActiveProfileKey, "")
+	}
+	return nil
+}
+
+func setProfiles(profiles []Profile) {
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	maps := make([]map[string]interface{}, 0, len(profiles))
+	for _, p := range profiles {
+		maps = append(maps, p.toMap())
+	}
+	ViperInstance.Set(ProfilesKey, maps)
+}
@@ -0,0 +1,104 @@
+package mc_test
+
+import (
+	"mcmods/mc"
+	. "mcmods/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Profiles", func() {
+	vanilla := mc.Profile{Name: "vanilla-plus", InstallPath: "/home/me/.minecraft", GameVersion: "1.18.1"}
+	creative := mc.Profile{Name: "creative", InstallPath: "/home/me/creative", Groups: []string{"required"}, ExcludedMods: []string{"mod1"}}
+
+	BeforeEach(func() {
+		InitTestData()
+		mc.ServerGroups = TestingServerGroups
+		mc.ViperInstance.Set(mc.ProfilesKey, []interface{}{})
+		mc.ViperInstance.Set(mc.ActiveProfileKey, "")
+	})
+
+	AfterEach(func() {
+		mc.UseProfile(nil)
+	})
+
+	It("adds profiles, sorted by name", func() {
+		Expect(mc.SetProfile(vanilla)).To(BeNil())
+		Expect(mc.SetProfile(creative)).To(BeNil())
+
+		profiles, err := mc.GetProfiles()
+
+		Expect(err).To(BeNil())
+		Expect(profiles).To(HaveLen(2))
+		Expect(profiles[0].Name).To(Equal(creative.Name))
+		Expect(profiles[0].Groups).To(Equal(creative.Groups))
+		Expect(profiles[0].ExcludedMods).To(Equal(creative.ExcludedMods))
+		Expect(profiles[1].Name).To(Equal(vanilla.Name))
+		Expect(profiles[1].GameVersion).To(Equal(vanilla.GameVersion))
+	})
+
+	It("replaces profiles with the same name", func() {
+		Expect(mc.SetProfile(vanilla)).To(BeNil())
+		updated := vanilla
+		updated.InstallPath = "/home/me/other"
+
+		Expect(mc.SetProfile(updated)).To(BeNil())
+
+		p, err := mc.GetProfile(vanilla.Name)
+		Expect(err).To(BeNil())
+		Expect(p.InstallPath).To(Equal(updated.InstallPath))
+	})
+
+	It("rejects invalid profiles", func() {
+		invalid := vanilla
+		invalid.Name = "Vanilla Plus"
+		Expect(mc.SetProfile(invalid)).ToNot(BeNil())
+
+		invalid = vanilla
+		invalid.InstallPath = "relative/.minecraft"
+		Expect(mc.SetProfile(invalid)).ToNot(BeNil())
+
+		invalid = vanilla
+		invalid.Groups = []string{"unknown"}
+		Expect(mc.SetProfile(invalid)).ToNot(BeNil())
+	})
+
+	It("doesn't let profiles share an install path, so their records stay separate", func() {
+		Expect(mc.SetProfile(vanilla)).To(BeNil())
+		other := creative
+		other.InstallPath = vanilla.InstallPath + "/"
+
+		err := mc.SetProfile(other)
+
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring(vanilla.Name))
+	})
+
+	It("removes profiles and unsets the active one", func() {
+		Expect(mc.SetProfile(vanilla)).To(BeNil())
+		mc.ViperInstance.Set(mc.ActiveProfileKey, vanilla.Name)
+
+		Expect(mc.RemoveProfile(vanilla.Name)).To(BeNil())
+
+		_, err := mc.GetProfile(vanilla.Name)
+		Expect(err).ToNot(BeNil())
+		Expect(mc.ViperInstance.GetString(mc.ActiveProfileKey)).To(BeEmpty())
+		Expect(mc.RemoveProfile(vanilla.Name)).ToNot(BeNil())
+	})
+
+	It("excludes the groups which aren't selected", func() {
+		Expect(creative.ExcludedGroups()).To(Equal([]string{"optional", "performance", "server-only"}))
+		Expect(vanilla.ExcludedGroups()).To(BeEmpty())
+	})
+
+	It("uses the install path of the profile in use", func() {
+		mc.ViperInstance.Set(mc.InstallPathKey, "/global/.minecraft")
+
+		mc.UseProfile(&creative)
+		Expect(mc.GetInstallPath()).To(Equal(creative.InstallPath))
+
+		mc.UseProfile(nil)
+		Expect(mc.GetInstallPath()).To(Equal("/global/.minecraft"))
+	})
+})
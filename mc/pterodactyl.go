@@ -0,0 +1,399 @@
+package mc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// PterodactylProtocol is the protocol name for the file API of a
+	// Pterodactyl-compatible hosting panel
+	PterodactylProtocol = "pterodactyl"
+
+	// pterodactylUploadSuffix is added to the names of files while they're
+	// being uploaded, so a failed upload doesn't replace the file
+	pterodactylUploadSuffix = ".mcmods-upload"
+
+	// pterodactylReplacedSuffix is added to the names of files while they're
+	// being replaced by a rename
+	pterodactylReplacedSuffix = ".mcmods-replaced"
+)
+
+// PterodactylFileSystem is used to interact with Minecraft servers through the
+// client file API of a Pterodactyl-compatible hosting panel. Paths are
+// relative to the server's root directory.
+type PterodactylFileSystem struct {
+	// PanelURL is the base URL of the panel, e.g. https://panel.example.com
+	PanelURL string
+
+	// ServerID is the panel's identifier of the server
+	ServerID string
+
+	// APIKey is a client API key of a panel user with access to the server
+	APIKey string
+
+	// BaseDir is the Minecraft directory on the server. Relative to the
+	// server's root directory.
+	BaseDir string
+
+	Client *http.Client
+}
+
+// pterodactylFileObject describes a file in the panel's list responses
+type pterodactylFileObject struct {
+	Attributes struct {
+		Name       string `json:"name"`
+		Size       int64  `json:"size"`
+		IsFile     bool   `json:"is_file"`
+		ModifiedAt string `json:"modified_at"`
+	} `json:"attributes"`
+}
+
+// pterodactylSignedURL is the response of the download and upload endpoints
+type pterodactylSignedURL struct {
+	Attributes struct {
+		URL string `json:"url"`
+	} `json:"attributes"`
+}
+
+// pterodactylErrors is the body of the panel's error responses
+type pterodactylErrors struct {
+	Errors []struct {
+		Detail string `json:"detail"`
+	} `json:"errors"`
+}
+
+// WriteFile uploads the file under a temporary name, then replaces the file
+// at the given path with it.
+func (p PterodactylFileSystem) WriteFile(r io.Reader, relPath string) error {
+	dir, name := path.Split(p.panelPath(relPath))
+	tmpName := name + pterodactylUploadSuffix
+
+	signed := pterodactylSignedURL{}
+	if err := p.call(http.MethodGet, "upload", nil, nil, &signed); err != nil {
+		return err
+	}
+
+	uploadURL, err := url.Parse(signed.Attributes.URL)
+	if err != nil {
+		return err
+	}
+	query := uploadURL.Query()
+	query.Set("directory", dir)
+	uploadURL.RawQuery = query.Encode()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	part, err := mw.CreateFormFile("files", tmpName)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, r); err != nil {
+		return err
+	}
+	if err = mw.Close(); err != nil {
+		return err
+	}
+
+	// the signed URL authenticates the upload, so the API key isn't sent
+	resp, err := p.Client.Post(uploadURL.String(), mw.FormDataContentType(), body)
+	if err != nil {
+		return err
+	}
+	if err = pterodactylResponseErr(resp, "upload"); err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return p.rename(dir, tmpName, name)
+}
+
+// ReadFile downloads the file at the given path.
+func (p PterodactylFileSystem) ReadFile(relPath string) ([]byte, error) {
+	signed := pterodactylSignedURL{}
+	query := url.Values{"file": {p.panelPath(relPath)}}
+	if err := p.call(http.MethodGet, "download", query, nil, &signed); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Client.Get(signed.Attributes.URL)
+	if err != nil {
+		return nil, err
+	}
+	if err = pterodactylResponseErr(resp, "download"); err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// MkDirAll creates all non-existant folders in the given path.
+func (p PterodactylFileSystem) MkDirAll(relPath string) error {
+	dir := p.panelPath(relPath)
+	if dir == "/" {
+		return nil
+	}
+
+	// the panel creates the parent folders as well
+	return p.call(http.MethodPost, "create-folder", nil, map[string]string{
+		"root": "/",
+		"name": strings.TrimPrefix(dir, "/"),
+	}, nil)
+}
+
+// ReadDir lists the directory at the given path, sorted by name.
+func (p PterodactylFileSystem) ReadDir(relPath string) ([]FileInfo, error) {
+	return p.list(p.panelPath(relPath))
+}
+
+// Stat describes the file at the given path, by listing its directory.
+func (p PterodactylFileSystem) Stat(relPath string) (FileInfo, error) {
+	panelPath := p.panelPath(relPath)
+	if panelPath == "/" {
+		return FileInfo{Name: "/", IsDir: true}, nil
+	}
+
+	return p.stat(path.Split(panelPath))
+}
+
+// Remove deletes the file at the given path.
+func (p PterodactylFileSystem) Remove(relPath string) error {
+	dir, name := path.Split(p.panelPath(relPath))
+	return p.remove(dir, name)
+}
+
+// Rename moves the file at the given path. The panel won't replace a file, so
+// it's moved aside first, and deleted once the file is in its place.
+func (p PterodactylFileSystem) Rename(oldRelPath, newRelPath string) error {
+	oldDir, oldName := path.Split(p.panelPath(oldRelPath))
+	newDir, newName := path.Split(p.panelPath(newRelPath))
+	if oldDir == newDir {
+		return p.rename(oldDir, oldName, newName)
+	}
+	return p.rename("/", strings.TrimPrefix(oldDir+oldName, "/"), strings.TrimPrefix(newDir+newName, "/"))
+}
+
+// Close closes the idle connections to the panel
+func (p PterodactylFileSystem) Close() {
+	p.Client.CloseIdleConnections()
+}
+
+// stat finds the file in its directory's listing, since the panel can't
+// describe a single file
+func (p PterodactylFileSystem) stat(dir, name string) (FileInfo, error) {
+	infos, err := p.list(dir)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	for _, fi := range infos {
+		if fi.Name == name {
+			return fi, nil
+		}
+	}
+	return FileInfo{}, os.ErrNotExist
+}
+
+// remove deletes the file, or returns os.ErrNotExist if there isn't one, since
+// the panel doesn't report missing files
+func (p PterodactylFileSystem) remove(dir, name string) error {
+	if _, err := p.stat(dir, name); err != nil {
+		return err
+	}
+	return p.call(http.MethodPost, "delete", nil, map[string]interface{}{
+		"root":  dir,
+		"files": []string{name},
+	}, nil)
+}
+
+// rename replaces the file at the to path, relative to the directory, with the
+// file at the from path. The file being replaced is moved aside rather than
+// deleted first, so a run which stops halfway leaves it at one of the paths.
+func (p PterodactylFileSystem) rename(dir, from, to string) error {
+	toDir, toName := path.Split(path.Join(dir, to))
+	aside := to + pterodactylReplacedSuffix
+
+	_, err := p.stat(toDir, toName)
+	replacing := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if replacing {
+		// left behind by a run which stopped before deleting it
+		if err = p.remove(toDir, toName+pterodactylReplacedSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err = p.move(dir, to, aside); err != nil {
+			return err
+		}
+	}
+
+	if err = p.move(dir, from, to); err != nil {
+		if replacing {
+			p.move(dir, aside, to)
+		}
+		return err
+	}
+	if replacing {
+		// the file is in place, so a copy left behind is only clutter
+		p.remove(toDir, toName+pterodactylReplacedSuffix)
+	}
+	return nil
+}
+
+// move renames the file at the from path, relative to the directory, to the
+// to path, which mustn't exist
+func (p PterodactylFileSystem) move(dir, from, to string) error {
+	return p.call(http.MethodPut, "rename", nil, map[string]interface{}{
+		"root":  dir,
+		"files": []map[string]string{{"from": from, "to": to}},
+	}, nil)
+}
+
+func (p PterodactylFileSystem) list(dir string) ([]FileInfo, error) {
+	list := struct {
+		Data []pterodactylFileObject `json:"data"`
+	}{}
+	if err := p.call(http.MethodGet, "list", url.Values{"directory": {dir}}, nil, &list); err != nil {
+		return nil, err
+	}
+
+	infos := make([]FileInfo, 0, len(list.Data))
+	for _, obj := range list.Data {
+		modTime, _ := time.Parse(time.RFC3339, obj.Attributes.ModifiedAt)
+		infos = append(infos, FileInfo{
+			Name:    obj.Attributes.Name,
+			Size:    obj.Attributes.Size,
+			ModTime: modTime,
+			IsDir:   !obj.Attributes.IsFile,
+		})
+	}
+	sortFileInfos(infos)
+	return infos, nil
+}
+
+// call sends a request to a files endpoint of the client API, and decodes
+// the JSON response into out if it isn't nil
+func (p PterodactylFileSystem) call(method, endpoint string, query url.Values, in interface{}, out interface{}) error {
+	u := fmt.Sprintf("%s/api/client/servers/%s/files/%s", strings.TrimSuffix(p.PanelURL, "/"), url.PathEscape(p.ServerID), endpoint)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err = pterodactylResponseErr(resp, endpoint); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// pterodactylResponseErr returns os.ErrNotExist for 404 responses, or an error
+// with the panel's details for other failures. The body is closed on errors.
+func pterodactylResponseErr(resp *http.Response, endpoint string) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return os.ErrNotExist
+	}
+
+	errs := pterodactylErrors{}
+	json.NewDecoder(resp.Body).Decode(&errs)
+	details := []string{}
+	for _, e := range errs.Errors {
+		if e.Detail != "" {
+			details = append(details, e.Detail)
+		}
+	}
+
+	if len(details) == 0 {
+		return fmt.Errorf("panel %s request failed: %s", endpoint, resp.Status)
+	}
+	return fmt.Errorf("panel %s request failed: %s: %s", endpoint, resp.Status, strings.Join(details, "; "))
+}
+
+func (p PterodactylFileSystem) baseDir() string {
+	return strings.Trim(filepath.ToSlash(p.BaseDir), "/")
+}
+
+func (p PterodactylFileSystem) panelPath(relPath string) string {
+	return path.Clean("/" + path.Join(p.baseDir(), filepath.ToSlash(relPath)))
+}
+
+func openPterodactylToServer(args *FTPArgs) (FileSystem, error) {
+	if args.Pw == "" || args.User == "" || args.Server == "" {
+		return nil, errors.New("panel access requires the panel URL as the server, the server identifier as the user, and an API key as the password")
+	}
+
+	panelURL := args.Server
+	if !strings.Contains(panelURL, "://") {
+		panelURL = "https://" + panelURL
+	}
+
+	timeout := time.Duration(args.TimeoutMs) * time.Millisecond
+	p := PterodactylFileSystem{
+		PanelURL: panelURL,
+		ServerID: args.User,
+		APIKey:   args.Pw,
+		BaseDir:  args.BaseDir,
+		Client: &http.Client{Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: timeout}).DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: 2 * time.Minute,
+		}},
+	}
+
+	// list the base directory to check the key and server before going on
+	fmt.Printf("Connecting to the panel at %s\n", panelURL)
+	if _, err := p.list(p.panelPath("")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("the server %s or its directory %s wasn't found on the panel", p.ServerID, p.panelPath(""))
+		}
+		return nil, err
+	}
+	return p, nil
+}
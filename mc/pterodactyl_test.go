@@ -0,0 +1,244 @@
+package mc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mcmods/mc"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+// fakePanel is a stand-in for the client file API of a Pterodactyl panel,
+// serving the files of an afero file system
+type fakePanel struct {
+	Fs     afero.Fs
+	Server *httptest.Server
+
+	// FailRenamesFrom makes renames of the file at the path fail
+	FailRenamesFrom string
+}
+
+func newFakePanel() *fakePanel {
+	p := &fakePanel{Fs: afero.NewMemMapFs()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/client/servers/srv1/files/", p.serveAPI)
+	mux.HandleFunc("/signed/download", func(w http.ResponseWriter, r *http.Request) {
+		b, err := afero.ReadFile(p.Fs, r.URL.Query().Get("file"))
+		if err != nil {
+			panelError(w, http.StatusNotFound, "file not found")
+			return
+		}
+		w.Write(b)
+	})
+	mux.HandleFunc("/signed/upload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			panelError(w, http.StatusBadRequest, "the API key was sent to the signed URL")
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			panelError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		for _, fh := range r.MultipartForm.File["files"] {
+			f, _ := fh.Open()
+			b, _ := ioutil.ReadAll(f)
+			afero.WriteFile(p.Fs, path.Join(r.URL.Query().Get("directory"), fh.Filename), b, 0644)
+		}
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *fakePanel) serveAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer key" {
+		panelError(w, http.StatusUnauthorized, "Unauthenticated.")
+		return
+	}
+
+	body := struct {
+		Root  string          `json:"root"`
+		Name  string          `json:"name"`
+		Files json.RawMessage `json:"files"`
+	}{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	query := r.URL.Query()
+	switch endpoint := path.Base(r.URL.Path); endpoint {
+	case "list":
+		fis, err := afero.ReadDir(p.Fs, query.Get("directory"))
+		if err != nil {
+			panelError(w, http.StatusNotFound, "directory not found")
+			return
+		}
+		data := []interface{}{}
+		for _, fi := range fis {
+			data = append(data, map[string]interface{}{
+				"object":     "file_object",
+				"attributes": map[string]interface{}{"name": fi.Name(), "size": fi.Size(), "is_file": !fi.IsDir(), "modified_at": fi.ModTime().Format("2006-01-02T15:04:05-07:00")},
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": data})
+	case "download":
+		signedURL(w, p.Server.URL+"/signed/download?file="+url.QueryEscape(query.Get("file")))
+	case "upload":
+		signedURL(w, p.Server.URL+"/signed/upload?token=abc")
+	case "create-folder":
+		p.Fs.MkdirAll(path.Join(body.Root, body.Name), 0755)
+	case "delete":
+		names := []string{}
+		json.Unmarshal(body.Files, &names)
+		for _, name := range names {
+			p.Fs.Remove(path.Join(body.Root, name))
+		}
+	case "rename":
+		renames := []struct{ From, To string }{}
+		json.Unmarshal(body.Files, &renames)
+		for _, rename := range renames {
+			if path.Join(body.Root, rename.From) == p.FailRenamesFrom {
+				panelError(w, http.StatusInternalServerError, "the rename failed")
+				return
+			}
+			// like the panel, renaming over an existing file fails
+			if exists, _ := afero.Exists(p.Fs, path.Join(body.Root, rename.To)); exists {
+				panelError(w, http.StatusBadRequest, "a file with that name already exists")
+				return
+			}
+			p.Fs.Rename(path.Join(body.Root, rename.From), path.Join(body.Root, rename.To))
+		}
+	default:
+		panelError(w, http.StatusNotFound, "unknown endpoint "+endpoint)
+	}
+}
+
+func signedURL(w http.ResponseWriter, u string) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"object": "signed_url", "attributes": map[string]string{"url": u}})
+}
+
+func panelError(w http.ResponseWriter, status int, detail string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"errors": []map[string]string{{"detail": detail}}})
+}
+
+var _ = Describe("Pterodactyl File System", func() {
+	var panel *fakePanel
+	var args *mc.FTPArgs
+
+	content := []byte("jar content")
+
+	BeforeEach(func() {
+		panel = newFakePanel()
+		Expect(panel.Fs.MkdirAll("/minecraft", 0755)).To(BeNil())
+
+		args = &mc.FTPArgs{Protocol: mc.PterodactylProtocol, Server: panel.Server.URL, User: "srv1", Pw: "key", BaseDir: "minecraft", TimeoutMs: 1000}
+	})
+
+	AfterEach(func() {
+		panel.Server.Close()
+	})
+
+	openPanel := func() mc.FileSystem {
+		fs, err := mc.NewFs(args)
+		Expect(err).To(BeNil())
+		return fs
+	}
+
+	It("uploads files and reads them back", func() {
+		fs := openPanel()
+		defer fs.Close()
+
+		Expect(fs.MkDirAll(mc.ModFolderName)).To(BeNil())
+		Expect(fs.WriteFile(bytes.NewReader(content), mc.ModInstallPath("mod-a"))).To(BeNil())
+
+		b, err := fs.ReadFile(mc.ModInstallPath("mod-a"))
+		Expect(err).To(BeNil())
+		Expect(b).To(Equal(content))
+		b, _ = afero.ReadFile(panel.Fs, "/minecraft/mods/mod-a.jar")
+		Expect(b).To(Equal(content))
+	})
+
+	It("replaces existing files without leaving the upload behind", func() {
+		Expect(afero.WriteFile(panel.Fs, "/minecraft/mods/mod-a.jar", []byte("old"), 0644)).To(BeNil())
+		fs := openPanel()
+
+		Expect(fs.WriteFile(bytes.NewReader(content), mc.ModInstallPath("mod-a"))).To(BeNil())
+
+		infos, err := fs.ReadDir(mc.ModFolderName)
+		Expect(err).To(BeNil())
+		Expect(infos).To(HaveLen(1))
+		Expect(infos[0].Name).To(Equal("mod-a.jar"))
+		Expect(infos[0].Size).To(Equal(int64(len(content))))
+	})
+
+	It("renames over existing files", func() {
+		Expect(afero.WriteFile(panel.Fs, "/minecraft/mods/mod-a.tmp", content, 0644)).To(BeNil())
+		Expect(afero.WriteFile(panel.Fs, "/minecraft/mods/mod-a.jar", []byte("old"), 0644)).To(BeNil())
+		fs := openPanel()
+
+		Expect(fs.Rename(mc.ModFolderName+"/mod-a.tmp", mc.ModInstallPath("mod-a"))).To(BeNil())
+
+		b, _ := afero.ReadFile(panel.Fs, "/minecraft/mods/mod-a.jar")
+		Expect(b).To(Equal(content))
+		infos, _ := afero.ReadDir(panel.Fs, "/minecraft/mods")
+		Expect(infos).To(HaveLen(1), "the upload and the replaced file aren't left behind")
+	})
+
+	It("keeps the existing file when renaming over it fails", func() {
+		Expect(afero.WriteFile(panel.Fs, "/minecraft/mods/mod-a.tmp", content, 0644)).To(BeNil())
+		Expect(afero.WriteFile(panel.Fs, "/minecraft/mods/mod-a.jar", []byte("old"), 0644)).To(BeNil())
+		panel.FailRenamesFrom = "/minecraft/mods/mod-a.tmp"
+		fs := openPanel()
+
+		Expect(fs.Rename(mc.ModFolderName+"/mod-a.tmp", mc.ModInstallPath("mod-a"))).ToNot(BeNil())
+
+		b, _ := afero.ReadFile(panel.Fs, "/minecraft/mods/mod-a.jar")
+		Expect(string(b)).To(Equal("old"))
+		infos, _ := afero.ReadDir(panel.Fs, "/minecraft/mods")
+		Expect(infos).To(HaveLen(2), "nothing else is left behind")
+	})
+
+	It("describes and removes files", func() {
+		Expect(afero.WriteFile(panel.Fs, "/minecraft/mods/mod-a.jar", content, 0644)).To(BeNil())
+		fs := openPanel()
+
+		fi, err := fs.Stat(mc.ModInstallPath("mod-a"))
+		Expect(err).To(BeNil())
+		Expect(fi.Size).To(Equal(int64(len(content))))
+		Expect(fi.IsDir).To(BeFalse())
+
+		Expect(fs.Remove(mc.ModInstallPath("mod-a"))).To(BeNil())
+
+		_, err = fs.Stat(mc.ModInstallPath("mod-a"))
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		Expect(errors.Is(fs.Remove(mc.ModInstallPath("mod-a")), os.ErrNotExist)).To(BeTrue())
+		_, err = fs.ReadFile(mc.ModInstallPath("mod-a"))
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+	})
+
+	It("returns the panel's error when the API key is rejected", func() {
+		args.Pw = "wrong"
+
+		_, err := mc.NewFs(args)
+
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("Unauthenticated."))
+	})
+
+	It("returns an error when the server or its directory isn't found", func() {
+		args.BaseDir = "other"
+
+		_, err := mc.NewFs(args)
+
+		Expect(err).ToNot(BeNil())
+		Expect(err.Error()).To(ContainSubstring("wasn't found on the panel"))
+	})
+})
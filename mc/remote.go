@@ -0,0 +1,146 @@
+package mc
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+const (
+	// RemotesKey - The key of the named server profiles
+	RemotesKey = "remotes"
+
+	// DefaultRemoteKey - The key of the name of the remote used by default
+	DefaultRemoteKey = "defaultRemote"
+)
+
+var remoteNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Remote is a named profile of a server to install mods on. Remotes are stored
+// as a list, since viper can't remove keys from maps in the config file.
+type Remote struct {
+	Name     string `mapstructure:"name"`
+	Server   string `mapstructure:"server"`
+	User     string `mapstructure:"user"`
+	Protocol string `mapstructure:"protocol"`
+
+	// BaseDir is the Minecraft directory on the server, which contains the mods
+	// folder. Relative to the login directory unless absolute.
+	BaseDir string `mapstructure:"baseDir"`
+}
+
+// FTPArgs creates the args for connecting to the remote
+func (r Remote) FTPArgs() *FTPArgs {
+	return &FTPArgs{
+		Remote:   r.Name,
+		Server:   r.Server,
+		User:     r.User,
+		Protocol: r.Protocol,
+		BaseDir:  r.BaseDir,
+	}
+}
+
+func (r Remote) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"name":     r.Name,
+		"server":   r.Server,
+		"user":     r.User,
+		"protocol": r.Protocol,
+		"baseDir":  r.BaseDir,
+	}
+}
+
+// ValidateRemoteName returns an error if the name can't be used for a remote
+func ValidateRemoteName(name string) error {
+	if !remoteNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid remote name %q: use lower case letters, numbers, - and _", name)
+	}
+	return nil
+}
+
+// GetRemotes reads the remotes set in Viper, sorted by name
+func GetRemotes() ([]Remote, error) {
+	remotes := []Remote{}
+	if err := ViperInstance.UnmarshalKey(RemotesKey, &remotes); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", RemotesKey, err)
+	}
+
+	sort.Slice(remotes, func(i, j int) bool {
+		return remotes[i].Name < remotes[j].Name
+	})
+	return remotes, nil
+}
+
+// GetRemote returns the remote with the given name
+func GetRemote(name string) (Remote, error) {
+	remotes, err := GetRemotes()
+	if err != nil {
+		return Remote{}, err
+	}
+
+	for _, r := range remotes {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return Remote{}, fmt.Errorf("unknown remote: %s", name)
+}
+
+// SetRemote adds the remote to Viper, replacing any remote with the same name.
+// The config isn't written.
+func SetRemote(remote Remote) error {
+	if err := ValidateRemoteName(remote.Name); err != nil {
+		return err
+	}
+
+	remotes, err := GetRemotes()
+	if err != nil {
+		return err
+	}
+
+	kept := []Remote{remote}
+	for _, r := range remotes {
+		if r.Name != remote.Name {
+			kept = append(kept, r)
+		}
+	}
+	setRemotes(kept)
+	return nil
+}
+
+// RemoveRemote removes the remote from Viper, and unsets it as the default. The
+// config isn't written.
+func RemoveRemote(name string) error {
+	remotes, err := GetRemotes()
+	if err != nil {
+		return err
+	}
+
+	kept := []Remote{}
+	for _, r := range remotes {
+		if r.Name != name {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == len(remotes) {
+		return fmt.Errorf("unknown remote: %s", name)
+	}
+
+	setRemotes(kept)
+	if ViperInstance.GetString(DefaultRemoteKey) == name {
+		ViperInstance.Set(DefaultRemoteKey, "")
+	}
+	return nil
+}
+
+func setRemotes(remotes []Remote) {
+	sort.Slice(remotes, func(i, j int) bool {
+		return remotes[i].Name < remotes[j].Name
+	})
+
+	maps := make([]map[string]interface{}, 0, len(remotes))
+	for _, r := range remotes {
+		maps = append(maps, r.toMap())
+	}
+	ViperInstance.Set(RemotesKey, maps)
+}
@@ -0,0 +1,80 @@
+package mc_test
+
+import (
+	"mcmods/mc"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Remotes", func() {
+	survival := mc.Remote{Name: "survival", Server: "mc.example.com:21", User: "admin", BaseDir: "/minecraft"}
+	creative := mc.Remote{Name: "creative", Server: "sftp://test.example.com", User: "builder", Protocol: mc.SFTPProtocol}
+
+	BeforeEach(func() {
+		mc.ViperInstance.Set(mc.RemotesKey, []interface{}{})
+		mc.ViperInstance.Set(mc.DefaultRemoteKey, "")
+	})
+
+	It("returns no remotes when none are set", func() {
+		remotes, err := mc.GetRemotes()
+
+		Expect(err).To(BeNil())
+		Expect(remotes).To(BeEmpty())
+	})
+
+	It("adds remotes, sorted by name", func() {
+		Expect(mc.SetRemote(survival)).To(BeNil())
+		Expect(mc.SetRemote(creative)).To(BeNil())
+
+		remotes, err := mc.GetRemotes()
+
+		Expect(err).To(BeNil())
+		Expect(remotes).To(Equal([]mc.Remote{creative, survival}))
+	})
+
+	It("replaces remotes with the same name", func() {
+		Expect(mc.SetRemote(survival)).To(BeNil())
+		updated := survival
+		updated.User = "other"
+
+		Expect(mc.SetRemote(updated)).To(BeNil())
+
+		r, err := mc.GetRemote(survival.Name)
+		Expect(err).To(BeNil())
+		Expect(r).To(Equal(updated))
+	})
+
+	It("rejects invalid names", func() {
+		invalid := survival
+		invalid.Name = "Survival Server"
+
+		Expect(mc.SetRemote(invalid)).ToNot(BeNil())
+	})
+
+	It("returns an error for unknown remotes", func() {
+		_, err := mc.GetRemote("unknown")
+
+		Expect(err).ToNot(BeNil())
+		Expect(mc.RemoveRemote("unknown")).ToNot(BeNil())
+	})
+
+	It("removes remotes and unsets the default", func() {
+		Expect(mc.SetRemote(survival)).To(BeNil())
+		Expect(mc.SetRemote(creative)).To(BeNil())
+		mc.ViperInstance.Set(mc.DefaultRemoteKey, survival.Name)
+
+		Expect(mc.RemoveRemote(survival.Name)).To(BeNil())
+
+		remotes, err := mc.GetRemotes()
+		Expect(err).To(BeNil())
+		Expect(remotes).To(Equal([]mc.Remote{creative}))
+		Expect(mc.ViperInstance.GetString(mc.DefaultRemoteKey)).To(BeEmpty())
+	})
+
+	It("converts to FTP args", func() {
+		args := survival.FTPArgs()
+
+		Expect(*args).To(Equal(mc.FTPArgs{Remote: survival.Name, Server: survival.Server, User: survival.User, BaseDir: survival.BaseDir}))
+	})
+})
@@ -0,0 +1,161 @@
+package mc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// ModConfigCorruptSuffix is added to the user config file name for the copy of
+// a corrupt file which was repaired
+const ModConfigCorruptSuffix = ".corrupt"
+
+// ErrConfigNotCorrupt is returned when repairing a user config file which can
+// be loaded
+var ErrConfigNotCorrupt = errors.New("the install config isn't corrupt, so there's nothing to repair")
+
+// ConfigRepair is the result of rebuilding a corrupt user config file
+type ConfigRepair struct {
+	// Config is the rebuilt config. It isn't written to the target, so it can
+	// be saved like any other change.
+	Config *UserModConfig
+
+	// FromBackup is set if the config is the last good copy of the file.
+	// Otherwise, the installations were rebuilt from the mods folder.
+	FromBackup bool
+
+	// Recovered are the mods whose installations were rebuilt from the mods
+	// folder. Matched are the ones whose record was found in the history.
+	Recovered []string
+	Matched   []string
+
+	// Skipped are the files in the mods folder which aren't known mods
+	Skipped []string
+}
+
+// RepairConfig rebuilds a user config file which can't be parsed. The last
+// good copy is used if there is one and scan isn't set. Otherwise, the
+// installations are rebuilt from the jars in the mods folder: a jar which is
+// the last file the history has for its mod gets that record back, and other
+// jars of known mods are recorded without a download URL, so they're replaced
+// on the next install. The client mods are kept from the backup, if there is
+// one. The corrupt file is kept next to the config.
+func RepairConfig(fs FileSystem, scan bool) (ConfigRepair, error) {
+	res := ConfigRepair{}
+
+	_, err := NewUserModConfigIo(fs).LoadOrNew()
+	corrupt := &CorruptConfigError{}
+	if err == nil {
+		return res, ErrConfigNotCorrupt
+	} else if !errors.As(err, &corrupt) {
+		return res, err
+	}
+
+	b, err := fs.ReadFile(relUserConfigPath())
+	if err != nil {
+		return res, err
+	}
+	if err = fs.WriteFile(bytes.NewReader(b), relUserConfigPath()+ModConfigCorruptSuffix); err != nil {
+		return res, err
+	}
+
+	backup, err := loadConfigBackup(fs)
+	if err != nil {
+		fmt.Printf("The backup of the install config can't be used: %v\n", err)
+	}
+	if backup != nil && !scan {
+		res.Config = backup
+		res.FromBackup = true
+		return res, nil
+	}
+
+	cfg := NewUserModConfig()
+	if backup != nil {
+		cfg.ClientMods = backup.ClientMods
+	}
+	res.Config = &cfg
+	return res, scanInstallations(fs, &res)
+}
+
+// loadConfigBackup loads the last good copy of the config file, or returns nil
+// if there isn't one
+func loadConfigBackup(fs FileSystem) (*UserModConfig, error) {
+	b, err := fs.ReadFile(relUserConfigPath() + ModConfigBackupSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// the backup is loaded like the config, so older ones are migrated
+	records := LocalFileSystem{Fs: afero.NewMemMapFs(), Dir: "/"}
+	if err = records.MkDirAll(ModFolderName); err != nil {
+		return nil, err
+	}
+	if err = records.WriteFile(bytes.NewReader(b), relUserConfigPath()); err != nil {
+		return nil, err
+	}
+	return NewUserModConfigIo(records).LoadOrNew()
+}
+
+// scanInstallations records the jars in the mods folder in the repaired
+// config
+func scanInstallations(fs FileSystem, res *ConfigRepair) error {
+	infos, err := fs.ReadDir(ModFolderName)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	history, err := ReadHistory(fs)
+	if err != nil {
+		fmt.Printf("The install history can't be used: %v\n", err)
+		history = []HistoryEvent{}
+	}
+	last := map[string]*ModInstallation{}
+	for _, e := range history {
+		last[e.Mod] = e.After
+	}
+
+	known := NewModNameMapper().MapAllMods(res.Config.ClientMods)
+	for _, fi := range infos {
+		if fi.IsDir || !strings.HasSuffix(fi.Name, ".jar") {
+			continue
+		}
+
+		cliName := strings.TrimSuffix(fi.Name, ".jar")
+		_, isKnown := known[cliName]
+		recorded := last[cliName]
+		if !isKnown && recorded == nil {
+			res.Skipped = append(res.Skipped, fi.Name)
+			continue
+		}
+
+		b, err := fs.ReadFile(ModInstallPath(cliName))
+		if err != nil {
+			return err
+		}
+		installation := ModInstallation{
+			Timestamp: fi.ModTime.Truncate(time.Second),
+			SHA256:    HashSHA256(b),
+			Size:      int64(len(b)),
+		}
+		if recorded != nil && strings.EqualFold(recorded.SHA256, installation.SHA256) {
+			installation = *recorded
+			res.Matched = append(res.Matched, cliName)
+		}
+		res.Config.ModInstallations[cliName] = installation
+		res.Recovered = append(res.Recovered, cliName)
+	}
+
+	sort.Strings(res.Recovered)
+	sort.Strings(res.Matched)
+	return nil
+}
@@ -0,0 +1,90 @@
+package mc_test
+
+import (
+	"bytes"
+	"mcmods/mc"
+	. "mcmods/testdata"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Config Repair", func() {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var afs afero.Fs
+	var fs mc.FileSystem
+
+	configPath := filepath.Join("/minecraft", mc.ModFolderName, mc.ModConfigFileName)
+	writeJar := func(cliName, content string) {
+		Expect(afero.WriteFile(afs, filepath.Join("/minecraft", mc.ModInstallPath(cliName)), []byte(content), 0644)).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		InitTestData()
+		mc.ServerGroups = TestingServerGroups
+		afs = afero.NewMemMapFs()
+		fs = mc.LocalFileSystem{Fs: afs, Dir: "/minecraft"}
+
+		Expect(mc.NewUserModConfigIo(fs).Save(TestingConfig)).To(BeNil())
+		Expect(mc.NewUserModConfigIo(fs).Save(TestingConfig)).To(BeNil())
+		Expect(afero.WriteFile(afs, configPath, []byte(`{"modInstallations": {"mod`), 0644)).To(BeNil())
+	})
+
+	It("restores the last good copy, and keeps the corrupt file", func() {
+		res, err := mc.RepairConfig(fs, false)
+
+		Expect(err).To(BeNil())
+		Expect(res.FromBackup).To(BeTrue())
+		Expect(res.Config.ModInstallations).To(HaveLen(len(TestingConfig.ModInstallations)))
+		Expect(res.Config.ClientMods).To(HaveLen(len(TestingConfig.ClientMods)))
+		b, _ := afero.ReadFile(afs, configPath+mc.ModConfigCorruptSuffix)
+		Expect(string(b)).To(Equal(`{"modInstallations": {"mod`))
+	})
+
+	It("rebuilds the installations from the mods folder and the history", func() {
+		recorded := mc.ModInstallation{DownloadURL: "https://required1/2", Timestamp: now, SHA256: mc.HashSHA256([]byte("s2")), Size: 2}
+		Expect(mc.AppendHistory(fs, mc.DiffInstallations(nil, map[string]mc.ModInstallation{TestingServerRequired1.CliName: recorded}, now))).To(BeNil())
+		writeJar(TestingServerRequired1.CliName, "s2")
+		writeJar(TestingClientMod1.CliName, "c1")
+		writeJar("stranger", "?")
+
+		res, err := mc.RepairConfig(fs, true)
+
+		Expect(err).To(BeNil())
+		Expect(res.FromBackup).To(BeFalse())
+		Expect(res.Recovered).To(Equal([]string{TestingClientMod1.CliName, TestingServerRequired1.CliName}))
+		Expect(res.Matched).To(Equal([]string{TestingServerRequired1.CliName}))
+		Expect(res.Skipped).To(Equal([]string{"stranger.jar"}))
+		Expect(res.Config.ModInstallations[TestingServerRequired1.CliName]).To(Equal(recorded))
+		client := res.Config.ModInstallations[TestingClientMod1.CliName]
+		Expect(client.DownloadURL).To(BeEmpty())
+		Expect(client.SHA256).To(Equal(mc.HashSHA256([]byte("c1"))))
+		Expect(client.Size).To(Equal(int64(2)))
+		Expect(res.Config.ClientMods).To(HaveLen(len(TestingConfig.ClientMods)), "the client mods are kept from the backup")
+	})
+
+	It("scans the mods folder when there's no usable backup", func() {
+		backupPath := configPath + mc.ModConfigBackupSuffix
+		Expect(afero.WriteFile(afs, backupPath, []byte("{"), 0644)).To(BeNil())
+		writeJar(TestingServerRequired1.CliName, "s1")
+
+		res, err := mc.RepairConfig(fs, false)
+
+		Expect(err).To(BeNil())
+		Expect(res.FromBackup).To(BeFalse())
+		Expect(res.Recovered).To(Equal([]string{TestingServerRequired1.CliName}))
+		Expect(res.Config.ClientMods).To(BeEmpty())
+	})
+
+	It("refuses to repair a config which loads", func() {
+		Expect(fs.WriteFile(bytes.NewReader([]byte(`{}`)), filepath.Join(mc.ModFolderName, mc.ModConfigFileName))).To(BeNil())
+
+		_, err := mc.RepairConfig(fs, false)
+
+		Expect(err).To(Equal(mc.ErrConfigNotCorrupt))
+	})
+})
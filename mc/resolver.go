@@ -0,0 +1,149 @@
+package mc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// ModrinthAPIURL is the base URL of the public Modrinth API
+	ModrinthAPIURL = "https://api.modrinth.com/v2"
+)
+
+var (
+	// ErrNoUpdateSource is returned when there's no way to look up new
+	// versions of a mod
+	ErrNoUpdateSource = errors.New("no update source for mod")
+)
+
+// ModFile is a single downloadable file published upstream for a mod
+type ModFile struct {
+	URL      string
+	FileName string
+	Hashes   map[string]string
+}
+
+// ModResolver looks up the newest upstream file for mods
+type ModResolver interface {
+	// Resolve returns the newest file of the mod which is compatible with the
+	// given Minecraft version and mod loader
+	Resolve(mod *Mod, gameVersion string, loader string) (*ModFile, error)
+}
+
+type modrinthResolver struct {
+	HTTPClient *HTTPClient
+	APIURL     string
+}
+
+// NewModrinthResolver creates a ModResolver which queries the Modrinth API
+// over the given http client
+func NewModrinthResolver(hc *HTTPClient) ModResolver {
+	return modrinthResolver{
+		HTTPClient: hc,
+		APIURL:     ModrinthAPIURL,
+	}
+}
+
+type modrinthVersion struct {
+	VersionNumber string         `json:"version_number"`
+	Files         []modrinthFile `json:"files"`
+}
+
+type modrinthFile struct {
+	URL      string            `json:"url"`
+	FileName string            `json:"filename"`
+	Primary  bool              `json:"primary"`
+	Hashes   map[string]string `json:"hashes"`
+}
+
+// Resolve returns the newest file of the mod which is compatible with the
+// given Minecraft version and mod loader
+func (r modrinthResolver) Resolve(mod *Mod, gameVersion string, loader string) (*ModFile, error) {
+	projectID := GetModrinthProjectID(mod)
+	if projectID == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoUpdateSource, mod.CliName)
+	}
+
+	query := url.Values{}
+	query.Set("loaders", fmt.Sprintf(`["%s"]`, loader))
+	query.Set("game_versions", fmt.Sprintf(`["%s"]`, gameVersion))
+	reqURL := fmt.Sprintf("%s/project/%s/version?%s", r.APIURL, url.PathEscape(projectID), query.Encode())
+
+	resp, err := r.HTTPClient.Getter.Get(reqURL)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s (unknown project %s)", ErrNoUpdateSource, mod.CliName, projectID)
+	} else if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("looking up %s failed: %s", mod.CliName, resp.Status)
+	}
+
+	versions := []modrinthVersion{}
+	if err = json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return nil, err
+	}
+
+	// versions are returned newest first
+	for _, v := range versions {
+		if f := primaryFile(v.Files); f != nil {
+			return &ModFile{
+				URL:      f.URL,
+				FileName: f.FileName,
+				Hashes:   f.Hashes,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no %s release of %s for Minecraft %s", loader, mod.CliName, gameVersion)
+}
+
+func primaryFile(files []modrinthFile) *modrinthFile {
+	for i := range files {
+		if files[i].Primary {
+			return &files[i]
+		}
+	}
+	if len(files) > 0 {
+		return &files[0]
+	}
+	return nil
+}
+
+// GetModrinthProjectID returns the mod's project ID, falling back to parsing it
+// from Modrinth download or homepage URLs. Returns an empty string if unknown.
+func GetModrinthProjectID(mod *Mod) string {
+	if mod.ProjectID != "" {
+		return mod.ProjectID
+	}
+
+	// https://cdn.modrinth.com/data/<id>/versions/<version>/<file>
+	if id := pathSegmentAfter(mod.LatestURL, "cdn.modrinth.com", "data"); id != "" {
+		return id
+	}
+
+	// https://modrinth.com/mod/<slug>
+	return pathSegmentAfter(mod.DetailsURL, "modrinth.com", "mod")
+}
+
+func pathSegmentAfter(rawURL string, host string, segment string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host != host && u.Host != "www."+host {
+		return ""
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == segment {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,114 @@
+package mc_test
+
+import (
+	"errors"
+	"io"
+	"mcmods/mc"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolver", func() {
+	var mod *mc.Mod
+	var hc *mc.HTTPClient
+
+	versionsJSON := `[
+		{"version_number": "2.0", "files": [
+			{"url": "https://cdn.modrinth.com/data/abc/versions/2/extra.jar", "filename": "extra.jar", "primary": false, "hashes": {}},
+			{"url": "https://cdn.modrinth.com/data/abc/versions/2/mod.jar", "filename": "mod.jar", "primary": true, "hashes": {"sha1": "s1", "sha512": "s512"}}
+		]},
+		{"version_number": "1.0", "files": [
+			{"url": "https://cdn.modrinth.com/data/abc/versions/1/mod.jar", "filename": "mod.jar", "primary": true, "hashes": {}}
+		]}
+	]`
+
+	jsonResponse := func(status int, body string) *http.Response {
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	BeforeEach(func() {
+		mod = &mc.Mod{
+			CliName:   "some-mod",
+			ProjectID: "abc",
+		}
+		hc = &mc.HTTPClient{Getter: emptyGetter{Res: jsonResponse(http.StatusOK, versionsJSON)}}
+	})
+
+	It("queries the project versions for the game version and loader", func() {
+		hc.Getter = getURLVerifier{
+			emptyGetter: emptyGetter{Res: jsonResponse(http.StatusOK, versionsJSON)},
+			ExpectedURL: mc.ModrinthAPIURL + `/project/abc/version?game_versions=%5B%221.18.1%22%5D&loaders=%5B%22fabric%22%5D`,
+		}
+
+		_, err := mc.NewModrinthResolver(hc).Resolve(mod, "1.18.1", "fabric")
+
+		Expect(err).To(BeNil())
+	})
+
+	It("returns the primary file of the newest version", func() {
+		f, err := mc.NewModrinthResolver(hc).Resolve(mod, "1.18.1", "fabric")
+
+		Expect(err).To(BeNil())
+		Expect(f.URL).To(Equal("https://cdn.modrinth.com/data/abc/versions/2/mod.jar"))
+		Expect(f.FileName).To(Equal("mod.jar"))
+		Expect(f.Hashes).To(HaveKeyWithValue("sha512", "s512"))
+	})
+
+	It("returns an error when no version is compatible", func() {
+		hc.Getter = emptyGetter{Res: jsonResponse(http.StatusOK, "[]")}
+
+		_, err := mc.NewModrinthResolver(hc).Resolve(mod, "1.18.1", "fabric")
+
+		Expect(err).ToNot(BeNil())
+	})
+
+	It("returns ErrNoUpdateSource for unknown projects", func() {
+		hc.Getter = emptyGetter{Res: jsonResponse(http.StatusNotFound, "")}
+
+		_, err := mc.NewModrinthResolver(hc).Resolve(mod, "1.18.1", "fabric")
+
+		Expect(errors.Is(err, mc.ErrNoUpdateSource)).To(BeTrue())
+	})
+
+	It("returns ErrNoUpdateSource when the project can't be determined", func() {
+		mod.ProjectID = ""
+		mod.LatestURL = "https://www.curseforge.com/minecraft/mc-mods/ducts/download/3571121/file"
+
+		_, err := mc.NewModrinthResolver(hc).Resolve(mod, "1.18.1", "fabric")
+
+		Expect(errors.Is(err, mc.ErrNoUpdateSource)).To(BeTrue())
+	})
+
+	It("returns errors from the http client", func() {
+		hc.Getter = emptyGetter{Err: errors.New("get error")}
+
+		_, err := mc.NewModrinthResolver(hc).Resolve(mod, "1.18.1", "fabric")
+
+		Expect(err).To(Equal(hc.Getter.(emptyGetter).Err))
+	})
+
+	Context("GetModrinthProjectID", func() {
+		It("prefers the project ID field", func() {
+			mod.LatestURL = "https://cdn.modrinth.com/data/xyz/versions/1/mod.jar"
+
+			Expect(mc.GetModrinthProjectID(mod)).To(Equal("abc"))
+		})
+
+		It("parses the ID from modrinth CDN URLs", func() {
+			mod.ProjectID = ""
+			mod.LatestURL = "https://cdn.modrinth.com/data/AANobbMI/versions/1.0/sodium.jar"
+
+			Expect(mc.GetModrinthProjectID(mod)).To(Equal("AANobbMI"))
+		})
+
+		It("parses the slug from modrinth homepage URLs", func() {
+			mod.ProjectID = ""
+			mod.DetailsURL = "https://modrinth.com/mod/lithium"
+
+			Expect(mc.GetModrinthProjectID(mod)).To(Equal("lithium"))
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package mc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// InstallState is the installations as they were after a revision of the
+// history. Only mods which appear in the history are known.
+type InstallState struct {
+	Revision int
+
+	// Installations are the records of the known mods which were installed
+	Installations map[string]ModInstallation
+
+	// Mods are the CLI names of every mod in the history, sorted
+	Mods []string
+}
+
+// InstallStateAt replays the history to find the installations after the
+// revision. Revision 0 is the state before the first recorded change, which is
+// known from the records before each mod's first event.
+func InstallStateAt(events []HistoryEvent, revision int) InstallState {
+	state := InstallState{Revision: revision, Installations: map[string]ModInstallation{}, Mods: []string{}}
+
+	// the last event at or before the revision, or else the first after it,
+	// since the events are in order
+	decided := map[string]HistoryEvent{}
+	for _, e := range events {
+		if _, seen := decided[e.Mod]; !seen {
+			state.Mods = append(state.Mods, e.Mod)
+			decided[e.Mod] = e
+		} else if e.Revision <= revision {
+			decided[e.Mod] = e
+		}
+	}
+	sort.Strings(state.Mods)
+
+	for cliName, e := range decided {
+		record := e.After
+		if e.Revision > revision {
+			record = e.Before
+		}
+		if record != nil {
+			state.Installations[cliName] = *record
+		}
+	}
+	return state
+}
+
+// RollbackResult lists the mods changed by a rollback, by CLI name
+type RollbackResult struct {
+	Restored  []string
+	Removed   []string
+	Unchanged int
+	Failed    []RollbackFailure
+}
+
+// RollbackFailure is a mod which couldn't be returned to its earlier state
+type RollbackFailure struct {
+	Mod    string
+	Reason string
+}
+
+// InstallRollbacker returns installations to an earlier state
+type InstallRollbacker interface {
+	// Rollback makes the jars and installation records of the known mods
+	// match the state. The jars are taken from the cache if it isn't nil, or
+	// downloaded again. The config is updated, but not saved. Mods which
+	// can't be restored are left as they are and reported in the result.
+	Rollback(fs FileSystem, dl ModDownloader, cache *JarCache, cfg *UserModConfig, state InstallState) RollbackResult
+}
+
+type installRollbacker struct{}
+
+// NewInstallRollbacker returns a new struct which implements
+// InstallRollbacker
+func NewInstallRollbacker() InstallRollbacker {
+	return installRollbacker{}
+}
+
+// Rollback restores or removes each known mod which differs from the state
+func (r installRollbacker) Rollback(fs FileSystem, dl ModDownloader, cache *JarCache, cfg *UserModConfig, state InstallState) RollbackResult {
+	res := RollbackResult{Restored: []string{}, Removed: []string{}, Failed: []RollbackFailure{}}
+	if cfg.ModInstallations == nil {
+		cfg.ModInstallations = map[string]ModInstallation{}
+	}
+
+	for _, cliName := range state.Mods {
+		want, wanted := state.Installations[cliName]
+		have, installed := cfg.ModInstallations[cliName]
+
+		switch {
+		case wanted && installed && sameInstallation(want, have), !wanted && !installed:
+			res.Unchanged++
+		case !wanted:
+			fmt.Printf("Removing %s\n", cliName)
+			if err := fs.Remove(ModInstallPath(cliName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				res.Failed = append(res.Failed, RollbackFailure{Mod: cliName, Reason: err.Error()})
+				continue
+			}
+			delete(cfg.ModInstallations, cliName)
+			res.Removed = append(res.Removed, cliName)
+		default:
+			fmt.Printf("Restoring %s\n", cliName)
+			if err := restoreJar(fs, dl, cache, cliName, want); err != nil {
+				res.Failed = append(res.Failed, RollbackFailure{Mod: cliName, Reason: err.Error()})
+				continue
+			}
+			cfg.ModInstallations[cliName] = want
+			res.Restored = append(res.Restored, cliName)
+		}
+	}
+
+	return res
+}
+
+// restoreJar writes the cached jar with the record's hash, or else downloads
+// the recorded URLs again, verified by the hash
+func restoreJar(fs FileSystem, dl ModDownloader, cache *JarCache, cliName string, installation ModInstallation) error {
+	relPath := ModInstallPath(cliName)
+
+	if cache != nil && installation.SHA256 != "" {
+		if b, err := cache.Get(installation.SHA256); err == nil {
+			fmt.Printf("  Copying the cached jar\n    to: %s\n", relPath)
+			if err = fs.MkDirAll(ModFolderName); err != nil {
+				return err
+			}
+			return fs.WriteFile(bytes.NewReader(b), relPath)
+		}
+	}
+
+	if installation.DownloadURL == "" {
+		return errors.New("the jar isn't cached and no download URL was recorded")
+	}
+
+	mod := &Mod{CliName: cliName, FriendlyName: cliName, LatestURL: installation.DownloadURL}
+	if installation.SourceURL != "" && installation.SourceURL != installation.DownloadURL {
+		mod.MirrorURLs = []string{installation.SourceURL}
+	}
+	if installation.SHA256 != "" {
+		mod.Hashes = map[string]string{"sha256": installation.SHA256}
+	}
+
+	if _, err := dl.Download(mod, relPath); err != nil {
+		return fmt.Errorf("the jar isn't cached and couldn't be downloaded: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package mc_test
+
+import (
+	"bytes"
+	"errors"
+	"mcmods/mc"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+// writes the content of the mod's URL, verified like the real downloader
+type urlDownloader struct {
+	Fs       mc.FileSystem
+	Contents map[string][]byte
+}
+
+func (u urlDownloader) Download(mod *mc.Mod, relPath string) (*mc.DownloadResult, error) {
+	b, ok := u.Contents[mod.LatestURL]
+	if !ok {
+		return nil, errors.New("404 Not Found")
+	}
+	if _, err := mc.VerifyHashes(b, mod.Hashes); err != nil {
+		return nil, err
+	}
+	if err := u.Fs.MkDirAll(filepath.Dir(relPath)); err != nil {
+		return nil, err
+	}
+	return &mc.DownloadResult{URL: mod.LatestURL}, u.Fs.WriteFile(bytes.NewReader(b), relPath)
+}
+
+var _ = Describe("Rollback", func() {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	v1 := mc.ModInstallation{DownloadURL: "https://a/1", SHA256: mc.HashSHA256([]byte("v1")), Size: 2}
+	v2 := mc.ModInstallation{DownloadURL: "https://a/2", SHA256: mc.HashSHA256([]byte("v2")), Size: 2}
+	b1 := mc.ModInstallation{DownloadURL: "https://b/1"}
+
+	// revision 1 updates mod-a, revision 2 installs mod-b, revision 3 removes
+	// mod-a. mod-c is installed, but not in the history.
+	history := func() []mc.HistoryEvent {
+		afs := afero.NewMemMapFs()
+		fs := mc.LocalFileSystem{Fs: afs, Dir: "/history"}
+		Expect(mc.AppendHistory(fs, mc.DiffInstallations(
+			map[string]mc.ModInstallation{"mod-a": v1}, map[string]mc.ModInstallation{"mod-a": v2}, now))).To(BeNil())
+		Expect(mc.AppendHistory(fs, mc.DiffInstallations(
+			nil, map[string]mc.ModInstallation{"mod-b": b1}, now.Add(time.Hour)))).To(BeNil())
+		Expect(mc.AppendHistory(fs, mc.DiffInstallations(
+			map[string]mc.ModInstallation{"mod-a": v2}, nil, now.Add(2*time.Hour)))).To(BeNil())
+
+		events, err := mc.ReadHistory(fs)
+		Expect(err).To(BeNil())
+		return events
+	}
+
+	Context("install state", func() {
+		It("replays the history up to the revision", func() {
+			events := history()
+
+			Expect(mc.InstallStateAt(events, 3).Installations).To(Equal(map[string]mc.ModInstallation{"mod-b": b1}))
+			Expect(mc.InstallStateAt(events, 2).Installations).To(Equal(map[string]mc.ModInstallation{"mod-a": v2, "mod-b": b1}))
+			Expect(mc.InstallStateAt(events, 1).Installations).To(Equal(map[string]mc.ModInstallation{"mod-a": v2}))
+		})
+
+		It("uses the records before each mod's first event for revision 0", func() {
+			state := mc.InstallStateAt(history(), 0)
+
+			Expect(state.Installations).To(Equal(map[string]mc.ModInstallation{"mod-a": v1}))
+			Expect(state.Mods).To(Equal([]string{"mod-a", "mod-b"}))
+		})
+	})
+
+	Context("rollbacker", func() {
+		var afs afero.Fs
+		var fs mc.FileSystem
+		var cache *mc.JarCache
+		var dl urlDownloader
+		var cfg *mc.UserModConfig
+
+		jarPath := func(cliName string) string {
+			return filepath.Join("/minecraft", mc.ModInstallPath(cliName))
+		}
+
+		BeforeEach(func() {
+			afs = afero.NewMemMapFs()
+			fs = mc.LocalFileSystem{Fs: afs, Dir: "/minecraft"}
+			cache = &mc.JarCache{Fs: afs, Dir: "/cache"}
+			dl = urlDownloader{Fs: fs, Contents: map[string][]byte{}}
+
+			Expect(afero.WriteFile(afs, jarPath("mod-b"), []byte("b1"), 0644)).To(BeNil())
+			Expect(afero.WriteFile(afs, jarPath("mod-c"), []byte("c1"), 0644)).To(BeNil())
+			cfg = &mc.UserModConfig{ModInstallations: map[string]mc.ModInstallation{
+				"mod-b": b1,
+				"mod-c": {DownloadURL: "https://c/1"},
+			}}
+		})
+
+		It("restores cached jars and removes jars installed since", func() {
+			Expect(cache.Put([]byte("v2"))).To(BeNil())
+
+			res := mc.NewInstallRollbacker().Rollback(fs, dl, cache, cfg, mc.InstallStateAt(history(), 1))
+
+			Expect(res.Failed).To(BeEmpty())
+			Expect(res.Restored).To(Equal([]string{"mod-a"}))
+			Expect(res.Removed).To(Equal([]string{"mod-b"}))
+			Expect(cfg.ModInstallations).To(Equal(map[string]mc.ModInstallation{
+				"mod-a": v2,
+				"mod-c": {DownloadURL: "https://c/1"},
+			}))
+
+			b, _ := afero.ReadFile(afs, jarPath("mod-a"))
+			Expect(string(b)).To(Equal("v2"))
+			exists, _ := afero.Exists(afs, jarPath("mod-b"))
+			Expect(exists).To(BeFalse())
+			exists, _ = afero.Exists(afs, jarPath("mod-c"))
+			Expect(exists).To(BeTrue())
+		})
+
+		It("downloads jars which aren't cached, verified by their hash", func() {
+			dl.Contents["https://a/2"] = []byte("v2")
+
+			res := mc.NewInstallRollbacker().Rollback(fs, dl, cache, cfg, mc.InstallStateAt(history(), 1))
+
+			Expect(res.Failed).To(BeEmpty())
+			Expect(res.Restored).To(Equal([]string{"mod-a"}))
+			b, _ := afero.ReadFile(afs, jarPath("mod-a"))
+			Expect(string(b)).To(Equal("v2"))
+		})
+
+		It("reports the jars which can't be restored, and leaves them as they are", func() {
+			dl.Contents["https://a/2"] = []byte("changed")
+
+			res := mc.NewInstallRollbacker().Rollback(fs, dl, cache, cfg, mc.InstallStateAt(history(), 1))
+
+			Expect(res.Failed).To(HaveLen(1))
+			Expect(res.Failed[0].Mod).To(Equal("mod-a"))
+			Expect(cfg.ModInstallations).ToNot(HaveKey("mod-a"))
+			Expect(res.Removed).To(Equal([]string{"mod-b"}))
+		})
+	})
+
+	Context("jar cache", func() {
+		It("treats corrupt jars as missing", func() {
+			afs := afero.NewMemMapFs()
+			cache := &mc.JarCache{Fs: afs, Dir: "/cache"}
+			hash := mc.HashSHA256([]byte("v1"))
+			Expect(cache.Put([]byte("v1"))).To(BeNil())
+			Expect(afero.WriteFile(afs, filepath.Join("/cache", hash+".jar"), []byte("corrupt"), 0644)).To(BeNil())
+
+			_, err := cache.Get(hash)
+
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,126 @@
+package mc
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// SelectionsKey - The key of the map of target names to the mods chosen
+	// for installs on the target
+	SelectionsKey = "installSelections"
+
+	// SelectionClientOnly is the mode of selections which only install client
+	// mods
+	SelectionClientOnly = "client-only"
+
+	// SelectionFullServer is the mode of selections which install every
+	// server mod
+	SelectionFullServer = "full-server"
+)
+
+// Selection is the choice of mods installed on a target, remembered so plain
+// installs keep installing the same mods
+type Selection struct {
+	// Mode is SelectionClientOnly, SelectionFullServer, or empty for the
+	// client and server mods, without the server-only ones
+	Mode string `mapstructure:"mode"`
+
+	ExcludedGroups []string `mapstructure:"excludedGroups"`
+	ExcludedMods   []string `mapstructure:"excludedMods"`
+}
+
+// IsEmpty returns true if the selection installs the same mods as a plain
+// install without it
+func (s Selection) IsEmpty() bool {
+	return s.Mode == "" && len(s.ExcludedGroups) == 0 && len(s.ExcludedMods) == 0
+}
+
+func (s Selection) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"mode":           s.Mode,
+		"excludedGroups": s.ExcludedGroups,
+		"excludedMods":   s.ExcludedMods,
+	}
+}
+
+// GetSelection returns the selection saved for the target, and false if
+// there isn't one
+func GetSelection(target string) (Selection, bool, error) {
+	selections, err := getSelections()
+	if err != nil {
+		return Selection{}, false, err
+	}
+
+	s, ok := selections[BackupTargetName(target)]
+	return s, ok, nil
+}
+
+// SetSelection saves the selection for the target in Viper, replacing any
+// saved one. Exclusions are ignored by full server installs, so they aren't
+// kept. The config isn't written.
+func SetSelection(target string, s Selection) error {
+	if s.Mode != "" && s.Mode != SelectionClientOnly && s.Mode != SelectionFullServer {
+		return fmt.Errorf("unknown selection mode %q: use %s or %s", s.Mode, SelectionClientOnly, SelectionFullServer)
+	}
+	for _, group := range s.ExcludedGroups {
+		if _, ok := ServerGroups[group]; !ok {
+			return NewUnknownGroupError(group)
+		}
+	}
+
+	if s.Mode == SelectionFullServer {
+		s.ExcludedGroups = []string{}
+		s.ExcludedMods = []string{}
+	}
+	s.ExcludedGroups = sortedCopy(s.ExcludedGroups)
+	s.ExcludedMods = sortedCopy(s.ExcludedMods)
+
+	selections, err := getSelections()
+	if err != nil {
+		return err
+	}
+	selections[BackupTargetName(target)] = s
+	setSelections(selections)
+	return nil
+}
+
+// ClearSelection removes the selection saved for the target from Viper.
+// Returns false if there wasn't one. The config isn't written.
+func ClearSelection(target string) (bool, error) {
+	selections, err := getSelections()
+	if err != nil {
+		return false, err
+	}
+
+	name := BackupTargetName(target)
+	if _, ok := selections[name]; !ok {
+		return false, nil
+	}
+	delete(selections, name)
+	setSelections(selections)
+	return true, nil
+}
+
+func getSelections() (map[string]Selection, error) {
+	selections := map[string]Selection{}
+	if err := ViperInstance.UnmarshalKey(SelectionsKey, &selections); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", SelectionsKey, err)
+	}
+	return selections, nil
+}
+
+// setSelections replaces the whole map, since Viper can't unset a key
+func setSelections(selections map[string]Selection) {
+	maps := make(map[string]interface{}, len(selections))
+	for name, s := range selections {
+		maps[name] = s.toMap()
+	}
+	ViperInstance.Set(SelectionsKey, maps)
+}
+
+func sortedCopy(s []string) []string {
+	c := append([]string{}, s...)
+	sort.Strings(c)
+	return c
+}
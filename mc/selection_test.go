@@ -0,0 +1,77 @@
+package mc_test
+
+import (
+	"mcmods/mc"
+	. "mcmods/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Selections", func() {
+	BeforeEach(func() {
+		InitTestData()
+		mc.ServerGroups = TestingServerGroups
+		mc.ViperInstance.Set(mc.SelectionsKey, map[string]interface{}{})
+	})
+
+	It("saves a selection for each target", func() {
+		Expect(mc.SetSelection("local", mc.Selection{ExcludedGroups: []string{"performance", "optional"}, ExcludedMods: []string{"mod1"}})).To(BeNil())
+		Expect(mc.SetSelection("remote-survival", mc.Selection{Mode: mc.SelectionFullServer})).To(BeNil())
+
+		local, ok, err := mc.GetSelection("local")
+		Expect(err).To(BeNil())
+		Expect(ok).To(BeTrue())
+		Expect(local.Mode).To(BeEmpty())
+		Expect(local.ExcludedGroups).To(Equal([]string{"optional", "performance"}))
+		Expect(local.ExcludedMods).To(Equal([]string{"mod1"}))
+
+		server, ok, _ := mc.GetSelection("remote-survival")
+		Expect(ok).To(BeTrue())
+		Expect(server.Mode).To(Equal(mc.SelectionFullServer))
+
+		_, ok, _ = mc.GetSelection("server-mc.example.com")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("replaces the saved selection", func() {
+		Expect(mc.SetSelection("local", mc.Selection{ExcludedMods: []string{"mod1"}})).To(BeNil())
+		Expect(mc.SetSelection("local", mc.Selection{Mode: mc.SelectionClientOnly})).To(BeNil())
+
+		s, _, _ := mc.GetSelection("local")
+		Expect(s.Mode).To(Equal(mc.SelectionClientOnly))
+		Expect(s.ExcludedMods).To(BeEmpty())
+	})
+
+	It("drops the exclusions of full server selections", func() {
+		Expect(mc.SetSelection("local", mc.Selection{Mode: mc.SelectionFullServer, ExcludedGroups: []string{"optional"}})).To(BeNil())
+
+		s, _, _ := mc.GetSelection("local")
+		Expect(s.ExcludedGroups).To(BeEmpty())
+	})
+
+	It("rejects unknown groups and modes", func() {
+		Expect(mc.SetSelection("local", mc.Selection{ExcludedGroups: []string{"unknown"}})).ToNot(BeNil())
+		Expect(mc.SetSelection("local", mc.Selection{Mode: "server-only"})).ToNot(BeNil())
+
+		_, ok, _ := mc.GetSelection("local")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("clears the saved selection", func() {
+		Expect(mc.SetSelection("local", mc.Selection{Mode: mc.SelectionClientOnly})).To(BeNil())
+		Expect(mc.SetSelection("profile-creative", mc.Selection{Mode: mc.SelectionClientOnly})).To(BeNil())
+
+		cleared, err := mc.ClearSelection("local")
+
+		Expect(err).To(BeNil())
+		Expect(cleared).To(BeTrue())
+		_, ok, _ := mc.GetSelection("local")
+		Expect(ok).To(BeFalse())
+		_, ok, _ = mc.GetSelection("profile-creative")
+		Expect(ok).To(BeTrue())
+
+		cleared, _ = mc.ClearSelection("local")
+		Expect(cleared).To(BeFalse())
+	})
+})
@@ -0,0 +1,223 @@
+package mc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	// SFTPDefaultPort is used when the server address doesn't include a port
+	SFTPDefaultPort = "22"
+)
+
+// SFTPFileSystem is used to interact with Minecraft servers over SFTP to
+// maintain mod installations. Paths are relative to the login directory.
+type SFTPFileSystem struct {
+	Client *sftp.Client
+	SSH    *ssh.Client
+
+	// BaseDir is the Minecraft directory on the server. Relative to the login
+	// directory unless absolute.
+	BaseDir string
+}
+
+// WriteFile writes the bytes over SFTP to the given path on the server.
+func (s SFTPFileSystem) WriteFile(r io.Reader, relPath string) error {
+	f, err := s.Client.Create(s.sftpPath(relPath))
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadFile reads the bytes of the given path over SFTP.
+func (s SFTPFileSystem) ReadFile(relPath string) ([]byte, error) {
+	f, err := s.Client.Open(s.sftpPath(relPath))
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// MkDirAll creates all non-existant folders in the given path.
+func (s SFTPFileSystem) MkDirAll(relPath string) error {
+	p := s.sftpPath(relPath)
+	if p == "." {
+		return nil
+	}
+	return s.Client.MkdirAll(p)
+}
+
+// ReadDir lists the directory at the given path over SFTP, sorted by name.
+func (s SFTPFileSystem) ReadDir(relPath string) ([]FileInfo, error) {
+	fis, err := s.Client.ReadDir(s.sftpPath(relPath))
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]FileInfo, 0, len(fis))
+	for _, fi := range fis {
+		infos = append(infos, newFileInfo(fi))
+	}
+	sortFileInfos(infos)
+	return infos, nil
+}
+
+// Stat describes the file at the given path over SFTP.
+func (s SFTPFileSystem) Stat(relPath string) (FileInfo, error) {
+	fi, err := s.Client.Stat(s.sftpPath(relPath))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return newFileInfo(fi), nil
+}
+
+// Remove deletes the file at the given path over SFTP.
+func (s SFTPFileSystem) Remove(relPath string) error {
+	return s.Client.Remove(s.sftpPath(relPath))
+}
+
+// Rename moves the file at the given path over SFTP. Servers without the
+// POSIX rename extension can't replace a file, so it's deleted first.
+func (s SFTPFileSystem) Rename(oldRelPath, newRelPath string) error {
+	from, to := s.sftpPath(oldRelPath), s.sftpPath(newRelPath)
+	err := s.Client.PosixRename(from, to)
+	if err == nil || !canReplace(s, oldRelPath, newRelPath) {
+		return err
+	}
+	if err = s.Client.Remove(to); err != nil {
+		return err
+	}
+	return s.Client.Rename(from, to)
+}
+
+// Close closes the SFTP session and the SSH connection
+func (s SFTPFileSystem) Close() {
+	s.Client.Close()
+	s.SSH.Close()
+}
+
+func openSFTPToServer(args *FTPArgs) (FileSystem, error) {
+	if args.User == "" || args.Server == "" || args.Pw == "" && args.KeyFile == "" {
+		return nil, errors.New("SFTP access requires a username, server, and a password or private key")
+	}
+
+	hostKeyCallback, err := knownHostsCallback(args.KnownHostsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := sftpAuthMethods(args)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := sftpAddress(args.Server)
+	fmt.Printf("Connecting SFTP to %s\n", addr)
+
+	sshClient, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            args.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         time.Duration(args.TimeoutMs) * time.Millisecond,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+
+	return &SFTPFileSystem{Client: client, SSH: sshClient, BaseDir: args.BaseDir}, nil
+}
+
+// sftpAuthMethods uses the private key if given, which is decrypted with the
+// password if needed. Otherwise, the password is used to log in.
+func sftpAuthMethods(args *FTPArgs) ([]ssh.AuthMethod, error) {
+	if args.KeyFile == "" {
+		return []ssh.AuthMethod{ssh.Password(args.Pw)}, nil
+	}
+
+	pem, err := ioutil.ReadFile(args.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(pem)
+	if _, encrypted := err.(*ssh.PassphraseMissingError); encrypted {
+		if args.Pw == "" {
+			return nil, errors.New("the private key is encrypted; provide its passphrase as the password")
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(args.Pw))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+}
+
+// knownHostsCallback verifies host keys against the known_hosts file, which
+// defaults to the user's ~/.ssh/known_hosts
+func knownHostsCallback(file string) (ssh.HostKeyCallback, error) {
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		file = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	callback, err := knownhosts.New(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		keyErr := &knownhosts.KeyError{}
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return fmt.Errorf("%s is not a known host; add its key to %s, e.g. with ssh-keyscan", hostname, file)
+		}
+		return err
+	}, nil
+}
+
+// sftpAddress strips the sftp:// scheme from the server and adds the default
+// port if there isn't one
+func sftpAddress(server string) string {
+	if strings.HasPrefix(strings.ToLower(server), SFTPScheme) {
+		server = server[len(SFTPScheme):]
+	}
+	server = strings.TrimSuffix(server, "/")
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, SFTPDefaultPort)
+	}
+	return server
+}
+
+func (s SFTPFileSystem) sftpPath(relPath string) string {
+	return path.Clean(path.Join(filepath.ToSlash(s.BaseDir), filepath.ToSlash(relPath)))
+}
@@ -0,0 +1,373 @@
+package mc_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"mcmods/mc"
+	"net"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+var _ = Describe("SFTP File System", func() {
+	var server *testSFTPServer
+	var args *mc.FTPArgs
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "mcmods-sftp")
+		Expect(err).To(BeNil())
+
+		server = startTestSFTPServer("admin", "hunter2")
+
+		knownHosts := filepath.Join(dir, "known_hosts")
+		line := knownhosts.Line([]string{server.Addr}, server.HostKey.PublicKey())
+		Expect(ioutil.WriteFile(knownHosts, []byte(line+"\n"), 0600)).To(BeNil())
+
+		args = &mc.FTPArgs{
+			Server:         mc.SFTPScheme + server.Addr,
+			User:           "admin",
+			Pw:             "hunter2",
+			TimeoutMs:      2000,
+			KnownHostsFile: knownHosts,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(dir)
+	})
+
+	Context("NewFs", func() {
+		It("selects SFTP from the server URL scheme", func() {
+			fs, err := mc.NewFs(args)
+
+			Expect(err).To(BeNil())
+			Expect(fs).To(BeAssignableToTypeOf(&mc.SFTPFileSystem{}))
+			fs.Close()
+		})
+
+		It("accepts the scheme in any case", func() {
+			args.Server = "SFTP://" + server.Addr
+
+			fs, err := mc.NewFs(args)
+
+			Expect(err).To(BeNil())
+			fs.Close()
+		})
+
+		It("selects SFTP from the protocol", func() {
+			args.Server = server.Addr
+			args.Protocol = mc.SFTPProtocol
+
+			fs, err := mc.NewFs(args)
+
+			Expect(err).To(BeNil())
+			Expect(fs).To(BeAssignableToTypeOf(&mc.SFTPFileSystem{}))
+			fs.Close()
+		})
+
+		It("returns an error for unknown protocols", func() {
+			args.Protocol = "gopher"
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("requires a password or private key", func() {
+			args.Pw = ""
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("returns an error for the wrong password", func() {
+			args.Pw = "wrong"
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		It("rejects hosts which aren't in the known_hosts file", func() {
+			Expect(ioutil.WriteFile(args.KnownHostsFile, []byte{}, 0600)).To(BeNil())
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("not a known host"))
+		})
+
+		It("rejects hosts with a different key", func() {
+			otherKey := newTestSigner()
+			line := knownhosts.Line([]string{server.Addr}, otherKey.PublicKey())
+			Expect(ioutil.WriteFile(args.KnownHostsFile, []byte(line+"\n"), 0600)).To(BeNil())
+
+			_, err := mc.NewFs(args)
+
+			Expect(err).ToNot(BeNil())
+		})
+
+		Context("private key", func() {
+			var keyFile string
+
+			BeforeEach(func() {
+				_, priv, err := ed25519.GenerateKey(rand.Reader)
+				Expect(err).To(BeNil())
+				signer, err := ssh.NewSignerFromKey(priv)
+				Expect(err).To(BeNil())
+				server.AuthorizedKey = signer.PublicKey()
+
+				keyFile = filepath.Join(dir, "id_ed25519")
+				Expect(ioutil.WriteFile(keyFile, marshalTestKey(priv), 0600)).To(BeNil())
+
+				args.Pw = ""
+				args.KeyFile = keyFile
+			})
+
+			It("logs in with the private key", func() {
+				fs, err := mc.NewFs(args)
+
+				Expect(err).To(BeNil())
+				fs.Close()
+			})
+
+			It("returns an error for unauthorized keys", func() {
+				server.AuthorizedKey = newTestSigner().PublicKey()
+
+				_, err := mc.NewFs(args)
+
+				Expect(err).ToNot(BeNil())
+			})
+
+			It("returns an error for missing key files", func() {
+				args.KeyFile = filepath.Join(dir, "missing")
+
+				_, err := mc.NewFs(args)
+
+				Expect(err).ToNot(BeNil())
+			})
+		})
+	})
+
+	Context("file operations", func() {
+		var fs mc.FileSystem
+
+		BeforeEach(func() {
+			var err error
+			fs, err = mc.NewFs(args)
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			fs.Close()
+		})
+
+		It("writes and reads files", func() {
+			relPath := filepath.Join(mc.ModFolderName, "sub", "a.jar")
+			Expect(fs.MkDirAll(filepath.Dir(relPath))).To(BeNil())
+
+			Expect(fs.WriteFile(bytes.NewReader([]byte("jar content")), relPath)).To(BeNil())
+
+			b, err := fs.ReadFile(relPath)
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal("jar content"))
+		})
+
+		It("prefixes paths with the base dir", func() {
+			args.BaseDir = "/srv/survival"
+			baseFs, err := mc.NewFs(args)
+			Expect(err).To(BeNil())
+			defer baseFs.Close()
+
+			Expect(baseFs.MkDirAll(mc.ModFolderName)).To(BeNil())
+			Expect(baseFs.WriteFile(bytes.NewReader([]byte("based")), filepath.Join(mc.ModFolderName, "a.jar"))).To(BeNil())
+
+			b, err := fs.ReadFile("/srv/survival/mods/a.jar")
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal("based"))
+		})
+
+		It("overwrites existing files", func() {
+			Expect(fs.WriteFile(bytes.NewReader([]byte("long original content")), "a.jar")).To(BeNil())
+			Expect(fs.WriteFile(bytes.NewReader([]byte("new")), "a.jar")).To(BeNil())
+
+			b, err := fs.ReadFile("a.jar")
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal("new"))
+		})
+
+		It("renames over existing files", func() {
+			Expect(fs.WriteFile(bytes.NewReader([]byte("new")), "a.tmp")).To(BeNil())
+			Expect(fs.WriteFile(bytes.NewReader([]byte("old")), "a.jar")).To(BeNil())
+
+			Expect(fs.Rename("a.tmp", "a.jar")).To(BeNil())
+
+			b, err := fs.ReadFile("a.jar")
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal("new"))
+			_, err = fs.Stat("a.tmp")
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+
+		It("keeps the file at the new path when the file to move is missing", func() {
+			Expect(fs.WriteFile(bytes.NewReader([]byte("old")), "a.jar")).To(BeNil())
+
+			Expect(fs.Rename("a.tmp", "a.jar")).ToNot(BeNil())
+
+			b, err := fs.ReadFile("a.jar")
+			Expect(err).To(BeNil())
+			Expect(string(b)).To(Equal("old"))
+		})
+
+		It("returns os.ErrNotExist for missing files", func() {
+			_, err := fs.ReadFile(filepath.Join(mc.ModFolderName, "missing.jar"))
+
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+
+		It("lists directories sorted by name and stats files", func() {
+			Expect(fs.MkDirAll(filepath.Join(mc.ModFolderName, "sub"))).To(BeNil())
+			Expect(fs.WriteFile(bytes.NewReader([]byte("12345")), filepath.Join(mc.ModFolderName, "b.jar"))).To(BeNil())
+
+			infos, err := fs.ReadDir(mc.ModFolderName)
+
+			Expect(err).To(BeNil())
+			Expect(infos).To(HaveLen(2))
+			Expect(infos[0].Name).To(Equal("b.jar"))
+			Expect(infos[0].Size).To(Equal(int64(5)))
+			Expect(infos[1].Name).To(Equal("sub"))
+			Expect(infos[1].IsDir).To(BeTrue())
+
+			fi, err := fs.Stat(filepath.Join(mc.ModFolderName, "b.jar"))
+			Expect(err).To(BeNil())
+			Expect(fi.Size).To(Equal(int64(5)))
+
+			_, err = fs.Stat(filepath.Join(mc.ModFolderName, "missing.jar"))
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+
+		It("doesn't fail when the directory already exists", func() {
+			Expect(fs.MkDirAll(mc.ModFolderName)).To(BeNil())
+			Expect(fs.MkDirAll(mc.ModFolderName)).To(BeNil())
+			Expect(fs.MkDirAll("")).To(BeNil())
+		})
+	})
+})
+
+// -----
+// IN-PROCESS SFTP SERVER
+// -----
+
+type testSFTPServer struct {
+	Addr          string
+	HostKey       ssh.Signer
+	AuthorizedKey ssh.PublicKey
+	listener      net.Listener
+}
+
+func startTestSFTPServer(user, pw string) *testSFTPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).To(BeNil())
+
+	s := &testSFTPServer{
+		Addr:     listener.Addr().String(),
+		HostKey:  newTestSigner(),
+		listener: listener,
+	}
+
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, p []byte) (*ssh.Permissions, error) {
+			if c.User() == user && string(p) == pw {
+				return nil, nil
+			}
+			return nil, errors.New("bad password")
+		},
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if s.AuthorizedKey != nil && bytes.Equal(key.Marshal(), s.AuthorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized key")
+		},
+	}
+	cfg.AddHostKey(s.HostKey)
+
+	// every connection shares one in-memory file system
+	handlers := sftp.InMemHandler()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSFTP(conn, cfg, handlers)
+		}
+	}()
+
+	return s
+}
+
+func (s *testSFTPServer) Close() {
+	s.listener.Close()
+}
+
+func serveTestSFTP(conn net.Conn, cfg *ssh.ServerConfig, handlers sftp.Handlers) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "unsupported")
+			continue
+		}
+		channel, requests, err := newChan.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}(requests)
+
+		go func() {
+			server := sftp.NewRequestServer(channel, handlers)
+			server.Serve()
+			server.Close()
+		}()
+	}
+}
+
+func newTestSigner() ssh.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).To(BeNil())
+	signer, err := ssh.NewSignerFromKey(priv)
+	Expect(err).To(BeNil())
+	return signer
+}
+
+func marshalTestKey(priv ed25519.PrivateKey) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	Expect(err).To(BeNil())
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
@@ -0,0 +1,52 @@
+package mc
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+const fileScheme = "file://"
+
+var (
+	// LocalSourceFs is the file system local mod sources are read from
+	LocalSourceFs = afero.NewOsFs()
+)
+
+// IsLocalSource returns true if the mod source is a file:// URL or a plain
+// file system path rather than an HTTP URL
+func IsLocalSource(src string) bool {
+	if strings.HasPrefix(strings.ToLower(src), fileScheme) {
+		return true
+	}
+	if filepath.IsAbs(src) || filepath.VolumeName(src) != "" {
+		return true
+	}
+	u, err := url.Parse(src)
+	return err != nil || u.Scheme == "" || len(u.Scheme) == 1 // single letter schemes are windows drives
+}
+
+// LocalSourcePath returns the file system path of a local mod source
+func LocalSourcePath(src string) string {
+	if !strings.HasPrefix(strings.ToLower(src), fileScheme) {
+		return src
+	}
+
+	p := src[len(fileScheme):]
+	if u, err := url.Parse(src); err == nil {
+		p = u.Path
+	}
+
+	// file:///C:/path/to.jar
+	if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p)
+}
+
+// ReadLocalSource reads the content of a local mod source
+func ReadLocalSource(src string) ([]byte, error) {
+	return afero.ReadFile(LocalSourceFs, LocalSourcePath(src))
+}
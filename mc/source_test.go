@@ -0,0 +1,103 @@
+package mc_test
+
+import (
+	"mcmods/mc"
+	. "mcmods/testdata"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Local Sources", func() {
+	jarPath := "/builds/private-mod.jar"
+	jarContent := "private build"
+
+	BeforeEach(func() {
+		InitTestData()
+		mc.LocalSourceFs = afero.NewMemMapFs()
+		Expect(afero.WriteFile(mc.LocalSourceFs, jarPath, []byte(jarContent), 0644)).To(BeNil())
+	})
+
+	Context("IsLocalSource", func() {
+		It("is true for file URLs and paths", func() {
+			for _, src := range []string{"file:///builds/mod.jar", "/builds/mod.jar", "builds/mod.jar", `C:\builds\mod.jar`} {
+				Expect(mc.IsLocalSource(src)).To(BeTrue(), src)
+			}
+		})
+
+		It("is false for HTTP URLs", func() {
+			for _, src := range []string{"https://mod_site/mod.jar", "http://mod_site/mod.jar"} {
+				Expect(mc.IsLocalSource(src)).To(BeFalse(), src)
+			}
+		})
+	})
+
+	Context("LocalSourcePath", func() {
+		It("strips the file scheme", func() {
+			Expect(mc.LocalSourcePath("file:///builds/mod.jar")).To(Equal(filepath.FromSlash("/builds/mod.jar")))
+		})
+
+		It("strips the leading slash before windows drives", func() {
+			Expect(mc.LocalSourcePath("file:///C:/builds/mod.jar")).To(Equal(filepath.FromSlash("C:/builds/mod.jar")))
+		})
+
+		It("leaves plain paths alone", func() {
+			Expect(mc.LocalSourcePath(jarPath)).To(Equal(jarPath))
+		})
+	})
+
+	Context("downloading", func() {
+		It("copies the local file to the file system", func() {
+			mc.ViperInstance.Set(mc.InstallPathKey, "/mc")
+			fs := afero.NewMemMapFs()
+			mod := &mc.Mod{CliName: "private-mod", LatestURL: "file://" + jarPath}
+			dl := mc.NewModDownloader(&mc.HTTPClient{Getter: emptyGetter{}}, &mc.LocalFileSystem{Fs: fs}, nil)
+
+			res, err := dl.Download(mod, "mods/private-mod.jar")
+
+			Expect(err).To(BeNil())
+			Expect(res.URL).To(Equal(mod.LatestURL))
+			Expect(res.SHA256).To(Equal(mc.HashSHA256([]byte(jarContent))))
+			Expect(res.Size).To(Equal(int64(len(jarContent))))
+
+			b, _ := afero.ReadFile(fs, "/mc/mods/private-mod.jar")
+			Expect(string(b)).To(Equal(jarContent))
+		})
+	})
+
+	Context("IsUpToDate", func() {
+		var mod *mc.Mod
+
+		BeforeEach(func() {
+			mod = &mc.Mod{CliName: "private-mod", LatestURL: jarPath}
+		})
+
+		It("compares content hashes instead of URLs", func() {
+			install := mc.ModInstallation{DownloadURL: jarPath, SHA256: mc.HashSHA256([]byte(jarContent))}
+
+			Expect(mc.IsUpToDate(mod, install)).To(BeTrue())
+
+			Expect(afero.WriteFile(mc.LocalSourceFs, jarPath, []byte("new build"), 0644)).To(BeNil())
+
+			Expect(mc.IsUpToDate(mod, install)).To(BeFalse())
+		})
+
+		It("is false without a recorded hash", func() {
+			Expect(mc.IsUpToDate(mod, mc.ModInstallation{DownloadURL: jarPath})).To(BeFalse())
+		})
+
+		It("is false when the file can't be read", func() {
+			mod.LatestURL = "/builds/missing.jar"
+			install := mc.ModInstallation{DownloadURL: mod.LatestURL, SHA256: mc.HashSHA256([]byte(jarContent))}
+
+			Expect(mc.IsUpToDate(mod, install)).To(BeFalse())
+		})
+
+		It("compares URLs for HTTP sources", func() {
+			Expect(mc.IsUpToDate(TestingClientMod1, mc.ModInstallation{DownloadURL: TestingClientMod1.LatestURL})).To(BeTrue())
+			Expect(mc.IsUpToDate(TestingClientMod1, mc.ModInstallation{DownloadURL: "old"})).To(BeFalse())
+		})
+	})
+})
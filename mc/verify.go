@@ -0,0 +1,141 @@
+package mc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problems found when verifying installations
+const (
+	// VerifyMissing - the installed file doesn't exist
+	VerifyMissing = "missing"
+
+	// VerifySizeMismatch - the installed file's size differs from the record
+	VerifySizeMismatch = "size mismatch"
+
+	// VerifyHashMismatch - the installed file's content differs from the record
+	VerifyHashMismatch = "hash mismatch"
+
+	// VerifyUnknown - a jar in the mods folder which has no installation record
+	VerifyUnknown = "unknown jar"
+)
+
+// VerifyIssue describes a difference between the installation records and the
+// files on the file system
+type VerifyIssue struct {
+	// CliName is the mod of the installation record, or empty for unknown jars
+	CliName string
+
+	// Path is the file's path relative to the install directory
+	Path string
+
+	// Problem is one of the Verify* problems
+	Problem string
+
+	// Detail explains the problem, e.g. the expected and actual sizes
+	Detail string
+}
+
+// Broken returns true if re-installing the mod fixes the issue
+func (i VerifyIssue) Broken() bool {
+	return i.CliName != ""
+}
+
+// InstallVerifier checks the installation records against the file system
+type InstallVerifier interface {
+	// Verify returns the issues found, sorted by path
+	Verify(fs FileSystem, cfg *UserModConfig) ([]VerifyIssue, error)
+}
+
+type installVerifier struct{}
+
+// NewInstallVerifier returns a new struct which implements InstallVerifier
+func NewInstallVerifier() InstallVerifier {
+	return installVerifier{}
+}
+
+// ModInstallPath returns the path of the mod's jar relative to the install
+// directory
+func ModInstallPath(cliName string) string {
+	return filepath.Join(ModFolderName, fmt.Sprintf("%s.jar", cliName))
+}
+
+// Verify checks each installation's file exists, and matches the recorded size
+// and hash. Files are only read when their size matches and there's a hash to
+// compare, which is the case for mods installed by newer versions of the tool.
+func (v installVerifier) Verify(fs FileSystem, cfg *UserModConfig) ([]VerifyIssue, error) {
+	issues := []VerifyIssue{}
+	knownFiles := map[string]bool{}
+
+	for cliName, installation := range cfg.ModInstallations {
+		relPath := ModInstallPath(cliName)
+		knownFiles[filepath.Base(relPath)] = true
+
+		issue, err := verifyInstallation(fs, relPath, installation)
+		if err != nil {
+			return nil, err
+		}
+		if issue != nil {
+			issue.CliName = cliName
+			issues = append(issues, *issue)
+		}
+	}
+
+	infos, err := fs.ReadDir(ModFolderName)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, fi := range infos {
+		if fi.IsDir || knownFiles[fi.Name] || !strings.EqualFold(filepath.Ext(fi.Name), ".jar") {
+			continue
+		}
+		issues = append(issues, VerifyIssue{
+			Path:    filepath.Join(ModFolderName, fi.Name),
+			Problem: VerifyUnknown,
+		})
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Path < issues[j].Path
+	})
+	return issues, nil
+}
+
+func verifyInstallation(fs FileSystem, relPath string, installation ModInstallation) (*VerifyIssue, error) {
+	fi, err := fs.Stat(relPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &VerifyIssue{Path: relPath, Problem: VerifyMissing}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if installation.Size != 0 && fi.Size != installation.Size {
+		return &VerifyIssue{
+			Path:    relPath,
+			Problem: VerifySizeMismatch,
+			Detail:  fmt.Sprintf("expected %d bytes, found %d", installation.Size, fi.Size),
+		}, nil
+	}
+
+	if installation.SHA256 == "" {
+		return nil, nil
+	}
+
+	b, err := fs.ReadFile(relPath)
+	if err != nil {
+		return nil, err
+	}
+	if actual := HashSHA256(b); !strings.EqualFold(actual, installation.SHA256) {
+		return &VerifyIssue{
+			Path:    relPath,
+			Problem: VerifyHashMismatch,
+			Detail:  fmt.Sprintf("expected sha256 %s, found %s", installation.SHA256, actual),
+		}, nil
+	}
+
+	return nil, nil
+}
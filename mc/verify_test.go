@@ -0,0 +1,134 @@
+package mc_test
+
+import (
+	"errors"
+	"mcmods/mc"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Install Verifier", func() {
+	var aferoFs afero.Fs
+	var fs mc.FileSystem
+	var cfg *mc.UserModConfig
+	var verifier mc.InstallVerifier
+
+	content := []byte("jar content")
+	modsDir := filepath.Join("/mc", mc.ModFolderName)
+
+	writeJar := func(name string, b []byte) {
+		Expect(afero.WriteFile(aferoFs, filepath.Join(modsDir, name), b, 0644)).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		mc.ViperInstance.Set(mc.InstallPathKey, "/mc")
+		aferoFs = afero.NewMemMapFs()
+		Expect(aferoFs.MkdirAll(modsDir, 0755)).To(BeNil())
+		fs = &mc.LocalFileSystem{Fs: aferoFs}
+		verifier = mc.NewInstallVerifier()
+
+		cfg = &mc.UserModConfig{
+			ModInstallations: map[string]mc.ModInstallation{
+				"mod-a": {SHA256: mc.HashSHA256(content), Size: int64(len(content))},
+			},
+		}
+	})
+
+	It("finds no issues when the files match", func() {
+		writeJar("mod-a.jar", content)
+
+		issues, err := verifier.Verify(fs, cfg)
+
+		Expect(err).To(BeNil())
+		Expect(issues).To(BeEmpty())
+	})
+
+	It("reports missing files", func() {
+		issues, err := verifier.Verify(fs, cfg)
+
+		Expect(err).To(BeNil())
+		Expect(issues).To(Equal([]mc.VerifyIssue{
+			{CliName: "mod-a", Path: mc.ModInstallPath("mod-a"), Problem: mc.VerifyMissing},
+		}))
+		Expect(issues[0].Broken()).To(BeTrue())
+	})
+
+	It("reports all installations as missing without a mods folder", func() {
+		Expect(aferoFs.RemoveAll(modsDir)).To(BeNil())
+
+		issues, err := verifier.Verify(fs, cfg)
+
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].Problem).To(Equal(mc.VerifyMissing))
+	})
+
+	It("reports size mismatches", func() {
+		writeJar("mod-a.jar", []byte("truncated"))
+
+		issues, err := verifier.Verify(fs, cfg)
+
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].Problem).To(Equal(mc.VerifySizeMismatch))
+		Expect(issues[0].Detail).To(Equal("expected 11 bytes, found 9"))
+	})
+
+	It("reports hash mismatches", func() {
+		writeJar("mod-a.jar", []byte("jar CONTENT"))
+
+		issues, err := verifier.Verify(fs, cfg)
+
+		Expect(err).To(BeNil())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].CliName).To(Equal("mod-a"))
+		Expect(issues[0].Problem).To(Equal(mc.VerifyHashMismatch))
+	})
+
+	It("only checks existence for installations without a size or hash", func() {
+		cfg.ModInstallations["mod-a"] = mc.ModInstallation{DownloadURL: "url"}
+		writeJar("mod-a.jar", []byte("anything"))
+
+		issues, err := verifier.Verify(fs, cfg)
+
+		Expect(err).To(BeNil())
+		Expect(issues).To(BeEmpty())
+	})
+
+	It("reports unknown jars, sorted by path, ignoring other files", func() {
+		writeJar("mod-a.jar", content)
+		writeJar("z.jar", content)
+		writeJar("b.JAR", content)
+		writeJar("notes.txt", content)
+		Expect(aferoFs.MkdirAll(filepath.Join(modsDir, "dir.jar"), 0755)).To(BeNil())
+
+		issues, err := verifier.Verify(fs, cfg)
+
+		Expect(err).To(BeNil())
+		Expect(issues).To(Equal([]mc.VerifyIssue{
+			{Path: filepath.Join(mc.ModFolderName, "b.JAR"), Problem: mc.VerifyUnknown},
+			{Path: filepath.Join(mc.ModFolderName, "z.jar"), Problem: mc.VerifyUnknown},
+		}))
+		Expect(issues[0].Broken()).To(BeFalse())
+	})
+
+	It("returns errors from the file system", func() {
+		statErr := errors.New("stat err")
+
+		_, err := verifier.Verify(statErrFs{FileSystem: fs, Err: statErr}, cfg)
+
+		Expect(err).To(Equal(statErr))
+	})
+})
+
+type statErrFs struct {
+	mc.FileSystem
+	Err error
+}
+
+func (s statErrFs) Stat(relPath string) (mc.FileInfo, error) {
+	return mc.FileInfo{}, s.Err
+}
@@ -0,0 +1,241 @@
+package mc
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// PackManifestFileName is the name of the manifest at the root of a mod pack
+	PackManifestFileName = "mcmods-manifest.json"
+)
+
+// ZipFileSystem writes files into a new zip archive, e.g. to share mods with
+// players who can't run the tool. The archive is written to a temporary file
+// and only moved to its path by Finish. Files can't be read back or replaced
+// once they're written.
+type ZipFileSystem struct {
+	Path string
+
+	afs     afero.Fs
+	tmpPath string
+	file    afero.File
+	zw      *zip.Writer
+	entries map[string]FileInfo
+	done    bool
+}
+
+// NewZipFileSystem starts a new archive which will be written to the path
+func NewZipFileSystem(afs afero.Fs, archivePath string) (*ZipFileSystem, error) {
+	if dir := filepath.Dir(archivePath); dir != "." {
+		if err := afs.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	tmpPath := archivePath + ".tmp"
+	file, err := afs.Create(tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ZipFileSystem{
+		Path:    archivePath,
+		afs:     afs,
+		tmpPath: tmpPath,
+		file:    file,
+		zw:      zip.NewWriter(file),
+		entries: map[string]FileInfo{},
+	}, nil
+}
+
+// WriteFile adds the file to the archive
+func (z *ZipFileSystem) WriteFile(r io.Reader, relPath string) error {
+	name := zipEntryName(relPath)
+	if _, exists := z.entries[name]; exists {
+		return fmt.Errorf("%s was already written to the archive", name)
+	}
+	if err := z.MkDirAll(path.Dir(name)); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w, err := z.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+
+	z.entries[name] = FileInfo{Name: path.Base(name), Size: n, ModTime: now}
+	return nil
+}
+
+// ReadFile returns os.ErrNotExist for files which haven't been written, or an
+// error for files which have, since the archive can't be read until it's done
+func (z *ZipFileSystem) ReadFile(relPath string) ([]byte, error) {
+	if _, exists := z.entries[zipEntryName(relPath)]; !exists {
+		return nil, os.ErrNotExist
+	}
+	return nil, errors.New("files can't be read back from a zip archive being written")
+}
+
+// MkDirAll adds an entry for each directory in the path which isn't in the
+// archive yet
+func (z *ZipFileSystem) MkDirAll(relPath string) error {
+	name := zipEntryName(relPath)
+	for _, dir := range GetRecursiveDirs("/" + name) {
+		dir = strings.TrimPrefix(dir, "/")
+		if _, exists := z.entries[dir]; exists {
+			continue
+		}
+
+		now := time.Now()
+		if _, err := z.zw.CreateHeader(&zip.FileHeader{Name: dir + "/", Modified: now}); err != nil {
+			return err
+		}
+		z.entries[dir] = FileInfo{Name: path.Base(dir), ModTime: now, IsDir: true}
+	}
+	return nil
+}
+
+// ReadDir lists the files and directories written to the directory, sorted by
+// name
+func (z *ZipFileSystem) ReadDir(relPath string) ([]FileInfo, error) {
+	dir := zipEntryName(relPath)
+	if fi, exists := z.entries[dir]; dir != "." && (!exists || !fi.IsDir) {
+		return nil, os.ErrNotExist
+	}
+
+	infos := []FileInfo{}
+	for name, fi := range z.entries {
+		if path.Dir(name) == dir {
+			infos = append(infos, fi)
+		}
+	}
+	sortFileInfos(infos)
+	return infos, nil
+}
+
+// Stat describes a file or directory written to the archive
+func (z *ZipFileSystem) Stat(relPath string) (FileInfo, error) {
+	name := zipEntryName(relPath)
+	if name == "." {
+		return FileInfo{Name: "/", IsDir: true}, nil
+	}
+
+	fi, exists := z.entries[name]
+	if !exists {
+		return FileInfo{}, os.ErrNotExist
+	}
+	return fi, nil
+}
+
+// Remove returns an error, since files can't be removed from a zip archive
+func (z *ZipFileSystem) Remove(relPath string) error {
+	return errors.New("files can't be removed from a zip archive")
+}
+
+// Rename returns an error, since files can't be renamed in a zip archive
+func (z *ZipFileSystem) Rename(oldRelPath, newRelPath string) error {
+	return errors.New("files can't be renamed in a zip archive")
+}
+
+// Finish completes the archive and moves it to its path
+func (z *ZipFileSystem) Finish() error {
+	if z.done {
+		return errors.New("the zip archive was already closed")
+	}
+	z.done = true
+
+	err := z.zw.Close()
+	if closeErr := z.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = z.afs.Rename(z.tmpPath, z.Path)
+	}
+	if err != nil {
+		z.afs.Remove(z.tmpPath)
+	}
+	return err
+}
+
+// Close discards the archive if it wasn't finished
+func (z *ZipFileSystem) Close() {
+	if z.done {
+		return
+	}
+	z.done = true
+
+	z.file.Close()
+	z.afs.Remove(z.tmpPath)
+}
+
+func zipEntryName(relPath string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(relPath)), "/")
+}
+
+// PackManifest describes the mods in a mod pack
+type PackManifest struct {
+	Created string            `json:"created"`
+	Mods    []PackManifestMod `json:"mods"`
+}
+
+// PackManifestMod describes a mod file in a mod pack
+type PackManifestMod struct {
+	CliName      string `json:"cliName"`
+	FriendlyName string `json:"friendlyName"`
+	File         string `json:"file"`
+	DownloadURL  string `json:"downloadUrl"`
+	SHA256       string `json:"sha256,omitempty"`
+	Size         int64  `json:"size,omitempty"`
+}
+
+// WritePackManifest writes the manifest of the installed mods, sorted by CLI
+// name, to the root of the file system
+func WritePackManifest(fs FileSystem, mods []*Mod, cfg *UserModConfig) error {
+	manifest := PackManifest{
+		Created: time.Now().Format(time.RFC3339),
+		Mods:    make([]PackManifestMod, 0, len(mods)),
+	}
+	for _, m := range mods {
+		installation := cfg.ModInstallations[m.CliName]
+		url := installation.SourceURL
+		if url == "" {
+			url = installation.DownloadURL
+		}
+
+		manifest.Mods = append(manifest.Mods, PackManifestMod{
+			CliName:      m.CliName,
+			FriendlyName: m.FriendlyName,
+			File:         filepath.ToSlash(ModInstallPath(m.CliName)),
+			DownloadURL:  url,
+			SHA256:       installation.SHA256,
+			Size:         installation.Size,
+		})
+	}
+	sort.Slice(manifest.Mods, func(i, j int) bool {
+		return manifest.Mods[i].CliName < manifest.Mods[j].CliName
+	})
+
+	b, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return err
+	}
+	return fs.WriteFile(bytes.NewReader(b), PackManifestFileName)
+}
@@ -0,0 +1,120 @@
+package mc_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mcmods/mc"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Zip File System", func() {
+	var afs afero.Fs
+	var zfs *mc.ZipFileSystem
+
+	archivePath := filepath.Join("/out", "pack.zip")
+	content := []byte("jar content")
+
+	readArchive := func() map[string]string {
+		b, err := afero.ReadFile(afs, archivePath)
+		Expect(err).To(BeNil())
+		zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+		Expect(err).To(BeNil())
+
+		files := map[string]string{}
+		for _, f := range zr.File {
+			r, err := f.Open()
+			Expect(err).To(BeNil())
+			b, err := ioutil.ReadAll(r)
+			Expect(err).To(BeNil())
+			files[f.Name] = string(b)
+		}
+		return files
+	}
+
+	BeforeEach(func() {
+		afs = afero.NewMemMapFs()
+
+		var err error
+		zfs, err = mc.NewZipFileSystem(afs, archivePath)
+		Expect(err).To(BeNil())
+	})
+
+	It("writes the files and their directories into the archive when finished", func() {
+		Expect(zfs.WriteFile(bytes.NewReader(content), mc.ModInstallPath("mod-a"))).To(BeNil())
+
+		exists, _ := afero.Exists(afs, archivePath)
+		Expect(exists).To(BeFalse())
+
+		Expect(zfs.Finish()).To(BeNil())
+
+		Expect(readArchive()).To(Equal(map[string]string{
+			"mods/":          "",
+			"mods/mod-a.jar": string(content),
+		}))
+		exists, _ = afero.Exists(afs, archivePath+".tmp")
+		Expect(exists).To(BeFalse())
+	})
+
+	It("describes the written files", func() {
+		Expect(zfs.WriteFile(bytes.NewReader(content), mc.ModInstallPath("mod-a"))).To(BeNil())
+
+		fi, err := zfs.Stat(mc.ModInstallPath("mod-a"))
+		Expect(err).To(BeNil())
+		Expect(fi.Size).To(Equal(int64(len(content))))
+
+		infos, err := zfs.ReadDir(mc.ModFolderName)
+		Expect(err).To(BeNil())
+		Expect(infos).To(HaveLen(1))
+		Expect(infos[0].Name).To(Equal("mod-a.jar"))
+
+		_, err = zfs.Stat("other.jar")
+		Expect(err).To(Equal(os.ErrNotExist))
+		_, err = zfs.ReadFile("other.jar")
+		Expect(err).To(Equal(os.ErrNotExist))
+	})
+
+	It("doesn't replace written files", func() {
+		Expect(zfs.WriteFile(bytes.NewReader(content), "a.jar")).To(BeNil())
+
+		Expect(zfs.WriteFile(bytes.NewReader(content), "a.jar")).ToNot(BeNil())
+	})
+
+	It("discards unfinished archives when closed", func() {
+		Expect(zfs.WriteFile(bytes.NewReader(content), "a.jar")).To(BeNil())
+
+		zfs.Close()
+
+		exists, _ := afero.Exists(afs, archivePath)
+		Expect(exists).To(BeFalse())
+		exists, _ = afero.Exists(afs, archivePath+".tmp")
+		Expect(exists).To(BeFalse())
+	})
+
+	It("writes a manifest of the installed mods", func() {
+		mods := []*mc.Mod{
+			{CliName: "mod-b", FriendlyName: "Mod B"},
+			{CliName: "mod-a", FriendlyName: "Mod A"},
+		}
+		cfg := &mc.UserModConfig{ModInstallations: map[string]mc.ModInstallation{
+			"mod-a": {DownloadURL: "https://a", SHA256: "abc", Size: 11},
+			"mod-b": {DownloadURL: "https://b", SourceURL: "https://mirror/b"},
+		}}
+
+		Expect(mc.WritePackManifest(zfs, mods, cfg)).To(BeNil())
+		Expect(zfs.Finish()).To(BeNil())
+
+		manifest := mc.PackManifest{}
+		Expect(json.Unmarshal([]byte(readArchive()[mc.PackManifestFileName]), &manifest)).To(BeNil())
+		Expect(manifest.Mods).To(Equal([]mc.PackManifestMod{
+			{CliName: "mod-a", FriendlyName: "Mod A", File: "mods/mod-a.jar", DownloadURL: "https://a", SHA256: "abc", Size: 11},
+			{CliName: "mod-b", FriendlyName: "Mod B", File: "mods/mod-b.jar", DownloadURL: "https://mirror/b"},
+		}))
+	})
+})
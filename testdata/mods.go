@@ -2,6 +2,7 @@ package testdata
 
 import (
 	"mcmods/mc"
+	"time"
 )
 
 var (
@@ -153,11 +154,11 @@ func InitTestData() {
 		ModInstallations: map[string]mc.ModInstallation{
 			TestingClientMod1.CliName: mc.ModInstallation{
 				DownloadURL: "dummy_url",
-				Timestamp:   "123",
+				Timestamp:   time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
 			},
 			TestingServerRequired1.CliName: mc.ModInstallation{
 				DownloadURL: TestingServerRequired1.LatestURL,
-				Timestamp:   "789",
+				Timestamp:   time.Date(2021, 6, 8, 12, 0, 0, 0, time.UTC),
 			},
 		},
 		ClientMods: []*mc.Mod{